@@ -20,6 +20,9 @@ import { createBidResource } from './routes/bid'
 import { createLeagueResource } from './routes/leagueRoute'
 import { createMarketplaceResource } from './routes/marketplace'
 import { createOutcomeeResource } from './routes/outcomeRoute'
+import { createProfileResource } from './routes/profile'
+import { createRealtimeResource } from './routes/realtime'
+import { createSeasonResource } from './routes/seasonRoute'
 import { createUserResource } from './routes/user'
 
 export function createApi(
@@ -81,6 +84,9 @@ export function createApi(
   createMarketplaceResource(api, lambdas.marketplace, authorizer)
   createLeagueResource(api, lambdas.league, authorizer)
   createOutcomeeResource(api, lambdas.outcome, authorizer)
+  createProfileResource(api, lambdas.profile, authorizer)
+  createRealtimeResource(api, lambdas.realtime, authorizer)
+  createSeasonResource(api, lambdas.season, authorizer)
   createUserResource(api, lambdas.user, authorizer)
   return api
 }
@@ -13,6 +13,11 @@ export function createBetResource(
     functions.get,
   )
 
+  const liveIntegration = new HttpLambdaIntegration(
+    'GetLiveBetsIntegration',
+    functions.live,
+  )
+
   api.addRoutes({
     path: '/bet',
     methods: [HttpMethod.GET],
@@ -28,4 +33,12 @@ export function createBetResource(
     authorizer,
     authorizationScopes: ['openid'],
   })
+
+  api.addRoutes({
+    path: '/bet/live',
+    methods: [HttpMethod.GET],
+    integration: liveIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
 }
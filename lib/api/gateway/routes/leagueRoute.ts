@@ -12,6 +12,17 @@ export function createLeagueResource(
     'GetUserInfoIntegration',
     functions.getUsers,
   )
+
+  const getLeaderboardIntegration = new HttpLambdaIntegration(
+    'GetLeaderboardIntegration',
+    functions.getLeaderboard,
+  )
+
+  const updateSportsIntegration = new HttpLambdaIntegration(
+    'UpdateLeagueSportsIntegration',
+    functions.updateSports,
+  )
+
   api.addRoutes({
     path: '/league/users/{league}',
     methods: [HttpMethod.GET],
@@ -19,4 +30,20 @@ export function createLeagueResource(
     authorizer,
     authorizationScopes: ['openid'],
   })
+
+  api.addRoutes({
+    path: '/league/leaderboard/{league}',
+    methods: [HttpMethod.GET],
+    integration: getLeaderboardIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
+
+  api.addRoutes({
+    path: '/league/sports/{league}',
+    methods: [HttpMethod.PUT],
+    integration: updateSportsIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
 }
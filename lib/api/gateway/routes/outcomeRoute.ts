@@ -20,4 +20,17 @@ export function createOutcomeeResource(
     authorizer,
     authorizationScopes: ['openid'],
   })
+
+  const getHistoryIntegration = new HttpLambdaIntegration(
+    'HistoryIntegration',
+    functions.getHistory,
+  )
+
+  api.addRoutes({
+    path: '/outcome/history/{league}',
+    methods: [HttpMethod.GET],
+    integration: getHistoryIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
 }
@@ -0,0 +1,36 @@
+import { HttpApi, HttpMethod } from '@aws-cdk/aws-apigatewayv2-alpha'
+import { HttpJwtAuthorizer } from '@aws-cdk/aws-apigatewayv2-authorizers-alpha'
+import { HttpLambdaIntegration } from '@aws-cdk/aws-apigatewayv2-integrations-alpha'
+import { ProfileLambdas } from '../../lambdas/profile'
+
+export function createProfileResource(
+  api: HttpApi,
+  functions: ProfileLambdas,
+  authorizer: HttpJwtAuthorizer,
+) {
+  const getProfileIntegration = new HttpLambdaIntegration(
+    'GetProfileIntegration',
+    functions.get,
+  )
+
+  const updateProfileIntegration = new HttpLambdaIntegration(
+    'UpdateProfileIntegration',
+    functions.update,
+  )
+
+  api.addRoutes({
+    path: '/profile',
+    methods: [HttpMethod.GET],
+    integration: getProfileIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
+
+  api.addRoutes({
+    path: '/profile',
+    methods: [HttpMethod.PUT],
+    integration: updateProfileIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
+}
@@ -0,0 +1,23 @@
+import { HttpApi, HttpMethod } from '@aws-cdk/aws-apigatewayv2-alpha'
+import { HttpJwtAuthorizer } from '@aws-cdk/aws-apigatewayv2-authorizers-alpha'
+import { HttpLambdaIntegration } from '@aws-cdk/aws-apigatewayv2-integrations-alpha'
+import { RealtimeLambdas } from '../../lambdas/realtime'
+
+export function createRealtimeResource(
+  api: HttpApi,
+  functions: RealtimeLambdas,
+  authorizer: HttpJwtAuthorizer,
+) {
+  const ticketIntegration = new HttpLambdaIntegration(
+    'RealtimeTicketIntegration',
+    functions.ticket,
+  )
+
+  api.addRoutes({
+    path: '/realtime/ticket',
+    methods: [HttpMethod.POST],
+    integration: ticketIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
+}
@@ -0,0 +1,23 @@
+import { HttpApi, HttpMethod } from '@aws-cdk/aws-apigatewayv2-alpha'
+import { HttpJwtAuthorizer } from '@aws-cdk/aws-apigatewayv2-authorizers-alpha'
+import { HttpLambdaIntegration } from '@aws-cdk/aws-apigatewayv2-integrations-alpha'
+import { SeasonLambdas } from '../../lambdas/season'
+
+export function createSeasonResource(
+  api: HttpApi,
+  functions: SeasonLambdas,
+  authorizer: HttpJwtAuthorizer,
+) {
+  const getSeasonsIntegration = new HttpLambdaIntegration(
+    'GetSeasonsIntegration',
+    functions.getSeasons,
+  )
+
+  api.addRoutes({
+    path: '/league/seasons/{league}',
+    methods: [HttpMethod.GET],
+    integration: getSeasonsIntegration,
+    authorizer,
+    authorizationScopes: ['openid'],
+  })
+}
@@ -0,0 +1,44 @@
+import { WebSocketApi, WebSocketStage } from '@aws-cdk/aws-apigatewayv2-alpha'
+import { WebSocketLambdaIntegration } from '@aws-cdk/aws-apigatewayv2-integrations-alpha'
+import { Construct } from 'constructs'
+import { RealtimeLambdas } from '../lambdas/realtime'
+
+// Clients connect with ?ticket= from POST /realtime/ticket, then send
+// {"action":"subscribe","channels":[...]}.
+export function createWebSocketApi(
+  scope: Construct,
+  functions: RealtimeLambdas,
+): WebSocketApi {
+  const api = new WebSocketApi(scope, 'WebSocketApi', {
+    connectRouteOptions: {
+      integration: new WebSocketLambdaIntegration(
+        'ConnectIntegration',
+        functions.connect,
+      ),
+    },
+    disconnectRouteOptions: {
+      integration: new WebSocketLambdaIntegration(
+        'DisconnectIntegration',
+        functions.disconnect,
+      ),
+    },
+  })
+
+  api.addRoute('subscribe', {
+    integration: new WebSocketLambdaIntegration(
+      'SubscribeIntegration',
+      functions.subscribe,
+    ),
+    returnResponse: true,
+  })
+
+  const stage = new WebSocketStage(scope, 'WebSocketStage', {
+    webSocketApi: api,
+    stageName: 'production',
+    autoDeploy: true,
+  })
+
+  functions.broadcast.addEnvironment('WEBSOCKET_ENDPOINT', stage.callbackUrl)
+  api.grantManageConnections(functions.broadcast)
+  return api
+}
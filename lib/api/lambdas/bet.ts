@@ -1,9 +1,13 @@
 import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
 import { Rule, Schedule } from 'aws-cdk-lib/aws-events'
 import { LambdaFunction } from 'aws-cdk-lib/aws-events-targets'
+import { Effect, PolicyStatement, Role, ServicePrincipal } from 'aws-cdk-lib/aws-iam'
 import { Construct } from 'constructs'
 import { CreateLambdaParams, LambdaConfig } from '.'
 
+// the one-time schedules that resolve a kickoff's bets
+export const resolveSchedules = `arn:aws:scheduler:${process.env.CDK_DEFAULT_REGION}:${process.env.CDK_DEFAULT_ACCOUNT}:schedule/default/resolve-*`
+
 export function createBetLambdas(
   scope: Construct,
   config: LambdaConfig,
@@ -14,13 +18,56 @@ export function createBetLambdas(
     ...config,
   })
 
+  // per game schedules invoke resolve as this role
+  const resolveRole = new Role(scope, 'ResolveScheduleRole', {
+    assumedBy: new ServicePrincipal('scheduler.amazonaws.com'),
+  })
+
   const resolve = new GoFunction(scope, 'resolveBetsLambda', {
     entry: 'src/main/bet/resolve',
     ...config,
+    environment: {
+      ...config.environment,
+      RESOLVE_ROLE_ARN: resolveRole.roleArn,
+    },
+  })
+
+  const live = new GoFunction(scope, 'getLiveBetsLambda', {
+    entry: 'src/main/bet/live',
+    ...config,
   })
 
   params.table.grantReadData(get)
   params.table.grantReadWriteData(resolve)
+  params.table.grantReadWriteData(live)
+
+  resolve.grantInvoke(resolveRole)
+
+  // retries are scheduled by resolve itself, the schedules delete themselves once they fire
+  resolve.addToRolePolicy(
+    new PolicyStatement({
+      actions: ['scheduler:CreateSchedule'],
+      resources: [resolveSchedules],
+      effect: Effect.ALLOW,
+    }),
+  )
+  resolveRole.grantPassRole(resolve.grantPrincipal)
+
+  // per game rules made before the schedules still fire once and delete themselves
+  const resolveRules = `arn:aws:events:${process.env.CDK_DEFAULT_REGION}:${process.env.CDK_DEFAULT_ACCOUNT}:rule/resolve-*`
+
+  resolve.addPermission('ResolveRuleInvoke', {
+    principal: new ServicePrincipal('events.amazonaws.com'),
+    sourceArn: resolveRules,
+  })
+
+  resolve.addToRolePolicy(
+    new PolicyStatement({
+      actions: ['events:RemoveTargets', 'events:DeleteRule'],
+      resources: [resolveRules],
+      effect: Effect.ALLOW,
+    }),
+  )
 
   new Rule(scope, 'ResolveBetsRule', {
     schedule: Schedule.cron({ minute: '0', hour: '4' }),
@@ -30,10 +77,14 @@ export function createBetLambdas(
   return {
     get,
     resolve,
+    resolveRole,
+    live,
   }
 }
 
 export type BetLambdas = {
   get: GoFunction
   resolve: GoFunction
+  resolveRole: Role
+  live: GoFunction
 }
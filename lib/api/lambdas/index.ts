@@ -1,31 +1,75 @@
+import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
 import { Table } from 'aws-cdk-lib/aws-dynamodb'
+import { EventBus } from 'aws-cdk-lib/aws-events'
 import { RetentionDays } from 'aws-cdk-lib/aws-logs'
+import { Secret } from 'aws-cdk-lib/aws-secretsmanager'
 import { Construct } from 'constructs'
+import { NotificationConfig } from '../../backend-stack'
 import { BetLambdas, createBetLambdas } from './bet'
 import { BidLambdas, createBidLambdas } from './bid'
 import { LeagueLambdas, createLeagueLambdas } from './league'
 import { MarketplaceLambdas, createMarketplaceLambdas } from './marketplace'
+import {
+  NotificationLambdas,
+  createNotificationLambdas,
+} from './notification'
 import { OutcomeLambdas, createOutcomeLambdas } from './outcome'
+import { ProfileLambdas, createProfileLambdas } from './profile'
+import { RealtimeLambdas, createRealtimeLambdas } from './realtime'
+import { SeasonLambdas, createSeasonLambdas } from './season'
 import { UserLambdas, createUserLambdas } from './user.'
 
 export function createLambdas(
   scope: Construct,
   params: CreateLambdaParams,
 ): Lambdas {
+  // signs pagination cursors so clients can't hand back made up keys
+  const cursorSecret = new Secret(scope, 'CursorSecret', {
+    generateSecretString: { excludePunctuation: true },
+  })
+
+  // domain events, see src/event
+  const eventBus = new EventBus(scope, 'MarketplaceEvents')
+
   const lambdaConfig: LambdaConfig = {
-    environment: { TABLE_NAME: params.table.tableName },
+    environment: {
+      TABLE_NAME: params.table.tableName,
+      EVENT_BUS_NAME: eventBus.eventBusName,
+      CURSOR_SECRET: cursorSecret.secretValue.unsafeUnwrap(),
+      LOG_LEVEL: params.logLevel ?? 'info',
+    },
     logRetention: RetentionDays.ONE_DAY,
   }
+  Object.assign(
+    lambdaConfig.environment,
+    notificationEnvironment(scope, params.notifications),
+  )
 
   const betLambdas = createBetLambdas(scope, lambdaConfig, params)
-  return {
+  const lambdas: Lambdas = {
     bet: betLambdas,
     bid: createBidLambdas(scope, lambdaConfig, params),
-    marketplace: createMarketplaceLambdas(scope, lambdaConfig, params),
+    marketplace: createMarketplaceLambdas(scope, lambdaConfig, params, betLambdas),
     league: createLeagueLambdas(scope, lambdaConfig, params),
+    notification: createNotificationLambdas(
+      scope,
+      lambdaConfig,
+      params,
+      eventBus,
+    ),
     outcome: createOutcomeLambdas(scope, lambdaConfig, params),
+    profile: createProfileLambdas(scope, lambdaConfig, params),
+    realtime: createRealtimeLambdas(scope, lambdaConfig, params, eventBus),
+    season: createSeasonLambdas(scope, lambdaConfig, params),
     user: createUserLambdas(scope, lambdaConfig, params),
   }
+
+  for (const group of Object.values(lambdas)) {
+    for (const lambda of Object.values(group) as GoFunction[]) {
+      eventBus.grantPutEventsTo(lambda)
+    }
+  }
+  return lambdas
 }
 
 export type Lambdas = {
@@ -33,10 +77,52 @@ export type Lambdas = {
   bid: BidLambdas
   marketplace: MarketplaceLambdas
   league: LeagueLambdas
+  notification: NotificationLambdas
   outcome: OutcomeLambdas
+  profile: ProfileLambdas
+  realtime: RealtimeLambdas
+  season: SeasonLambdas
   user: UserLambdas
 }
 
+function notificationEnvironment(
+  scope: Construct,
+  config?: NotificationConfig,
+): { [key: string]: string } {
+  // signs webhook bodies so receivers can check they came from us
+  const webhookSecret = new Secret(scope, 'WebhookSecret', {
+    generateSecretString: { excludePunctuation: true },
+  })
+  const environment: { [key: string]: string } = {
+    WEBHOOK_SECRET: webhookSecret.secretValue.unsafeUnwrap(),
+  }
+
+  const secret = config?.secretName
+    ? Secret.fromSecretNameV2(scope, 'NotificationSecret', config.secretName)
+    : undefined
+
+  if (config?.smtp && secret) {
+    Object.assign(environment, {
+      SMTP_HOST: config.smtp.host,
+      SMTP_PORT: config.smtp.port,
+      SMTP_USERNAME: config.smtp.username,
+      SMTP_FROM: config.smtp.from,
+      SMTP_PASSWORD: secret
+        .secretValueFromJson('smtpPassword')
+        .unsafeUnwrap(),
+    })
+  }
+  if (config?.vapidSubject && secret) {
+    Object.assign(environment, {
+      VAPID_SUBJECT: config.vapidSubject,
+      VAPID_PRIVATE_KEY: secret
+        .secretValueFromJson('vapidPrivateKey')
+        .unsafeUnwrap(),
+    })
+  }
+  return environment
+}
+
 export type LambdaConfig = {
   environment: { [key: string]: string }
   logRetention: RetentionDays
@@ -44,4 +130,6 @@ export type LambdaConfig = {
 
 export type CreateLambdaParams = {
   table: Table
+  notifications?: NotificationConfig
+  logLevel?: string
 }
@@ -12,13 +12,29 @@ export function createLeagueLambdas(
     ...config,
   })
 
+  const getLeaderboard = new GoFunction(scope, 'getLeaderboardLambda', {
+    entry: 'src/main/league/getLeaderboard',
+    ...config,
+  })
+
+  const updateSports = new GoFunction(scope, 'updateLeagueSportsLambda', {
+    entry: 'src/main/league/updateSports',
+    ...config,
+  })
+
   params.table.grantReadWriteData(getUsers)
+  params.table.grantReadData(getLeaderboard)
+  params.table.grantReadWriteData(updateSports)
 
   return {
     getUsers,
+    getLeaderboard,
+    updateSports,
   }
 }
 
 export type LeagueLambdas = {
   getUsers: GoFunction
+  getLeaderboard: GoFunction
+  updateSports: GoFunction
 }
@@ -4,11 +4,13 @@ import { LambdaFunction } from 'aws-cdk-lib/aws-events-targets'
 import { Effect, PolicyStatement } from 'aws-cdk-lib/aws-iam'
 import { Construct } from 'constructs'
 import { CreateLambdaParams, LambdaConfig } from '.'
+import { BetLambdas, resolveSchedules } from './bet'
 
 export function createMarketplaceLambdas(
   scope: Construct,
   config: LambdaConfig,
   params: CreateLambdaParams,
+  betLambdas: BetLambdas,
 ): MarketplaceLambdas {
   const getAvailableEvents = new GoFunction(scope, 'getAvailableEventsLambda', {
     entry: 'src/main/marketplace/get',
@@ -20,6 +22,8 @@ export function createMarketplaceLambdas(
     ...config,
     environment: {
       ...config.environment,
+      RESOLVE_LAMBDA_ARN: betLambdas.resolve.functionArn,
+      RESOLVE_ROLE_ARN: betLambdas.resolveRole.roleArn,
     },
   })
 
@@ -30,17 +34,16 @@ export function createMarketplaceLambdas(
 
   params.table.grantReadWriteData(createEvents)
   params.table.grantReadData(getAvailableEvents)
+  params.table.grantReadWriteData(getEspnInfo)
 
   createEvents.addToRolePolicy(
     new PolicyStatement({
-      actions: ['events:PutRule', 'events:PutTargets'],
-      resources: [
-        `arn:aws:events:${process.env.CDK_DEFAULT_REGION}:${process.env.CDK_DEFAULT_ACCOUNT}:rule/*`,
-        `arn:aws:events:${process.env.CDK_DEFAULT_REGION}:${process.env.CDK_DEFAULT_ACCOUNT}:event-bus/default`,
-      ],
+      actions: ['scheduler:CreateSchedule'],
+      resources: [resolveSchedules],
       effect: Effect.ALLOW,
     }),
   )
+  betLambdas.resolveRole.grantPassRole(createEvents.grantPrincipal)
 
   // const eventBus = EventBus.fromEventBusName(scope, 'DefaultBus', 'default')
 
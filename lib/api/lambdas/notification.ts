@@ -0,0 +1,52 @@
+import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
+import { Duration } from 'aws-cdk-lib'
+import { EventBus, Rule } from 'aws-cdk-lib/aws-events'
+import { SqsQueue } from 'aws-cdk-lib/aws-events-targets'
+import { SqsEventSource } from 'aws-cdk-lib/aws-lambda-event-sources'
+import { Queue } from 'aws-cdk-lib/aws-sqs'
+import { Construct } from 'constructs'
+import { CreateLambdaParams, LambdaConfig } from '.'
+
+export function createNotificationLambdas(
+  scope: Construct,
+  config: LambdaConfig,
+  params: CreateLambdaParams,
+  eventBus: EventBus,
+): NotificationLambdas {
+  const notify = new GoFunction(scope, 'notifyLambda', {
+    entry: 'src/main/notification/notify',
+    ...config,
+    timeout: Duration.seconds(30),
+  })
+
+  params.table.grantReadData(notify)
+
+  // events wait here so one settlement run reaches each member as a single message
+  const queue = new Queue(scope, 'NotificationQueue', {
+    visibilityTimeout: Duration.minutes(3),
+  })
+  notify.addEventSource(
+    new SqsEventSource(queue, {
+      batchSize: 100,
+      maxBatchingWindow: Duration.minutes(1),
+    }),
+  )
+
+  // members hear about matches and settlements from the events, not the requests
+  new Rule(scope, 'NotificationRule', {
+    eventBus,
+    eventPattern: {
+      source: ['sammy.link.marketplace'],
+      detailType: ['BidMatched', 'BetSettled'],
+    },
+    targets: [new SqsQueue(queue)],
+  })
+
+  return {
+    notify,
+  }
+}
+
+export type NotificationLambdas = {
+  notify: GoFunction
+}
@@ -12,13 +12,21 @@ export function createOutcomeLambdas(
     ...config,
   })
 
+  const getHistory = new GoFunction(scope, 'getHistoryLambda', {
+    entry: 'src/main/outcome/getHistory',
+    ...config,
+  })
+
   params.table.grantReadData(getByUser)
+  params.table.grantReadData(getHistory)
 
   return {
     getByUser,
+    getHistory,
   }
 }
 
 export type OutcomeLambdas = {
   getByUser: GoFunction
+  getHistory: GoFunction
 }
@@ -0,0 +1,32 @@
+import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
+import { Construct } from 'constructs'
+import { CreateLambdaParams, LambdaConfig } from '.'
+
+export function createProfileLambdas(
+  scope: Construct,
+  config: LambdaConfig,
+  params: CreateLambdaParams,
+): ProfileLambdas {
+  const get = new GoFunction(scope, 'getProfileLambda', {
+    entry: 'src/main/profile/get',
+    ...config,
+  })
+
+  const update = new GoFunction(scope, 'updateProfileLambda', {
+    entry: 'src/main/profile/update',
+    ...config,
+  })
+
+  params.table.grantReadData(get)
+  params.table.grantReadWriteData(update)
+
+  return {
+    get,
+    update,
+  }
+}
+
+export type ProfileLambdas = {
+  get: GoFunction
+  update: GoFunction
+}
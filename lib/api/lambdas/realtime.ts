@@ -0,0 +1,81 @@
+import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
+import { EventBus, Rule } from 'aws-cdk-lib/aws-events'
+import { LambdaFunction } from 'aws-cdk-lib/aws-events-targets'
+import { Secret } from 'aws-cdk-lib/aws-secretsmanager'
+import { Construct } from 'constructs'
+import { CreateLambdaParams, LambdaConfig } from '.'
+
+export function createRealtimeLambdas(
+  scope: Construct,
+  config: LambdaConfig,
+  params: CreateLambdaParams,
+  eventBus: EventBus,
+): RealtimeLambdas {
+  // signs the tickets WebSocket connections are opened with, see src/realtime
+  const ticketSecret = new Secret(scope, 'TicketSecret', {
+    generateSecretString: { excludePunctuation: true },
+  })
+  const realtimeConfig = {
+    ...config,
+    environment: {
+      ...config.environment,
+      TICKET_SECRET: ticketSecret.secretValue.unsafeUnwrap(),
+    },
+  }
+
+  const ticket = new GoFunction(scope, 'realtimeTicketLambda', {
+    entry: 'src/main/realtime/ticket',
+    ...realtimeConfig,
+  })
+
+  const connect = new GoFunction(scope, 'realtimeConnectLambda', {
+    entry: 'src/main/realtime/connect',
+    ...realtimeConfig,
+  })
+
+  const disconnect = new GoFunction(scope, 'realtimeDisconnectLambda', {
+    entry: 'src/main/realtime/disconnect',
+    ...config,
+  })
+
+  const subscribe = new GoFunction(scope, 'realtimeSubscribeLambda', {
+    entry: 'src/main/realtime/subscribe',
+    ...config,
+  })
+
+  // WEBSOCKET_ENDPOINT is added once the WebSocket API exists, see gateway/websocket
+  const broadcast = new GoFunction(scope, 'realtimeBroadcastLambda', {
+    entry: 'src/main/realtime/broadcast',
+    ...config,
+  })
+
+  params.table.grantReadWriteData(connect)
+  params.table.grantReadWriteData(disconnect)
+  params.table.grantReadWriteData(subscribe)
+  params.table.grantReadWriteData(broadcast)
+
+  new Rule(scope, 'RealtimeBroadcastRule', {
+    eventBus,
+    eventPattern: {
+      source: ['sammy.link.marketplace'],
+      detailType: ['ListingChanged', 'BetCreated'],
+    },
+    targets: [new LambdaFunction(broadcast)],
+  })
+
+  return {
+    ticket,
+    connect,
+    disconnect,
+    subscribe,
+    broadcast,
+  }
+}
+
+export type RealtimeLambdas = {
+  ticket: GoFunction
+  connect: GoFunction
+  disconnect: GoFunction
+  subscribe: GoFunction
+  broadcast: GoFunction
+}
@@ -0,0 +1,39 @@
+import { GoFunction } from '@aws-cdk/aws-lambda-go-alpha'
+import { Rule, Schedule } from 'aws-cdk-lib/aws-events'
+import { LambdaFunction } from 'aws-cdk-lib/aws-events-targets'
+import { Construct } from 'constructs'
+import { CreateLambdaParams, LambdaConfig } from '.'
+
+export function createSeasonLambdas(
+  scope: Construct,
+  config: LambdaConfig,
+  params: CreateLambdaParams,
+): SeasonLambdas {
+  const getSeasons = new GoFunction(scope, 'getSeasonsLambda', {
+    entry: 'src/main/league/getSeasons',
+    ...config,
+  })
+
+  const rollover = new GoFunction(scope, 'seasonRolloverLambda', {
+    entry: 'src/main/season/rollover',
+    ...config,
+  })
+
+  params.table.grantReadData(getSeasons)
+  params.table.grantReadWriteData(rollover)
+
+  new Rule(scope, 'SeasonRolloverRule', {
+    schedule: Schedule.cron({ minute: '0', hour: '6' }),
+    targets: [new LambdaFunction(rollover)],
+  })
+
+  return {
+    getSeasons,
+    rollover,
+  }
+}
+
+export type SeasonLambdas = {
+  getSeasons: GoFunction
+  rollover: GoFunction
+}
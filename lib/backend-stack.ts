@@ -1,6 +1,7 @@
 import * as cdk from 'aws-cdk-lib'
 import { Construct } from 'constructs'
 import { createApi } from './api/gateway'
+import { createWebSocketApi } from './api/gateway/websocket'
 import { createLambdas } from './api/lambdas'
 import { createDynamoDatabase } from './database'
 // import * as sqs from 'aws-cdk-lib/aws-sqs';
@@ -18,8 +19,13 @@ export class BackendStack extends cdk.Stack {
     const config = this.node.getContext(environment)
     const table = createDynamoDatabase(this)
 
-    const lambdas = createLambdas(this, { table })
+    const lambdas = createLambdas(this, {
+      table,
+      notifications: config.notifications,
+      logLevel: config.logLevel,
+    })
     createApi(this, lambdas, config)
+    createWebSocketApi(this, lambdas.realtime)
   }
 }
 
@@ -32,4 +38,21 @@ export type Config = {
     prefix: string
     root: string
   }
+  notifications?: NotificationConfig
+  // one of debug, info, warn or error, info if left out
+  logLevel?: string
+}
+
+// Channels are only turned on when configured. The SMTP password and VAPID private
+// key are read from the JSON secret named by secretName, under smtpPassword and
+// vapidPrivateKey.
+export type NotificationConfig = {
+  smtp?: {
+    host: string
+    port: string
+    username: string
+    from: string
+  }
+  vapidSubject?: string
+  secretName?: string
 }
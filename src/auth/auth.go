@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/user"
+	"sammy.link/util"
+)
+
+const EmailClaim = "https://sammy.link/email"
+
+type Service interface {
+	IsMember(ctx context.Context, email string, league string) bool
+	Authorize(ctx context.Context, request events.APIGatewayV2HTTPRequest, leagues ...string) (string, bool)
+}
+
+type AuthService struct {
+	userService user.Service
+}
+
+func NewService(userService user.Service) Service {
+	return &AuthService{
+		userService: userService,
+	}
+}
+
+func GetEmail(request events.APIGatewayV2HTTPRequest) string {
+	if request.RequestContext.Authorizer == nil || request.RequestContext.Authorizer.JWT == nil {
+		return ""
+	}
+	return request.RequestContext.Authorizer.JWT.Claims[EmailClaim]
+}
+
+func (s *AuthService) IsMember(ctx context.Context, email string, league string) bool {
+	if email == "" || league == "" {
+		return false
+	}
+
+	for _, item := range s.userService.GetUser(ctx, email) {
+		if item.League == league {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns the caller's email and whether they belong to every league passed in.
+func (s *AuthService) Authorize(ctx context.Context, request events.APIGatewayV2HTTPRequest, leagues ...string) (string, bool) {
+	email := GetEmail(request)
+
+	if email == "" || len(leagues) == 0 {
+		return email, false
+	}
+
+	memberships := make(map[string]bool)
+	for _, item := range s.userService.GetUser(ctx, email) {
+		memberships[item.League] = true
+	}
+
+	for _, league := range leagues {
+		if !memberships[league] {
+			return email, false
+		}
+	}
+	return email, true
+}
+
+func ForbiddenResponse() (events.APIGatewayV2HTTPResponse, error) {
+	resp, _ := json.Marshal(util.DefaultResponse{Message: "not a member of this league"})
+	return util.ApigatewayResponse(string(resp), 403)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/user"
+)
+
+type mockUserService struct {
+	items []user.Item
+}
+
+func (s *mockUserService) GetUser(ctx context.Context, email string) []user.Item {
+	resp := make([]user.Item, 0, len(s.items))
+	for _, item := range s.items {
+		if item.Email == email {
+			resp = append(resp, item)
+		}
+	}
+	return resp
+}
+
+func (s *mockUserService) Create(ctx context.Context, item user.Item) {}
+
+func (s *mockUserService) Update(ctx context.Context, user string, amount int64) {}
+
+func (s *mockUserService) Rename(ctx context.Context, item user.Item, oldName string) error {
+	return nil
+}
+
+func request(email string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{EmailClaim: email},
+				},
+			},
+		},
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	s := NewService(&mockUserService{items: []user.Item{
+		{Email: "sam@sam.com", League: "default"},
+		{Email: "sam@sam.com", League: "work"},
+		{Email: "greg@greg.com", League: "default"},
+	}})
+	ctx := context.TODO()
+
+	if email, ok := s.Authorize(ctx, request("sam@sam.com"), "default", "work"); !ok || email != "sam@sam.com" {
+		t.Fatalf("sam should be allowed in default and work but got %s %t", email, ok)
+	}
+
+	if _, ok := s.Authorize(ctx, request("greg@greg.com"), "work"); ok {
+		t.Fatalf("greg should not be allowed in work")
+	}
+
+	if _, ok := s.Authorize(ctx, request("sam@sam.com")); ok {
+		t.Fatalf("a league is required")
+	}
+
+	if _, ok := s.Authorize(ctx, events.APIGatewayV2HTTPRequest{}, "default"); ok {
+		t.Fatalf("a request without claims should not be allowed")
+	}
+}
+
+func TestIsMember(t *testing.T) {
+	s := NewService(&mockUserService{items: []user.Item{{Email: "sam@sam.com", League: "default"}}})
+
+	if !s.IsMember(context.TODO(), "sam@sam.com", "default") {
+		t.Fatalf("sam should be a member of default")
+	}
+
+	if s.IsMember(context.TODO(), "sam@sam.com", "") {
+		t.Fatalf("empty league should never match")
+	}
+}
@@ -0,0 +1,3 @@
+module sammy.link/auth
+
+go 1.21.0
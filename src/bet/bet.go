@@ -3,8 +3,8 @@ package bet
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"regexp"
 	"strconv"
 	"time"
 
@@ -12,6 +12,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/key"
+	"sammy.link/util"
 )
 
 type BetDynamoItem struct {
@@ -27,8 +30,15 @@ type BetDynamoItem struct {
 	Status           string `dynamodbav:"status"`
 	Spread           string `dynamodbav:"spread"`
 	Ttl              int64  `dynamodbav:"ttl"`
-	HomeAbbreviation string `dynamodbav:"ha"`
-	AwayAbbreviation string `dynamodbav:"aa"`
+	HomeAbbreviation string `dynamodbav:"homeAbbreviation"`
+	AwayAbbreviation string `dynamodbav:"awayAbbreviation"`
+	Season           string `dynamodbav:"season"`
+	Date             string `dynamodbav:"date"`
+	ClaimedAt        string `dynamodbav:"claimedAt,omitempty"`
+	Version          int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyHomeAbbreviation string `dynamodbav:"ha,omitempty"`
+	LegacyAwayAbbreviation string `dynamodbav:"aa,omitempty"`
 }
 
 type Bet struct {
@@ -46,27 +56,64 @@ type Bet struct {
 	Date             time.Time `json:"date"`
 	HomeAbbreviation string    `json:"homeAbbreviation"`
 	AwayAbbreviation string    `json:"awayAbbreviation"`
+	Season           string    `json:"season"`
+	ClaimedAt        time.Time `json:"-"`
+}
+
+const (
+	Pending  = "PENDING"
+	Settling = "SETTLING"
+	Settled  = "SETTLED"
+)
+
+// ClaimTimeout is how long a run has to settle the bets it claimed, the longest a
+// lambda runs. Bets still settling after it are taken over by the next run.
+const ClaimTimeout = 15 * time.Minute
+
+// Unsettled reports whether a run at now should try to settle item: it is pending,
+// or the run that claimed it never finished.
+func (item Bet) Unsettled(now time.Time) bool {
+	switch item.Status {
+	case Pending:
+		return true
+	case Settling:
+		return !now.Before(item.ClaimedAt.Add(ClaimTimeout))
+	}
+	return false
 }
 
 type Service interface {
 	GetBetsByEventDate(ctx context.Context, date string) []Bet
 	GetBetsByWeek(ctx context.Context, div string, week string) []Bet
+	GetBetsByWeekPage(ctx context.Context, div string, week string, limit int32, cursor string) ([]Bet, string, error)
 	GetBetsByUser(ctx context.Context, user string, isGsi2 bool) []Bet
+	GetBetsByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]Bet, string, error)
+	GetBetsByUserBetween(ctx context.Context, user string, isGsi2 bool, from time.Time, to time.Time) []Bet
+	SetStatus(ctx context.Context, item Bet, status string)
+	Claim(ctx context.Context, item Bet, now time.Time) (Bet, bool)
 	Write(ctx context.Context, items []Bet)
 }
 
 type BetService struct {
 	databaseService database.Service[BetDynamoItem, Bet]
+	publisher       event.Publisher
 }
 
-func NewService(databaseService database.Service[BetDynamoItem, Bet]) Service {
+func NewService(databaseService database.Service[BetDynamoItem, Bet], publisher event.Publisher) Service {
 	return &BetService{
 		databaseService: databaseService,
+		publisher:       publisher,
 	}
 }
 
+var (
+	idCodec      = key.Codec{Prefix: "BET", Parts: 2}
+	sortKeyCodec = key.Codec{Parts: 6}
+	userCodec    = key.Codec{Prefix: "BET", Parts: 1}
+)
+
 func BuildId(div string, week string) string {
-	return fmt.Sprintf("BET|%s|%s", div, week)
+	return idCodec.Encode(div, week)
 }
 
 func (item Bet) GetDynamoItem() database.DynamoItem {
@@ -76,55 +123,103 @@ func (item Bet) GetDynamoItem() database.DynamoItem {
 		SortKey:          BuildSortKey(item),
 		Amount:           item.Amount,
 		Gsi1_id:          "BET",
-		Gsi1_sortKey:     item.Date.Format(format),
-		Gsi2_id:          fmt.Sprintf("BET|%s", item.AwayUser),
-		Gsi2_sortKey:     item.Date.Format(time.RFC3339),
-		Gsi3_id:          fmt.Sprintf("BET|%s", item.HomeUser),
-		Gsi3_sortKey:     item.Date.Format(time.RFC3339),
+		Gsi1_sortKey:     item.Date.UTC().Format(format),
+		Gsi2_id:          userCodec.Encode(item.AwayUser),
+		Gsi2_sortKey:     key.Time(item.Date),
+		Gsi3_id:          userCodec.Encode(item.HomeUser),
+		Gsi3_sortKey:     key.Time(item.Date),
 		Status:           item.Status,
 		Spread:           item.Spread,
 		Ttl:              item.Date.AddDate(0, 0, 1).Unix(),
 		HomeAbbreviation: item.HomeAbbreviation,
 		AwayAbbreviation: item.AwayAbbreviation,
+		Season:           item.Season,
+		Date:             key.Time(item.Date),
+		ClaimedAt:        claimedAt(item.ClaimedAt),
+		Version:          database.Version,
+	}
+}
+
+func claimedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return key.Time(t)
 }
 
 const format = "20060102"
 
 func BuildSortKey(bet Bet) string {
-	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", bet.Kind, bet.AwayTeam, bet.HomeTeam, bet.AwayUser, bet.HomeUser, bet.Date.Format(time.RFC3339))
+	return sortKeyCodec.Encode(bet.Kind, bet.AwayTeam, bet.HomeTeam, bet.AwayUser, bet.HomeUser, key.Time(bet.Date))
 }
 
-func (s *BetService) GetBetsByWeek(ctx context.Context, div string, week string) []Bet {
-	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
+func weekQuery(div string, week string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
 		TableName:              aws.String(os.Getenv("TABLE_NAME")),
 		KeyConditionExpression: aws.String("id = :id"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":id": &types.AttributeValueMemberS{Value: BuildId(div, week)},
 		},
-	})
+	}
+}
+
+func userQuery(user string, isGsi2 bool) *dynamodb.QueryInput {
+	index := "gsi3"
+	if isGsi2 {
+		index = "gsi2"
+	}
+
+	return &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String(fmt.Sprintf("%s_id = :id", index)),
+		IndexName:              aws.String(index),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: userCodec.Encode(user)},
+		},
+	}
+}
+
+func (s *BetService) GetBetsByWeek(ctx context.Context, div string, week string) []Bet {
+	return s.databaseService.Query(ctx, weekQuery(div, week))
+}
+
+func (s *BetService) GetBetsByWeekPage(ctx context.Context, div string, week string, limit int32, cursor string) ([]Bet, string, error) {
+	return s.databaseService.QueryPaged(ctx, weekQuery(div, week), limit, cursor)
 }
 
 func (s *BetService) GetBetsByUser(ctx context.Context, user string, isGsi2 bool) []Bet {
+	return s.databaseService.Query(ctx, userQuery(user, isGsi2))
+}
+
+// GetBetsByUserPage pages through both sides of a user's bets, newest game first.
+func (s *BetService) GetBetsByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]Bet, string, error) {
+	away := userQuery(user, true)
+	away.ScanIndexForward = aws.Bool(false)
+	home := userQuery(user, false)
+	home.ScanIndexForward = aws.Bool(false)
+
+	return s.databaseService.QueryMerged(ctx, []*dynamodb.QueryInput{away, home}, limit, cursor, func(one Bet, two Bet) bool {
+		return one.Date.After(two.Date)
+	})
+}
+
+// GetBetsByUserBetween returns a user's bets on games starting between from and to.
+func (s *BetService) GetBetsByUserBetween(ctx context.Context, user string, isGsi2 bool, from time.Time, to time.Time) []Bet {
+	index := "gsi3"
 	if isGsi2 {
-		return s.databaseService.Query(ctx, &dynamodb.QueryInput{
-			TableName:              aws.String(os.Getenv("TABLE_NAME")),
-			KeyConditionExpression: aws.String("gsi2_id = :id"),
-			IndexName:              aws.String("gsi2"),
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":id": &types.AttributeValueMemberS{Value: fmt.Sprintf("BET|%s", user)},
-			},
-		})
-	} else {
-		return s.databaseService.Query(ctx, &dynamodb.QueryInput{
-			TableName:              aws.String(os.Getenv("TABLE_NAME")),
-			KeyConditionExpression: aws.String("gsi3_id = :id"),
-			IndexName:              aws.String("gsi3"),
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":id": &types.AttributeValueMemberS{Value: fmt.Sprintf("BET|%s", user)},
-			},
-		})
+		index = "gsi2"
 	}
+
+	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String(fmt.Sprintf("%s_id = :id and %s_sortKey between :from and :to", index, index)),
+		IndexName:              aws.String(index),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":   &types.AttributeValueMemberS{Value: userCodec.Encode(user)},
+			":from": &types.AttributeValueMemberS{Value: key.Time(from)},
+			":to":   &types.AttributeValueMemberS{Value: key.Time(to)},
+		},
+	})
 }
 
 func (s *BetService) GetBetsByEventDate(ctx context.Context, date string) []Bet {
@@ -139,46 +234,133 @@ func (s *BetService) GetBetsByEventDate(ctx context.Context, date string) []Bet
 	})
 }
 
+func (bet BetDynamoItem) upgrade() BetDynamoItem {
+	if bet.Version < 1 {
+		bet.HomeAbbreviation = util.Coalesce(bet.HomeAbbreviation, bet.LegacyHomeAbbreviation)
+		bet.AwayAbbreviation = util.Coalesce(bet.AwayAbbreviation, bet.LegacyAwayAbbreviation)
+	}
+	return bet
+}
+
 func (bet BetDynamoItem) GetItem() database.Item {
-	idExpression := regexp.MustCompile(`BET\|(?P<Div>[^|]+)\|(?P<Week>[^|]+)`)
-	// return fmt.Sprintf("%s|%s|%s|%s|%s", bet.Kind, bet.AwayTeam, bet.HomeTeam, bet.AwayUser, bet.HomeUser)
-	sortKeyExpression := regexp.MustCompile(`(?P<Kind>[^|]+)\|(?P<AwayTeam>[^|]+)\|(?P<HomeTeam>[^|]+)\|(?P<AwayUser>[^|]+)\|(?P<HomeUser>[^|]+)\|(?P<CreateDate>[^|]+)`)
+	bet = bet.upgrade()
 
-	idMatch := idExpression.FindStringSubmatch(bet.Id)
-	sortKeyMatch := sortKeyExpression.FindStringSubmatch(bet.SortKey)
+	ids, err := idCodec.Decode(bet.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
 
-	paramsMap := make(map[string]string)
-	for i, name := range idExpression.SubexpNames() {
-		if i > 0 && i <= len(idMatch) {
-			paramsMap[name] = idMatch[i]
-		}
+	sortKeys, err := sortKeyCodec.Decode(bet.SortKey)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		sortKeys = make([]string, sortKeyCodec.Parts)
 	}
 
-	for i, name := range sortKeyExpression.SubexpNames() {
-		if i > 0 && i <= len(sortKeyMatch) {
-			paramsMap[name] = sortKeyMatch[i]
-		}
+	// bets written before the date attribute only have it in the sort key
+	date := bet.Date
+	if date == "" {
+		date = sortKeys[5]
 	}
+	gameDate, _ := key.ParseTime(date)
+	claimed, _ := key.ParseTime(bet.ClaimedAt)
 
-	gameDate := time.Unix(bet.Ttl, 0)
-	week, _ := strconv.Atoi(paramsMap["Week"])
+	week, _ := strconv.Atoi(ids[1])
 	return Bet{
-		Kind:             paramsMap["Kind"],
-		AwayTeam:         paramsMap["AwayTeam"],
-		HomeTeam:         paramsMap["HomeTeam"],
+		Kind:             sortKeys[0],
+		AwayTeam:         sortKeys[1],
+		HomeTeam:         sortKeys[2],
 		Spread:           bet.Spread,
-		AwayUser:         paramsMap["AwayUser"],
-		HomeUser:         paramsMap["HomeUser"],
+		AwayUser:         sortKeys[3],
+		HomeUser:         sortKeys[4],
 		Status:           bet.Status,
 		Amount:           bet.Amount,
 		Week:             week,
-		Div:              paramsMap["Div"],
-		Date:             gameDate.AddDate(0, -1, -1),
+		Div:              ids[0],
+		Date:             gameDate,
 		HomeAbbreviation: bet.HomeAbbreviation,
 		AwayAbbreviation: bet.AwayAbbreviation,
+		Season:           bet.Season,
+		ClaimedAt:        claimed,
+	}
+}
+
+func (s *BetService) SetStatus(ctx context.Context, item Bet, status string) {
+	s.databaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key: betKey(item),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+		TableName:        aws.String(os.Getenv("TABLE_NAME")),
+		UpdateExpression: aws.String("SET #status = :status"),
+	})
+}
+
+func betKey(item Bet) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: BuildId(item.Div, strconv.Itoa(item.Week))},
+		"sortKey": &types.AttributeValueMemberS{Value: BuildSortKey(item)},
 	}
 }
 
+// Claim moves a pending bet, or one whose claim has timed out, to settling at now.
+// It reports false when another run holds the bet so that only one run settles it
+// at a time. The claimed bet is what SettledWrite needs to finish it.
+func (s *BetService) Claim(ctx context.Context, item Bet, now time.Time) (Bet, bool) {
+	_, err := s.databaseService.UpdateReturning(ctx, &dynamodb.UpdateItemInput{
+		Key: betKey(item),
+		// claims from before claimedAt was written have timed out
+		ConditionExpression: aws.String("#status = :pending OR (#status = :settling AND (attribute_not_exists(claimedAt) OR claimedAt <= :stale))"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending":  &types.AttributeValueMemberS{Value: Pending},
+			":settling": &types.AttributeValueMemberS{Value: Settling},
+			":stale":    &types.AttributeValueMemberS{Value: key.Time(now.Add(-ClaimTimeout))},
+			":now":      &types.AttributeValueMemberS{Value: key.Time(now)},
+		},
+		TableName:        aws.String(os.Getenv("TABLE_NAME")),
+		UpdateExpression: aws.String("SET #status = :settling, claimedAt = :now"),
+	})
+	if err != nil {
+		return item, false
+	}
+
+	item.Status = Settling
+	item.ClaimedAt = now.UTC().Truncate(time.Second)
+	return item, true
+}
+
+// SettledWrite marks a claimed bet settled, for the transaction that pays it out. It
+// only succeeds while the claim is still the one returned by Claim, so a run that
+// was taken over can't pay the bet a second time.
+func SettledWrite(item Bet) types.TransactWriteItem {
+	return types.TransactWriteItem{Update: &types.Update{
+		TableName:           aws.String(os.Getenv("TABLE_NAME")),
+		Key:                 betKey(item),
+		ConditionExpression: aws.String("#status = :settling AND claimedAt = :claimed"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":settling": &types.AttributeValueMemberS{Value: Settling},
+			":settled":  &types.AttributeValueMemberS{Value: Settled},
+			":claimed":  &types.AttributeValueMemberS{Value: key.Time(item.ClaimedAt)},
+		},
+		UpdateExpression: aws.String("SET #status = :settled"),
+	}}
+}
+
 func (s *BetService) Write(ctx context.Context, items []Bet) {
 	s.databaseService.Write(ctx, items)
+
+	events := make([]event.Event, len(items))
+	for i, item := range items {
+		events[i] = event.Event{Type: event.BetCreated, Detail: item}
+	}
+	event.Emit(ctx, s.publisher, events...)
 }
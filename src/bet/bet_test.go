@@ -0,0 +1,92 @@
+package bet
+
+import (
+	"context"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/event"
+)
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	roundTrip := func(div string, kind string, awayTeam string, homeTeam string, awayUser string, homeUser string, week uint8, seconds uint32) bool {
+		item := Bet{
+			Div:      div,
+			Kind:     kind,
+			AwayTeam: awayTeam,
+			HomeTeam: homeTeam,
+			AwayUser: awayUser,
+			HomeUser: homeUser,
+			Week:     int(week),
+			Date:     time.Unix(int64(seconds), 0).UTC(),
+			Status:   Pending,
+		}
+		return item.GetDynamoItem().(BetDynamoItem).GetItem() == item
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetItemWithoutDateAttribute(t *testing.T) {
+	item := BetDynamoItem{
+		Id:      "BET|default|1",
+		SortKey: "NFL|Detroit Lions|Kansas City Chiefs|sam@sam.com|greg@greg.com|2023-09-08T00:20:00Z",
+		Ttl:     time.Date(2023, time.September, 9, 0, 20, 0, 0, time.UTC).Unix(),
+	}
+
+	if date := item.GetItem().(Bet).Date; !date.Equal(time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)) {
+		t.Fatalf("older bets should get their date from the sort key but got %s", date)
+	}
+}
+
+// statuses holds bet statuses and claim times by sort key and applies a claim's
+// condition to them.
+type statuses struct {
+	database.Service[BetDynamoItem, Bet]
+	status    map[string]string
+	claimedAt map[string]string
+}
+
+func (s *statuses) UpdateReturning(ctx context.Context, params *dynamodb.UpdateItemInput) (Bet, error) {
+	sortKey := params.Key["sortKey"].(*types.AttributeValueMemberS).Value
+	value := func(name string) string {
+		return params.ExpressionAttributeValues[name].(*types.AttributeValueMemberS).Value
+	}
+
+	stale := s.status[sortKey] == value(":settling") && s.claimedAt[sortKey] <= value(":stale")
+	if s.status[sortKey] != value(":pending") && !stale {
+		return Bet{}, &types.ConditionalCheckFailedException{Message: aws.String("status")}
+	}
+	s.status[sortKey] = value(":settling")
+	s.claimedAt[sortKey] = value(":now")
+	return Bet{}, nil
+}
+
+func TestClaimOnce(t *testing.T) {
+	item := Bet{Div: "default", Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Week: 1, Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)}
+	db := &statuses{status: map[string]string{BuildSortKey(item): Pending}, claimedAt: map[string]string{}}
+	service := NewService(db, event.Discard)
+	now := time.Date(2023, time.September, 8, 4, 20, 0, 0, time.UTC)
+
+	claimed, ok := service.Claim(context.TODO(), item, now)
+	if !ok || claimed.Status != Settling || !claimed.ClaimedAt.Equal(now) {
+		t.Fatalf("should claim a pending bet but got %+v", claimed)
+	}
+	if _, ok := service.Claim(context.TODO(), item, now.Add(time.Minute)); ok {
+		t.Error("should not claim a bet another run is settling")
+	}
+	if status := db.status[BuildSortKey(item)]; status != Settling {
+		t.Errorf("status = %s", status)
+	}
+
+	if _, ok := service.Claim(context.TODO(), item, now.Add(ClaimTimeout)); !ok {
+		t.Error("should take over a claim that timed out")
+	}
+}
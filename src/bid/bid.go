@@ -2,9 +2,8 @@ package bid
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"os"
-	"regexp"
 	"strconv"
 	"sync"
 	"time"
@@ -15,7 +14,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"sammy.link/bet"
 	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/key"
+	"sammy.link/logging"
+	"sammy.link/sport"
 	"sammy.link/util"
+	"sammy.link/validation"
 )
 
 type Bid struct {
@@ -32,6 +36,7 @@ type Bid struct {
 	HomeAbbreviation string    `json:"homeAbbreviation"`
 	AwayAbbreviation string    `json:"awayAbbreviation"`
 	Div              string    `json:"div"`
+	Season           string    `json:"season"`
 }
 
 type DyanmoBidItem struct {
@@ -41,9 +46,17 @@ type DyanmoBidItem struct {
 	Gsi1_sortKey     string `dynamodbav:"gsi1_sortKey"`
 	Spread           string `dynamodbav:"spread"`
 	Ttl              int64  `dynamodbav:"ttl"`
-	Week             int    `dynamodbav:"we"`
-	HomeAbbreviation string `dynamodbav:"h_ab"`
-	AwayAbbreviation string `dynamodbav:"a_ab"`
+	Week             int    `dynamodbav:"week"`
+	HomeAbbreviation string `dynamodbav:"homeAbbreviation"`
+	AwayAbbreviation string `dynamodbav:"awayAbbreviation"`
+	Season           string `dynamodbav:"season"`
+	Date             string `dynamodbav:"date"`
+	CreateDate       string `dynamodbav:"createDate"`
+	Version          int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyWeek             int    `dynamodbav:"we,omitempty"`
+	LegacyHomeAbbreviation string `dynamodbav:"h_ab,omitempty"`
+	LegacyAwayAbbreviation string `dynamodbav:"a_ab,omitempty"`
 }
 
 type BidAndBet struct {
@@ -56,7 +69,8 @@ type BidAndBet struct {
 type Service interface {
 	GetBidsByEvent(ctx context.Context, event string, div string) []Bid
 	GetBidsByUser(ctx context.Context, user string) []Bid
-	Update(ctx context.Context, updateBid Bid)
+	GetBidsByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]Bid, string, error)
+	Update(ctx context.Context, updateBid Bid, matched int64)
 	WriteBids(ctx context.Context, items []Bid)
 	WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndBet, waitGroup *sync.WaitGroup)
 	Lock(ctx context.Context, key string)
@@ -65,67 +79,114 @@ type Service interface {
 }
 type BidService struct {
 	databaseService database.Service[DyanmoBidItem, Bid]
+	publisher       event.Publisher
 }
 
-func NewService(databaseService database.Service[DyanmoBidItem, Bid]) Service {
+func NewService(databaseService database.Service[DyanmoBidItem, Bid], publisher event.Publisher) Service {
 	return &BidService{
 		databaseService: databaseService,
+		publisher:       publisher,
 	}
 }
 
+var (
+	idCodec      = key.Codec{Prefix: "B", Parts: 5}
+	sortKeyCodec = key.Codec{Parts: 3}
+	userCodec    = key.Codec{Prefix: "BID", Parts: 1}
+	eventCodec   = key.Codec{Parts: 4}
+)
+
 func GetBidDynamoId(div string, kind string, date time.Time, awayTeam string, homeTeam string) string {
-	return fmt.Sprintf("B|%s|%s|%s|%s|%s", div, kind, date.Format(time.RFC3339), awayTeam, homeTeam)
+	return idCodec.Encode(div, kind, key.Time(date), awayTeam, homeTeam)
 }
 
 func GetBidDynamoSortKey(chosenTeam string, user string, createDate time.Time) string {
-	return fmt.Sprintf("%s|%s|%d", chosenTeam, user, createDate.Unix())
+	return sortKeyCodec.Encode(chosenTeam, user, strconv.FormatInt(createDate.Unix(), 10))
+}
+
+// GetEventKey identifies the game a bid is on, the same way marketplace listings do.
+func GetEventKey(kind string, date time.Time, awayTeam string, homeTeam string) string {
+	return eventCodec.Encode(kind, key.Time(date), awayTeam, homeTeam)
+}
+
+// Order is all a client sends to bid on a game. The teams, line and kickoff are filled
+// in from the marketplace so they can't be made up.
+type Order struct {
+	Div     string `json:"div"`
+	EventId string `json:"eventId"`
+	Side    string `json:"side"`
+	Amount  int64  `json:"amount"`
+}
+
+func CheckOrder(v *validation.Validator, order Order) bool {
+	v.String("div", order.Div).Required()
+	v.String("eventId", order.EventId).Required()
+	v.String("side", order.Side).OneOf(sport.Away, sport.Home)
+	v.Int("amount", order.Amount).Between(1, 100)
+	return v.Valid()
 }
 
 func (bid Bid) GetDynamoItem() database.DynamoItem {
 	return DyanmoBidItem{
 		Id:               GetBidDynamoId(bid.Div, bid.Kind, bid.Date, bid.AwayTeam, bid.HomeTeam),
 		SortKey:          GetBidDynamoSortKey(bid.ChosenCompetitor, bid.User, bid.CreateDate),
-		Gsi1_id:          fmt.Sprintf("BID|%s", bid.User),
+		Gsi1_id:          userCodec.Encode(bid.User),
 		Gsi1_sortKey:     strconv.FormatInt(bid.Amount, 10),
 		Spread:           bid.Spread,
 		Week:             bid.Week,
 		AwayAbbreviation: bid.AwayAbbreviation,
 		HomeAbbreviation: bid.HomeAbbreviation,
 		Ttl:              bid.Date.AddDate(0, 0, 1).Unix(),
+		Season:           bid.Season,
+		Date:             key.Time(bid.Date),
+		CreateDate:       bid.CreateDate.UTC().Format(time.RFC3339Nano),
+		Version:          database.Version,
 	}
 }
 
+func (bid DyanmoBidItem) upgrade() DyanmoBidItem {
+	if bid.Version < 1 {
+		bid.Week = util.Coalesce(bid.Week, bid.LegacyWeek)
+		bid.HomeAbbreviation = util.Coalesce(bid.HomeAbbreviation, bid.LegacyHomeAbbreviation)
+		bid.AwayAbbreviation = util.Coalesce(bid.AwayAbbreviation, bid.LegacyAwayAbbreviation)
+	}
+	return bid
+}
+
 func (bid DyanmoBidItem) GetItem() database.Item {
-	idExpression := regexp.MustCompile(`B\|(?P<Div>[^|]+)\|(?P<Kind>[^|]+)\|(?P<Date>[^|]+)\|(?P<AwayTeam>[^|]+)\|(?P<HomeTeam>[^|]+)`)
-	sortKeyExpression := regexp.MustCompile(`(?P<ChosenCompetitor>[^|]+)\|(?P<User>[^|]+)\|(?P<CreateDate>[^|]+)`)
+	bid = bid.upgrade()
 
-	idMatch := idExpression.FindStringSubmatch(bid.Id)
-	sortKeyMatch := sortKeyExpression.FindStringSubmatch(bid.SortKey)
+	ids, err := idCodec.Decode(bid.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
 
-	paramsMap := make(map[string]string)
-	for i, name := range idExpression.SubexpNames() {
-		if i > 0 && i <= len(idMatch) {
-			paramsMap[name] = idMatch[i]
-		}
+	sortKeys, err := sortKeyCodec.Decode(bid.SortKey)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		sortKeys = make([]string, sortKeyCodec.Parts)
 	}
 
-	for i, name := range sortKeyExpression.SubexpNames() {
-		if i > 0 && i <= len(sortKeyMatch) {
-			paramsMap[name] = sortKeyMatch[i]
-		}
+	// bids written before the date attributes only have them in their keys
+	date, err := key.ParseTime(bid.Date)
+	if err != nil {
+		date, _ = key.ParseTime(ids[2])
 	}
 
-	date, _ := time.Parse(time.RFC3339, paramsMap["Date"])
-	amount, _ := strconv.ParseInt(bid.Gsi1_sortKey, 10, 64)
+	createDate, err := time.Parse(time.RFC3339Nano, bid.CreateDate)
+	if err != nil {
+		createDateUnix, _ := strconv.ParseInt(sortKeys[2], 10, 64)
+		createDate = time.Unix(createDateUnix, 0)
+	}
 
-	createDateUnix, _ := strconv.ParseInt(paramsMap["CreateDate"], 10, 64)
-	createDate := time.Unix(createDateUnix, 0)
+	amount, _ := strconv.ParseInt(bid.Gsi1_sortKey, 10, 64)
 
 	return Bid{
-		Kind:             paramsMap["Kind"],
-		AwayTeam:         paramsMap["AwayTeam"],
-		HomeTeam:         paramsMap["HomeTeam"],
-		ChosenCompetitor: paramsMap["ChosenCompetitor"],
+		Kind:             ids[1],
+		AwayTeam:         ids[3],
+		HomeTeam:         ids[4],
+		ChosenCompetitor: sortKeys[0],
 		Spread:           bid.Spread,
 		Amount:           amount,
 		Date:             date,
@@ -133,8 +194,9 @@ func (bid DyanmoBidItem) GetItem() database.Item {
 		Week:             bid.Week,
 		HomeAbbreviation: bid.HomeAbbreviation,
 		AwayAbbreviation: bid.AwayAbbreviation,
-		User:             paramsMap["User"],
-		Div:              paramsMap["Div"],
+		User:             sortKeys[1],
+		Div:              ids[0],
+		Season:           bid.Season,
 	}
 }
 
@@ -146,14 +208,19 @@ func (s *BidService) ReleaseLock(ctx context.Context, key string) {
 	s.databaseService.ReleaseLock(ctx, key)
 }
 
+// WriteBidsAndBets publishes BidMatched for the bids it deletes, which carry the amount
+// they were matched for, BidPlaced for the ones it writes and BetCreated for the bets,
+// once they're all written.
 func (s *BidService) WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndBet, waitGroup *sync.WaitGroup) {
 	defer waitGroup.Done()
 
 	putItems := make([]types.WriteRequest, 0, 25)
+	events := make([]event.Event, 0, len(bidsAndBets))
 	for _, item := range bidsAndBets {
 		if item.IsBid {
 			if item.IsDelete {
 				for _, deleteBid := range item.MyBids {
+					events = append(events, event.Event{Type: event.BidMatched, Detail: deleteBid})
 					putItems = append(putItems, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
 						Key: map[string]types.AttributeValue{
 							"id": &types.AttributeValueMemberS{
@@ -166,6 +233,7 @@ func (s *BidService) WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndB
 					}})
 				}
 			} else {
+				events = append(events, event.Event{Type: event.BidPlaced, Detail: item.MyBids[0]})
 				attributeValueMap, _ := attributevalue.MarshalMap(item.MyBids[0].GetDynamoItem())
 				putItems = append(putItems, types.WriteRequest{PutRequest: &types.PutRequest{
 					Item: attributeValueMap,
@@ -173,6 +241,7 @@ func (s *BidService) WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndB
 			}
 		} else {
 			for _, newBet := range item.MyBets {
+				events = append(events, event.Event{Type: event.BetCreated, Detail: newBet})
 				attributeValueMap, _ := attributevalue.MarshalMap(newBet.GetDynamoItem())
 
 				putItems = append(putItems, types.WriteRequest{PutRequest: &types.PutRequest{
@@ -184,10 +253,11 @@ func (s *BidService) WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndB
 	}
 	// for _, putItem := range putItems {
 	// }
+	var written sync.WaitGroup
 	for i := 0; i < len(putItems); i += 25 {
-		waitGroup.Add(1)
+		written.Add(1)
 		go func(myPutItems []types.WriteRequest) {
-			defer waitGroup.Done()
+			defer written.Done()
 			writeRequests := map[string][]types.WriteRequest{}
 			writeRequests[os.Getenv("TABLE_NAME")] = myPutItems
 
@@ -197,10 +267,14 @@ func (s *BidService) WriteBidsAndBets(ctx context.Context, bidsAndBets []BidAndB
 			)
 		}(putItems[i:util.Min(i+25, len(putItems))])
 	}
+	written.Wait()
 
+	event.Emit(ctx, s.publisher, events...)
 }
 
-func (s *BidService) Update(ctx context.Context, updateBid Bid) {
+// Update leaves updateBid with what wasn't matched of it, and publishes it as matched
+// for the matched amount.
+func (s *BidService) Update(ctx context.Context, updateBid Bid, matched int64) {
 	s.databaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		Key: map[string]types.AttributeValue{
 			"id":      &types.AttributeValueMemberS{Value: GetBidDynamoId(updateBid.Div, updateBid.Kind, updateBid.Date, updateBid.AwayTeam, updateBid.HomeTeam)},
@@ -213,11 +287,13 @@ func (s *BidService) Update(ctx context.Context, updateBid Bid) {
 		UpdateExpression:    aws.String("SET gsi1_sortKey = :amount"),
 		ConditionExpression: aws.String("attribute_exists(sortKey)"),
 	})
+	matchedBid := updateBid
+	matchedBid.Amount = matched
+	event.Emit(ctx, s.publisher, event.Event{Type: event.BidMatched, Detail: matchedBid})
 }
 
 func (s *BidService) Delete(ctx context.Context, updateBid Bid) {
 	var dynamoBid = updateBid.GetDynamoItem().(DyanmoBidItem)
-	fmt.Printf("dynamo %+v\n", dynamoBid)
 	s.databaseService.Delete(ctx, &dynamodb.DeleteItemInput{
 		Key: map[string]types.AttributeValue{
 			"id":      &types.AttributeValueMemberS{Value: dynamoBid.Id},
@@ -225,40 +301,46 @@ func (s *BidService) Delete(ctx context.Context, updateBid Bid) {
 		},
 		TableName: aws.String(os.Getenv("TABLE_NAME")),
 	})
+	event.Emit(ctx, s.publisher, event.Event{Type: event.BidCancelled, Detail: updateBid})
 }
 
+// GetBidsByEvent takes an event key from GetEventKey. A chosen team on the end, which
+// older clients send, is ignored.
 func (s *BidService) GetBidsByEvent(ctx context.Context, event string, div string) []Bid {
-	eventExpression := regexp.MustCompile(`(?P<Kind>[^|]+)\|(?P<Date>[^|]+)\|(?P<AwayTeam>[^|]+)\|(?P<HomeTeam>[^|]+)\|?(?P<ChosenCompetitor>[^|]+)?`)
-
-	eventMatch := eventExpression.FindStringSubmatch(event)
-
-	paramsMap := make(map[string]string)
-	for i, name := range eventExpression.SubexpNames() {
-		if i > 0 && i <= len(eventMatch) && eventMatch[i] != "" {
-			paramsMap[name] = eventMatch[i]
-		}
+	parts := key.Split(event)
+	if len(parts) != eventCodec.Parts && len(parts) != eventCodec.Parts+1 {
+		logging.FromContext(ctx).Warn("malformed event key", "event", event)
+		return []Bid{}
 	}
 
-	date, _ := time.Parse(time.RFC3339, paramsMap["Date"])
+	date, _ := key.ParseTime(parts[1])
 
 	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(os.Getenv("TABLE_NAME")),
 		KeyConditionExpression: aws.String("id = :id"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":id": &types.AttributeValueMemberS{Value: GetBidDynamoId(div, paramsMap["Kind"], date, paramsMap["AwayTeam"], paramsMap["HomeTeam"])},
+			":id": &types.AttributeValueMemberS{Value: GetBidDynamoId(div, parts[0], date, parts[2], parts[3])},
 		},
 	})
 }
 
-func (s *BidService) GetBidsByUser(ctx context.Context, user string) []Bid {
-	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
+func userQuery(user string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
 		TableName:              aws.String(os.Getenv("TABLE_NAME")),
 		IndexName:              aws.String("gsi1"),
 		KeyConditionExpression: aws.String("gsi1_id = :id"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":id": &types.AttributeValueMemberS{Value: fmt.Sprintf("BID|%s", user)},
+			":id": &types.AttributeValueMemberS{Value: userCodec.Encode(user)},
 		},
-	})
+	}
+}
+
+func (s *BidService) GetBidsByUser(ctx context.Context, user string) []Bid {
+	return s.databaseService.Query(ctx, userQuery(user))
+}
+
+func (s *BidService) GetBidsByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]Bid, string, error) {
+	return s.databaseService.QueryPaged(ctx, userQuery(user), limit, cursor)
 }
 
 func (s *BidService) WriteBids(ctx context.Context, items []Bid) {
@@ -0,0 +1,112 @@
+package bid
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"sammy.link/bet"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/event"
+)
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	roundTrip := func(div string, kind string, awayTeam string, homeTeam string, user string, amount uint16, seconds uint32, nanos uint32) bool {
+		item := Bid{
+			Div:              div,
+			Kind:             kind,
+			AwayTeam:         awayTeam,
+			HomeTeam:         homeTeam,
+			ChosenCompetitor: homeTeam,
+			User:             user,
+			Amount:           int64(amount),
+			Date:             time.Unix(int64(seconds), 0).UTC(),
+			CreateDate:       time.Unix(int64(seconds), int64(nanos%1e9)).UTC(),
+		}
+		return item.GetDynamoItem().(DyanmoBidItem).GetItem() == item
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetItemWithoutDateAttributes(t *testing.T) {
+	item := DyanmoBidItem{
+		Id:           "B|default|NFL|2023-09-08T00:20:00Z|Detroit Lions|Kansas City Chiefs",
+		SortKey:      "Detroit Lions|sam@sam.com|1693526400",
+		Gsi1_sortKey: "10",
+	}
+
+	bid := item.GetItem().(Bid)
+	if !bid.Date.Equal(time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)) || bid.CreateDate.Unix() != 1693526400 || bid.User != "sam@sam.com" || bid.Amount != 10 {
+		t.Fatalf("older bids should be read from their keys but got %+v", bid)
+	}
+}
+
+type batchWrites struct {
+	database.Service[DyanmoBidItem, Bid]
+	mutex   sync.Mutex
+	written int
+}
+
+func (b *batchWrites) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, requests := range input.RequestItems {
+		b.written += len(requests)
+	}
+}
+
+func (b *batchWrites) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.written++
+}
+
+func TestUpdatePublishesTheMatchedAmount(t *testing.T) {
+	db := &batchWrites{}
+	memory := event.NewMemory(clock.System)
+	date := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	left := Bid{Kind: "nfl", Div: "default", AwayTeam: "Jets", HomeTeam: "Chiefs", ChosenCompetitor: "Jets", User: "away@sam.com", Amount: 15, Date: date, CreateDate: date}
+
+	NewService(db, memory).Update(context.TODO(), left, 5)
+
+	if db.written != 1 {
+		t.Fatalf("wrote %d items", db.written)
+	}
+	if matched := memory.Of(event.BidMatched); len(matched) != 1 || matched[0].Detail.(Bid).Amount != 5 {
+		t.Errorf("should publish the 5 matched but published %+v", matched)
+	}
+}
+
+func TestWriteBidsAndBetsPublishes(t *testing.T) {
+	db := &batchWrites{}
+	memory := event.NewMemory(clock.System)
+	date := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	matched := Bid{Kind: "nfl", Div: "default", AwayTeam: "Jets", HomeTeam: "Chiefs", ChosenCompetitor: "Jets", User: "away@sam.com", Date: date, CreateDate: date}
+	placed := Bid{Kind: "nfl", Div: "default", AwayTeam: "Jets", HomeTeam: "Chiefs", ChosenCompetitor: "Chiefs", User: "home@sam.com", Amount: 5, Date: date, CreateDate: date}
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	NewService(db, memory).WriteBidsAndBets(context.TODO(), []BidAndBet{
+		{MyBids: []Bid{matched}, IsBid: true, IsDelete: true},
+		{MyBets: []bet.Bet{{AwayUser: "away@sam.com", HomeUser: "home@sam.com", Amount: 10}}},
+		{MyBids: []Bid{placed}, IsBid: true},
+	}, &waitGroup)
+	waitGroup.Wait()
+
+	if db.written != 3 {
+		t.Fatalf("wrote %d items", db.written)
+	}
+	if len(memory.Of(event.BidMatched)) != 1 || len(memory.Of(event.BetCreated)) != 1 || len(memory.Of(event.BidPlaced)) != 1 {
+		t.Fatalf("published %+v", memory.Published)
+	}
+	if got := memory.Of(event.BidPlaced)[0].Detail.(Bid); got.User != "home@sam.com" {
+		t.Errorf("BidPlaced = %+v", got)
+	}
+}
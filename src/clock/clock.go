@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"sync"
+	"time"
+	// Lambda images don't ship a zoneinfo database
+	_ "time/tzdata"
+)
+
+const DateFormat = "20060102"
+
+// Eastern is where the game day is decided. ESPN's scoreboard dates and the late
+// games that kick off after midnight UTC both follow it.
+var Eastern = mustLoad("America/New_York")
+
+func mustLoad(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function such as time.Now into a Clock.
+type Func func() time.Time
+
+func (f Func) Now() time.Time {
+	return f()
+}
+
+var System Clock = Func(time.Now)
+
+// Fake is a clock tests move by hand.
+type Fake struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = now
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// GameDay is midnight Eastern on the day t falls on there.
+func GameDay(t time.Time) time.Time {
+	year, month, day := t.In(Eastern).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, Eastern)
+}
+
+// PreviousGameDay is the game day before the one t falls on.
+func PreviousGameDay(t time.Time) time.Time {
+	year, month, day := GameDay(t).Date()
+	return time.Date(year, month, day-1, 0, 0, 0, 0, Eastern)
+}
+
+// NextGameDay is the game day after the one t falls on. Days are built from the
+// calendar, so the ones daylight saving starts or ends on are 23 or 25 hours long.
+func NextGameDay(t time.Time) time.Time {
+	year, month, day := GameDay(t).Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, Eastern)
+}
+
+// GameDate formats the game day t falls on.
+func GameDate(t time.Time) string {
+	return t.In(Eastern).Format(DateFormat)
+}
+
+// OnGameDay reports whether t falls on the game day starting at day.
+func OnGameDay(t time.Time, day time.Time) bool {
+	return !t.Before(GameDay(day)) && t.Before(NextGameDay(day))
+}
+
+// UTCDates are the UTC dates the game day t falls on overlaps, which is always two
+// since Eastern is behind UTC.
+func UTCDates(t time.Time) []string {
+	start := GameDay(t).UTC()
+	last := NextGameDay(t).Add(-time.Nanosecond).UTC()
+
+	dates := []string{start.Format(DateFormat)}
+	if day := last.Format(DateFormat); day != dates[0] {
+		dates = append(dates, day)
+	}
+	return dates
+}
@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGameDay(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		// a Sunday night game kicks off on Monday in UTC
+		{time.Date(2023, 10, 2, 0, 20, 0, 0, time.UTC), "20231001"},
+		{time.Date(2023, 10, 2, 3, 59, 0, 0, time.UTC), "20231001"},
+		{time.Date(2023, 10, 2, 4, 0, 0, 0, time.UTC), "20231002"},
+		// standard time is five hours behind
+		{time.Date(2023, 12, 3, 4, 30, 0, 0, time.UTC), "20231202"},
+		{time.Date(2023, 12, 3, 5, 0, 0, 0, time.UTC), "20231203"},
+	}
+
+	for _, test := range tests {
+		day := GameDay(test.at)
+		if got := day.Format(DateFormat); got != test.want {
+			t.Errorf("GameDay(%v) = %s, want %s", test.at, got, test.want)
+		}
+		if GameDate(test.at) != test.want {
+			t.Errorf("GameDate(%v) = %s, want %s", test.at, GameDate(test.at), test.want)
+		}
+		if day.Hour() != 0 || day.Location() != Eastern {
+			t.Errorf("GameDay(%v) = %v, want midnight Eastern", test.at, day)
+		}
+		if !OnGameDay(test.at, day) || OnGameDay(test.at, NextGameDay(day)) || OnGameDay(test.at, PreviousGameDay(day)) {
+			t.Errorf("%v is not only on %v", test.at, day)
+		}
+	}
+}
+
+func TestDaylightSavingDays(t *testing.T) {
+	// clocks went back an hour on 2023-11-05
+	day := GameDay(time.Date(2023, 11, 5, 12, 0, 0, 0, time.UTC))
+	if length := NextGameDay(day).Sub(day); length != 25*time.Hour {
+		t.Errorf("2023-11-05 lasted %v", length)
+	}
+	if got := PreviousGameDay(NextGameDay(day)); !got.Equal(day) {
+		t.Errorf("PreviousGameDay(NextGameDay(%v)) = %v", day, got)
+	}
+
+	// and forward an hour on 2024-03-10
+	day = GameDay(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	if length := NextGameDay(day).Sub(day); length != 23*time.Hour {
+		t.Errorf("2024-03-10 lasted %v", length)
+	}
+}
+
+func TestUTCDates(t *testing.T) {
+	got := UTCDates(time.Date(2023, 10, 1, 13, 0, 0, 0, time.UTC))
+	if !slices.Equal(got, []string{"20231001", "20231002"}) {
+		t.Errorf("UTCDates = %v", got)
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	fake.Advance(time.Hour)
+	if !fake.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Now() = %v after advancing an hour", fake.Now())
+	}
+
+	fake.Set(start)
+	if !fake.Now().Equal(start) {
+		t.Errorf("Now() = %v after setting it back", fake.Now())
+	}
+}
@@ -0,0 +1,3 @@
+module sammy.link/clock
+
+go 1.21.0
@@ -2,7 +2,7 @@ package database
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"os"
 	"time"
 
@@ -10,7 +10,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"sammy.link/util"
+	"sammy.link/clock"
+	"sammy.link/logging"
 )
 
 type Item interface {
@@ -26,30 +27,40 @@ type Service[D DynamoItem, I Item] interface {
 	Get(ctx context.Context, params *dynamodb.GetItemInput) (I, error)
 	Write(ctx context.Context, items []I)
 	Query(ctx context.Context, params *dynamodb.QueryInput) []I
+	QueryPage(ctx context.Context, params *dynamodb.QueryInput) ([]I, map[string]types.AttributeValue)
+	QueryPaged(ctx context.Context, params *dynamodb.QueryInput, limit int32, cursor string) ([]I, string, error)
+	QueryMerged(ctx context.Context, params []*dynamodb.QueryInput, limit int32, cursor string, less func(I, I) bool) ([]I, string, error)
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput)
+	UpdateReturning(ctx context.Context, params *dynamodb.UpdateItemInput) (I, error)
+	TransactWrite(ctx context.Context, params *dynamodb.TransactWriteItemsInput) error
 	Lock(ctx context.Context, key string)
 	ReleaseLock(ctx context.Context, key string)
 	Delete(ctx context.Context, input *dynamodb.DeleteItemInput)
 }
 
-type DynamoDbService[D DynamoItem, I Item] struct {
-	client *dynamodb.Client
+var ErrNotFound = errors.New("item not found")
+
+// Client is the part of the DynamoDB client the services use, so tests can swap in a fake.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
-var client *dynamodb.Client
-
-func GetDatabaseService[D DynamoItem, I Item](ctx context.Context) *DynamoDbService[D, I] {
-	if client == nil {
-		defaultConfig, err := util.GetAwsConfig(ctx)
-
-		if err != nil {
-			fmt.Println(err.Error())
-		}
+type DynamoDbService[D DynamoItem, I Item] struct {
+	client Client
+	clock  clock.Clock
+}
 
-		client = dynamodb.NewFromConfig(defaultConfig)
-	}
+func NewDatabaseService[D DynamoItem, I Item](client Client, clock clock.Clock) *DynamoDbService[D, I] {
 	return &DynamoDbService[D, I]{
 		client: client,
+		clock:  clock,
 	}
 }
 
@@ -59,18 +70,23 @@ func (s *DynamoDbService[D, I]) Get(ctx context.Context, params *dynamodb.GetIte
 	resp, err := s.client.GetItem(ctx, params)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		logging.FromContext(ctx).Error("get failed", "err", err)
 		return getItem, err
 	}
 
-	err = attributevalue.UnmarshalMap(resp.Item, getItem)
+	if resp.Item == nil {
+		return getItem, ErrNotFound
+	}
+
+	var dynamoItem D
+	err = attributevalue.UnmarshalMap(resp.Item, &dynamoItem)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		logging.FromContext(ctx).Error("unreadable item", "err", err)
 		return getItem, err
 	}
 
-	return getItem, nil
+	return dynamoItem.GetItem().(I), nil
 }
 
 func (s *DynamoDbService[D, I]) Delete(ctx context.Context, input *dynamodb.DeleteItemInput) {
@@ -102,7 +118,7 @@ func (s *DynamoDbService[D, I]) Write(ctx context.Context, items []I) {
 	)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		logging.FromContext(ctx).Error("write failed", "items", len(items), "err", err)
 	}
 }
 
@@ -119,25 +135,104 @@ func (s *DynamoDbService[D, I]) Query(ctx context.Context, params *dynamodb.Quer
 	return itemSlice
 }
 
+// QueryPage runs a single query request and hands back the key to continue from,
+// which is empty once the last page has been read.
+func (s *DynamoDbService[D, I]) QueryPage(ctx context.Context, params *dynamodb.QueryInput) ([]I, map[string]types.AttributeValue) {
+	resp, err := s.client.Query(ctx, params)
+
+	if err != nil {
+		logging.FromContext(ctx).Error("query failed", "err", err)
+		return []I{}, nil
+	}
+
+	var dynamoItems []D
+	attributevalue.UnmarshalListOfMaps(resp.Items, &dynamoItems)
+
+	itemSlice := make([]I, len(dynamoItems))
+	for i, dynamoItem := range dynamoItems {
+		itemSlice[i] = dynamoItem.GetItem().(I)
+	}
+
+	return itemSlice, resp.LastEvaluatedKey
+}
+
 func (s *DynamoDbService[D, I]) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput) {
 	_, err := s.client.UpdateItem(ctx, params)
 
 	if err != nil {
-		fmt.Println(err.Error())
+		logging.FromContext(ctx).Error("update failed", "err", err)
 	}
 }
 
+// UpdateReturning is UpdateItem for callers that need the item as the update left it.
+func (s *DynamoDbService[D, I]) UpdateReturning(ctx context.Context, params *dynamodb.UpdateItemInput) (I, error) {
+	var updated I
+	params.ReturnValues = types.ReturnValueAllNew
+
+	resp, err := s.client.UpdateItem(ctx, params)
+	if ConditionFailed(err) {
+		// the caller's condition, theirs to report
+		return updated, err
+	} else if err != nil {
+		logging.FromContext(ctx).Error("update failed", "err", err)
+		return updated, err
+	}
+
+	var dynamoItem D
+	if err := attributevalue.UnmarshalMap(resp.Attributes, &dynamoItem); err != nil {
+		logging.FromContext(ctx).Error("unreadable item", "err", err)
+		return updated, err
+	}
+
+	return dynamoItem.GetItem().(I), nil
+}
+
+// TransactWrite hands back the error, unlike the other writes, since a cancelled
+// transaction is usually a condition the caller has to report.
+func (s *DynamoDbService[D, I]) TransactWrite(ctx context.Context, params *dynamodb.TransactWriteItemsInput) error {
+	_, err := s.client.TransactWriteItems(ctx, params)
+	return err
+}
+
+// ConditionFailed is whether err is a write's condition not holding.
+func ConditionFailed(err error) bool {
+	var failed *types.ConditionalCheckFailedException
+	return errors.As(err, &failed)
+}
+
+// CancelledBy reports which of a cancelled transaction's items failed their condition,
+// by their position in the transaction.
+func CancelledBy(err error) []int {
+	var cancelled *types.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		return nil
+	}
+
+	failed := make([]int, 0)
+	for i, reason := range cancelled.CancellationReasons {
+		if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
 type LockItem struct {
 	Id      string `dynamodbav:"id"`
 	SortKey string `dynamodbav:"sortKey"`
 	Ttl     int64  `dynamodbav:"ttl"`
+	Version int    `dynamodbav:"v"`
 }
 
-func GetLock(ctx context.Context, key string, client *dynamodb.Client) error {
+// lockTtl is how long a lock outlives a holder that never released it.
+const lockTtl = time.Minute
+
+func GetLock(ctx context.Context, key string, client Client, now time.Time) error {
 	av, _ := attributevalue.MarshalMap(LockItem{
 		Id:      "LOCK",
 		SortKey: key,
-		Ttl:     time.Now().Add(time.Duration(6e+10)).Unix(),
+		Ttl:     now.Add(lockTtl).Unix(),
+		Version: Version,
 	})
 
 	input := &dynamodb.PutItemInput{
@@ -156,7 +251,7 @@ func GetLock(ctx context.Context, key string, client *dynamodb.Client) error {
 
 func (s *DynamoDbService[D, I]) Lock(ctx context.Context, key string) {
 	for {
-		err := GetLock(ctx, key, s.client)
+		err := GetLock(ctx, key, s.client, s.clock.Now())
 		if err != nil {
 			time.Sleep(2 * time.Second)
 		} else {
@@ -175,28 +270,33 @@ func (s *DynamoDbService[D, I]) ReleaseLock(ctx context.Context, key string) {
 	})
 }
 
-func Query[D DynamoItem](ctx context.Context, client *dynamodb.Client, input *dynamodb.QueryInput) []D {
+func Query[D DynamoItem](ctx context.Context, client Client, input *dynamodb.QueryInput) []D {
 
 	dynamoItems := executeQuery[D](ctx, client, input)
 
 	return dynamoItems
 }
 
-func executeQuery[D DynamoItem](ctx context.Context, client *dynamodb.Client, input *dynamodb.QueryInput) []D {
-	resp, err := client.Query(ctx, input)
+// executeQuery follows LastEvaluatedKey on a copy of input so callers can reuse theirs.
+func executeQuery[D DynamoItem](ctx context.Context, client Client, input *dynamodb.QueryInput) []D {
+	params := *input
+	items := make([]D, 0)
 
-	if err != nil {
-		fmt.Println(err.Error())
-	}
+	for {
+		resp, err := client.Query(ctx, &params)
 
-	var items []D
+		if err != nil {
+			logging.FromContext(ctx).Error("query failed", "err", err)
+			return items
+		}
 
-	attributevalue.UnmarshalListOfMaps(resp.Items, &items)
+		var page []D
+		attributevalue.UnmarshalListOfMaps(resp.Items, &page)
+		items = append(items, page...)
 
-	if len(resp.LastEvaluatedKey) > 0 {
-		input.ExclusiveStartKey = resp.LastEvaluatedKey
-		return append(items, executeQuery[D](ctx, client, input)...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			return items
+		}
+		params.ExclusiveStartKey = resp.LastEvaluatedKey
 	}
-
-	return items
 }
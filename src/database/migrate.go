@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/logging"
+)
+
+// Version is the schema version every DynamoItem is written at, in the "v"
+// attribute. Items written before versioning have no "v" and read as 0. Bump it
+// together with the read-side upgrade in each GetItem whenever an attribute is
+// renamed or a key format changes, then run the migrate command.
+//
+// Version 2 put listings on gsi3 by event id. Version 3 escaped the separator in
+// leaderboard, season and history keys.
+const Version = 3
+
+// Upgrader rewrites a raw item at the current version.
+type Upgrader func(raw map[string]types.AttributeValue) (map[string]types.AttributeValue, error)
+
+// Upgrade reads raw as D, which upgrades it in GetItem, and writes it back out.
+func Upgrade[D DynamoItem](raw map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+	var dynamoItem D
+	if err := attributevalue.UnmarshalMap(raw, &dynamoItem); err != nil {
+		return nil, err
+	}
+	return attributevalue.MarshalMap(dynamoItem.GetItem().GetDynamoItem())
+}
+
+// Migration picks the upgrader for the items whose id starts with Prefix. Match
+// narrows it further when several entities share a prefix.
+type Migration struct {
+	Prefix  string
+	Match   func(id string, sortKey string) bool
+	Upgrade Upgrader
+}
+
+type MigrationClient interface {
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+type MigrationStats struct {
+	Scanned  int
+	Current  int
+	Upgraded int
+	Moved    int
+	Skipped  int
+	Failed   int
+}
+
+// Migrator scans the table page by page and rewrites items below Version. With
+// DryRun set nothing is written. Checkpoint is called with the key to resume from
+// after each page, and with an empty string once the scan is done.
+type Migrator struct {
+	Client     MigrationClient
+	Table      string
+	Migrations []Migration
+	DryRun     bool
+	PageSize   int32
+	Checkpoint func(resume string) error
+}
+
+func (m *Migrator) Run(ctx context.Context, resume string) (MigrationStats, error) {
+	stats := MigrationStats{}
+
+	startKey, err := DecodeResume(resume)
+	if err != nil {
+		return stats, err
+	}
+
+	for {
+		input := &dynamodb.ScanInput{
+			TableName:         aws.String(m.Table),
+			ExclusiveStartKey: startKey,
+		}
+		if m.PageSize > 0 {
+			input.Limit = aws.Int32(m.PageSize)
+		}
+
+		resp, err := m.Client.Scan(ctx, input)
+		if err != nil {
+			return stats, err
+		}
+
+		for _, raw := range resp.Items {
+			stats.Scanned++
+			m.migrate(ctx, raw, &stats)
+		}
+
+		startKey = resp.LastEvaluatedKey
+		next := EncodeResume(startKey)
+		if m.Checkpoint != nil {
+			if err := m.Checkpoint(next); err != nil {
+				return stats, err
+			}
+		}
+
+		if next == "" {
+			return stats, nil
+		}
+	}
+}
+
+func (m *Migrator) migrate(ctx context.Context, raw map[string]types.AttributeValue, stats *MigrationStats) {
+	if VersionOf(raw) >= Version {
+		stats.Current++
+		return
+	}
+
+	id, sortKey := stringAttribute(raw, "id"), stringAttribute(raw, "sortKey")
+	migration, ok := m.find(id, sortKey)
+	if !ok {
+		stats.Skipped++
+		return
+	}
+
+	logger := logging.FromContext(ctx).With("id", id, "sortKey", sortKey)
+
+	upgraded, err := migration.Upgrade(raw)
+	if err != nil {
+		logger.Error("upgrade failed", "err", err)
+		stats.Failed++
+		return
+	}
+
+	newId, newSortKey := stringAttribute(upgraded, "id"), stringAttribute(upgraded, "sortKey")
+	moved := newId != id || newSortKey != sortKey
+
+	if m.DryRun {
+		logger.Info("would upgrade", "newId", newId, "newSortKey", newSortKey)
+	} else {
+		_, err = m.Client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(m.Table),
+			Item:      upgraded,
+		})
+		if err == nil && moved {
+			_, err = m.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(m.Table),
+				Key: map[string]types.AttributeValue{
+					"id":      raw["id"],
+					"sortKey": raw["sortKey"],
+				},
+			})
+		}
+		if err != nil {
+			logger.Error("write failed", "err", err)
+			stats.Failed++
+			return
+		}
+	}
+
+	stats.Upgraded++
+	if moved {
+		stats.Moved++
+	}
+}
+
+func (m *Migrator) find(id string, sortKey string) (Migration, bool) {
+	for _, migration := range m.Migrations {
+		if !strings.HasPrefix(id, migration.Prefix) {
+			continue
+		}
+		if migration.Match == nil || migration.Match(id, sortKey) {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// VersionOf reads the schema version of a raw item.
+func VersionOf(raw map[string]types.AttributeValue) int {
+	if v, ok := raw["v"].(*types.AttributeValueMemberN); ok {
+		version, _ := strconv.Atoi(v.Value)
+		return version
+	}
+	return 0
+}
+
+func stringAttribute(raw map[string]types.AttributeValue, name string) string {
+	if s, ok := raw[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+// EncodeResume turns the key a scan stopped at into a token for the next run.
+// Every key attribute in the table is a string.
+func EncodeResume(key map[string]types.AttributeValue) string {
+	if len(key) == 0 {
+		return ""
+	}
+	id, sortKey := stringAttribute(key, "id"), stringAttribute(key, "sortKey")
+	return strconv.Quote(id) + " " + strconv.Quote(sortKey)
+}
+
+func DecodeResume(resume string) (map[string]types.AttributeValue, error) {
+	if resume == "" {
+		return nil, nil
+	}
+
+	id, rest, err := unquotePrefix(strings.TrimSpace(resume))
+	if err != nil {
+		return nil, fmt.Errorf("invalid resume token %q", resume)
+	}
+	sortKey, _, err := unquotePrefix(strings.TrimSpace(rest))
+	if err != nil {
+		return nil, fmt.Errorf("invalid resume token %q", resume)
+	}
+
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: id},
+		"sortKey": &types.AttributeValueMemberS{Value: sortKey},
+	}, nil
+}
+
+func unquotePrefix(s string) (string, string, error) {
+	quoted, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := strconv.Unquote(quoted)
+	return value, s[len(quoted):], err
+}
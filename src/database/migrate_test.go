@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type migrateItem struct {
+	Name string
+}
+
+type migrateDynamoItem struct {
+	Id         string `dynamodbav:"id"`
+	SortKey    string `dynamodbav:"sortKey"`
+	Name       string `dynamodbav:"name"`
+	Version    int    `dynamodbav:"v"`
+	LegacyName string `dynamodbav:"na,omitempty"`
+}
+
+func (item migrateItem) GetDynamoItem() DynamoItem {
+	return migrateDynamoItem{Id: "T|" + item.Name, SortKey: "T", Name: item.Name, Version: Version}
+}
+
+func (item migrateDynamoItem) GetItem() Item {
+	if item.Version < 1 && item.Name == "" {
+		item.Name = item.LegacyName
+	}
+	return migrateItem{Name: item.Name}
+}
+
+// fakeMigrationClient scans its items in order, pageSize at a time.
+type fakeMigrationClient struct {
+	items    []map[string]types.AttributeValue
+	pageSize int
+	puts     []map[string]types.AttributeValue
+	deletes  []map[string]types.AttributeValue
+}
+
+func (c *fakeMigrationClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	offset := 0
+	if params.ExclusiveStartKey != nil {
+		for offset < len(c.items) && EncodeResume(c.items[offset]) != EncodeResume(params.ExclusiveStartKey) {
+			offset++
+		}
+		offset++
+	}
+
+	end := min(offset+c.pageSize, len(c.items))
+	out := &dynamodb.ScanOutput{Items: c.items[offset:end]}
+	if end < len(c.items) {
+		last := c.items[end-1]
+		out.LastEvaluatedKey = map[string]types.AttributeValue{"id": last["id"], "sortKey": last["sortKey"]}
+	}
+	return out, nil
+}
+
+func (c *fakeMigrationClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.puts = append(c.puts, params.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (c *fakeMigrationClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.deletes = append(c.deletes, params.Key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func rawItem(t *testing.T, item migrateDynamoItem) map[string]types.AttributeValue {
+	raw, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Version == 0 {
+		delete(raw, "v")
+	}
+	return raw
+}
+
+func newFakeMigrationClient(t *testing.T) *fakeMigrationClient {
+	return &fakeMigrationClient{
+		pageSize: 2,
+		items: []map[string]types.AttributeValue{
+			rawItem(t, migrateDynamoItem{Id: "T|a", SortKey: "T", LegacyName: "a"}),
+			rawItem(t, migrateDynamoItem{Id: "T|b", SortKey: "T", Name: "b", Version: Version}),
+			// written under an old key
+			rawItem(t, migrateDynamoItem{Id: "OLD|c", SortKey: "T", LegacyName: "c"}),
+			rawItem(t, migrateDynamoItem{Id: "X|d", SortKey: "T"}),
+		},
+	}
+}
+
+var testMigrations = []Migration{
+	{Prefix: "T|", Upgrade: Upgrade[migrateDynamoItem]},
+	{Prefix: "OLD|", Upgrade: Upgrade[migrateDynamoItem]},
+}
+
+func TestMigratorRun(t *testing.T) {
+	client := newFakeMigrationClient(t)
+	checkpoints := make([]string, 0)
+	migrator := &Migrator{
+		Client:     client,
+		Table:      "table",
+		Migrations: testMigrations,
+		Checkpoint: func(resume string) error {
+			checkpoints = append(checkpoints, resume)
+			return nil
+		},
+	}
+
+	stats, err := migrator.Run(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := MigrationStats{Scanned: 4, Current: 1, Upgraded: 2, Moved: 1, Skipped: 1}
+	if stats != want {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+
+	if len(client.puts) != 2 {
+		t.Fatalf("puts = %d, want 2", len(client.puts))
+	}
+	for _, put := range client.puts {
+		if VersionOf(put) != Version {
+			t.Errorf("put %v at version %d", put, VersionOf(put))
+		}
+		if _, ok := put["na"]; ok {
+			t.Errorf("put %v kept the legacy attribute", put)
+		}
+	}
+	if name := stringAttribute(client.puts[1], "id"); name != "T|c" {
+		t.Errorf("moved item written to %s", name)
+	}
+
+	if len(client.deletes) != 1 || stringAttribute(client.deletes[0], "id") != "OLD|c" {
+		t.Errorf("deletes = %v, want OLD|c", client.deletes)
+	}
+
+	if len(checkpoints) != 2 || checkpoints[1] != "" {
+		t.Errorf("checkpoints = %q", checkpoints)
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	client := newFakeMigrationClient(t)
+	migrator := &Migrator{Client: client, Table: "table", Migrations: testMigrations, DryRun: true}
+
+	stats, err := migrator.Run(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Upgraded != 2 || len(client.puts) != 0 || len(client.deletes) != 0 {
+		t.Errorf("dry run wrote: stats = %+v, puts = %d, deletes = %d", stats, len(client.puts), len(client.deletes))
+	}
+}
+
+func TestMigratorResume(t *testing.T) {
+	client := newFakeMigrationClient(t)
+	migrator := &Migrator{Client: client, Table: "table", Migrations: testMigrations}
+
+	stats, err := migrator.Run(context.Background(), EncodeResume(client.items[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Scanned != 2 || stats.Upgraded != 1 {
+		t.Errorf("stats = %+v, want the last two items", stats)
+	}
+
+	if _, err := migrator.Run(context.Background(), "not a token"); err == nil {
+		t.Error("expected an invalid token to fail")
+	}
+}
+
+func TestResumeRoundTrip(t *testing.T) {
+	for _, sortKey := range []string{"", "T", `a "quoted" | key`, "with space"} {
+		key := map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: "T|" + sortKey},
+			"sortKey": &types.AttributeValueMemberS{Value: sortKey},
+		}
+
+		decoded, err := DecodeResume(EncodeResume(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if EncodeResume(decoded) != EncodeResume(key) || !strings.HasPrefix(stringAttribute(decoded, "id"), "T|") {
+			t.Errorf("%v round tripped to %v", key, decoded)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sourceCursor is where one query of a page left off. A nil Key with Done unset
+// means the query hasn't been read yet.
+type sourceCursor struct {
+	Key  map[string]string `json:"k,omitempty"`
+	Done bool              `json:"d,omitempty"`
+}
+
+// QueryPaged returns at most limit items from params and a cursor for the next page,
+// which is empty once there is nothing left to read. A limit of zero reads everything.
+func (s *DynamoDbService[D, I]) QueryPaged(ctx context.Context, params *dynamodb.QueryInput, limit int32, cursor string) ([]I, string, error) {
+	return s.QueryMerged(ctx, []*dynamodb.QueryInput{params}, limit, cursor, nil)
+}
+
+// QueryMerged pages through several queries as if they were one. Each query must
+// already be ordered by less; a nil less reads the queries one after another.
+func (s *DynamoDbService[D, I]) QueryMerged(ctx context.Context, params []*dynamodb.QueryInput, limit int32, cursor string, less func(I, I) bool) ([]I, string, error) {
+	starts, err := decodeCursor(params, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fetch := func(i int, start map[string]types.AttributeValue, pageLimit int32) ([]I, map[string]types.AttributeValue) {
+		input := *params[i]
+		input.ExclusiveStartKey = start
+		if pageLimit > 0 {
+			input.Limit = aws.Int32(pageLimit)
+		}
+		return s.QueryPage(ctx, &input)
+	}
+
+	keyOf := func(i int, item I) map[string]types.AttributeValue {
+		return KeyOf(item, aws.ToString(params[i].IndexName))
+	}
+
+	items, ends := merge(fetch, keyOf, starts, limit, less)
+	return items, encodeCursor(params, ends), nil
+}
+
+func merge[I Item](fetch func(int, map[string]types.AttributeValue, int32) ([]I, map[string]types.AttributeValue), keyOf func(int, I) map[string]types.AttributeValue, starts []sourceCursor, limit int32, less func(I, I) bool) ([]I, []sourceCursor) {
+	type source struct {
+		buffer    []I
+		next      map[string]types.AttributeValue
+		resume    map[string]types.AttributeValue
+		exhausted bool
+	}
+
+	sources := make([]source, len(starts))
+	for i, start := range starts {
+		sources[i].next = toAttributeValues(start.Key)
+		sources[i].resume = sources[i].next
+		sources[i].exhausted = start.Done
+	}
+
+	items := make([]I, 0)
+	for limit == 0 || int32(len(items)) < limit {
+		best := -1
+		for i := range sources {
+			src := &sources[i]
+			for len(src.buffer) == 0 && !src.exhausted {
+				remaining := int32(0)
+				if limit > 0 {
+					remaining = limit - int32(len(items))
+				}
+				src.resume = src.next
+				src.buffer, src.next = fetch(i, src.next, remaining)
+				src.exhausted = len(src.next) == 0
+			}
+
+			if len(src.buffer) > 0 && (best == -1 || (less != nil && less(src.buffer[0], sources[best].buffer[0]))) {
+				best = i
+			}
+
+			// read one after another, so later queries wait until they're needed
+			if less == nil && best != -1 {
+				break
+			}
+		}
+
+		if best == -1 {
+			break
+		}
+
+		src := &sources[best]
+		items = append(items, src.buffer[0])
+		src.buffer = src.buffer[1:]
+		if len(src.buffer) == 0 {
+			src.resume = src.next
+		} else {
+			src.resume = keyOf(best, items[len(items)-1])
+		}
+	}
+
+	ends := make([]sourceCursor, len(sources))
+	for i, src := range sources {
+		if len(src.buffer) == 0 && src.exhausted {
+			ends[i].Done = true
+		} else {
+			ends[i].Key = fromAttributeValues(src.resume)
+		}
+	}
+	return items, ends
+}
+
+// KeyOf rebuilds the table and index keys of an item so a query can resume right
+// after it. Index keys follow the table's gsiN_id and gsiN_sortKey naming.
+func KeyOf(item Item, index string) map[string]types.AttributeValue {
+	attributes, _ := attributevalue.MarshalMap(item.GetDynamoItem())
+
+	names := []string{"id", "sortKey"}
+	if index != "" {
+		names = append(names, index+"_id", index+"_sortKey")
+	}
+
+	key := make(map[string]types.AttributeValue)
+	for _, name := range names {
+		if value, ok := attributes[name]; ok {
+			key[name] = value
+		}
+	}
+	return key
+}
+
+// encodeCursor signs where each query stopped, along with the queries themselves so
+// a cursor can't be replayed against someone else's partition. It's empty when every
+// query has been read to the end.
+func encodeCursor(params []*dynamodb.QueryInput, ends []sourceCursor) string {
+	finished := true
+	for _, end := range ends {
+		finished = finished && end.Done
+	}
+	if finished {
+		return ""
+	}
+
+	payload, _ := json.Marshal(ends)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(params, encoded)
+}
+
+func decodeCursor(params []*dynamodb.QueryInput, cursor string) ([]sourceCursor, error) {
+	if cursor == "" {
+		return make([]sourceCursor, len(params)), nil
+	}
+
+	encoded, signature, found := strings.Cut(cursor, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(sign(params, encoded))) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var ends []sourceCursor
+	if err := json.Unmarshal(payload, &ends); err != nil || len(ends) != len(params) {
+		return nil, ErrInvalidCursor
+	}
+	return ends, nil
+}
+
+func sign(params []*dynamodb.QueryInput, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("CURSOR_SECRET")))
+	for _, input := range params {
+		mac.Write([]byte(scopeOf(input)))
+	}
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func scopeOf(input *dynamodb.QueryInput) string {
+	values := make([]string, 0, len(input.ExpressionAttributeValues))
+	for name, value := range input.ExpressionAttributeValues {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			values = append(values, name+"=S"+v.Value)
+		case *types.AttributeValueMemberN:
+			values = append(values, name+"=N"+v.Value)
+		}
+	}
+	sort.Strings(values)
+
+	return fmt.Sprintf("%s\n%s\n%s\n%s\n", aws.ToString(input.IndexName), aws.ToString(input.KeyConditionExpression), aws.ToString(input.FilterExpression), strings.Join(values, "\n"))
+}
+
+func toAttributeValues(key map[string]string) map[string]types.AttributeValue {
+	if key == nil {
+		return nil
+	}
+
+	values := make(map[string]types.AttributeValue)
+	for name, value := range key {
+		values[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return values
+}
+
+// fromAttributeValues only keeps strings, every key attribute in the table is one.
+func fromAttributeValues(key map[string]types.AttributeValue) map[string]string {
+	if key == nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for name, value := range key {
+		if s, ok := value.(*types.AttributeValueMemberS); ok {
+			values[name] = s.Value
+		}
+	}
+	return values
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type testItem struct {
+	Id      string
+	SortKey int
+}
+
+type testDynamoItem struct {
+	Id      string `dynamodbav:"id"`
+	SortKey string `dynamodbav:"sortKey"`
+}
+
+func (item testItem) GetDynamoItem() DynamoItem {
+	return testDynamoItem{Id: item.Id, SortKey: strconv.Itoa(item.SortKey)}
+}
+
+func (item testDynamoItem) GetItem() Item {
+	sortKey, _ := strconv.Atoi(item.SortKey)
+	return testItem{Id: item.Id, SortKey: sortKey}
+}
+
+// fakeTable serves each query's items in pages of at most pageSize, the way DynamoDB
+// stops early when a response gets too big.
+func fakeTable(data [][]testItem, pageSize int, reads *int) func(int, map[string]types.AttributeValue, int32) ([]testItem, map[string]types.AttributeValue) {
+	return func(i int, start map[string]types.AttributeValue, limit int32) ([]testItem, map[string]types.AttributeValue) {
+		*reads++
+		offset := 0
+		if start != nil {
+			last, _ := strconv.Atoi(start["sortKey"].(*types.AttributeValueMemberS).Value)
+			for offset < len(data[i]) && data[i][offset].SortKey <= last {
+				offset++
+			}
+		}
+
+		end := min(offset+pageSize, len(data[i]))
+		if limit > 0 {
+			end = min(end, offset+int(limit))
+		}
+
+		page := data[i][offset:end]
+		if end == len(data[i]) {
+			return page, nil
+		}
+		return page, KeyOf(page[len(page)-1], "")
+	}
+}
+
+func keyOf(i int, item testItem) map[string]types.AttributeValue {
+	return KeyOf(item, "")
+}
+
+func TestMergeReadsInOrderAcrossPages(t *testing.T) {
+	data := [][]testItem{
+		{{"a", 1}, {"a", 4}, {"a", 5}, {"a", 8}},
+		{{"b", 2}, {"b", 3}, {"b", 6}, {"b", 7}, {"b", 9}},
+	}
+	less := func(one testItem, two testItem) bool { return one.SortKey < two.SortKey }
+
+	reads := 0
+	starts := make([]sourceCursor, 2)
+	seen := make([]int, 0)
+	for page := 0; page < 10; page++ {
+		items, ends := merge(fakeTable(data, 2, &reads), keyOf, starts, 4, less)
+		for _, item := range items {
+			seen = append(seen, item.SortKey)
+		}
+		if ends[0].Done && ends[1].Done {
+			break
+		}
+		starts = ends
+	}
+
+	for i, sortKey := range seen {
+		if sortKey != i+1 {
+			t.Fatalf("should read 1 through 9 exactly once but got %v", seen)
+		}
+	}
+	if len(seen) != 9 {
+		t.Fatalf("should read all 9 items but got %v", seen)
+	}
+}
+
+func TestMergeWithoutOrderReadsOneQueryAtATime(t *testing.T) {
+	data := [][]testItem{
+		{{"a", 1}, {"a", 2}},
+		{{"b", 1}, {"b", 2}},
+	}
+
+	reads := 0
+	items, ends := merge(fakeTable(data, 10, &reads), keyOf, make([]sourceCursor, 2), 2, nil)
+	if len(items) != 2 || items[1].Id != "a" || reads != 1 {
+		t.Fatalf("the second query shouldn't be read until the first is done but got %+v after %d reads", items, reads)
+	}
+
+	if !ends[0].Done || ends[1].Done || ends[1].Key != nil {
+		t.Fatalf("should pick up the second query from the start but got %+v", ends)
+	}
+
+	items, ends = merge(fakeTable(data, 10, &reads), keyOf, ends, 2, nil)
+	if len(items) != 2 || items[0].Id != "b" || !ends[1].Done {
+		t.Fatalf("should finish with the second query but got %+v", items)
+	}
+}
+
+func TestCursorIsSignedAndScoped(t *testing.T) {
+	os.Setenv("CURSOR_SECRET", "test")
+
+	query := func(user string) []*dynamodb.QueryInput {
+		return []*dynamodb.QueryInput{{
+			IndexName:              aws.String("gsi1"),
+			KeyConditionExpression: aws.String("gsi1_id = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":id": &types.AttributeValueMemberS{Value: user},
+			},
+		}}
+	}
+
+	cursor := encodeCursor(query("sam@sam.com"), []sourceCursor{{Key: map[string]string{"id": "BID|sam@sam.com", "sortKey": "1"}}})
+
+	ends, err := decodeCursor(query("sam@sam.com"), cursor)
+	if err != nil || ends[0].Key["sortKey"] != "1" {
+		t.Fatalf("should read back its own cursor but got %+v %v", ends, err)
+	}
+
+	if _, err := decodeCursor(query("greg@greg.com"), cursor); err != ErrInvalidCursor {
+		t.Fatalf("a cursor shouldn't work against another user's query")
+	}
+
+	if _, err := decodeCursor(query("sam@sam.com"), "eyJrIjp7fX0."+cursor[len(cursor)-10:]); err != ErrInvalidCursor {
+		t.Fatalf("a tampered cursor should be rejected")
+	}
+
+	if cursor := encodeCursor(query("sam@sam.com"), []sourceCursor{{Done: true}}); cursor != "" {
+		t.Fatalf("there shouldn't be a cursor after the last page but got %s", cursor)
+	}
+}
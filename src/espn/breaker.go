@@ -0,0 +1,71 @@
+package espn
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("espn: circuit open, skipping request")
+
+// Breaker stops calls to ESPN after too many failures in a row and lets a single
+// trial call through once the cooldown has passed.
+type Breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *Breaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+func (b *Breaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *Breaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+func (b *Breaker) Open() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.failures >= b.threshold
+}
@@ -0,0 +1,305 @@
+package espn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/logging"
+)
+
+type CacheEntry struct {
+	Key      string
+	Response EspnResponse
+	Fetched  time.Time
+	// Expires is when the entry goes stale, Until is when it can no longer be served at all.
+	Expires time.Time
+	Until   time.Time
+}
+
+type Store interface {
+	Get(ctx context.Context, key string) (CacheEntry, bool)
+	Set(ctx context.Context, entry CacheEntry)
+}
+
+type CacheConfig struct {
+	LiveTtl     time.Duration
+	UpcomingTtl time.Duration
+	FinalTtl    time.Duration
+	// StaleFor is how long an expired entry is still served when refreshing it fails.
+	StaleFor time.Duration
+}
+
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		LiveTtl:     30 * time.Second,
+		UpcomingTtl: 10 * time.Minute,
+		FinalTtl:    6 * time.Hour,
+		StaleFor:    time.Hour,
+	}
+}
+
+type CachedService struct {
+	service    Service
+	stores     []Store
+	config     CacheConfig
+	clock      clock.Clock
+	refreshing sync.Map
+}
+
+func NewCachedService(service Service, config CacheConfig, clk clock.Clock, stores ...Store) Service {
+	return &CachedService{
+		service: service,
+		stores:  stores,
+		config:  config,
+		clock:   clk,
+	}
+}
+
+var (
+	// cache keys name the request, data or event, then the sport, league and dates or event id
+	cacheKeyCodec = key.Codec{Parts: 4}
+	cacheIdCodec  = key.Codec{Prefix: "ESPN", Parts: 4}
+)
+
+func (s *CachedService) ConvertKind(kind string) Kind {
+	return s.service.ConvertKind(kind)
+}
+
+func (s *CachedService) GetEspnData(ctx context.Context, sport string, league string, date time.Time, secondDate time.Time) (EspnResponse, error) {
+	cacheKey := cacheKeyCodec.Encode("data", sport, league, fmt.Sprintf("%s-%s", clock.GameDate(date), clock.GameDate(secondDate)))
+	return s.get(ctx, cacheKey, func(ctx context.Context) (EspnResponse, error) {
+		return s.service.GetEspnData(ctx, sport, league, date, secondDate)
+	})
+}
+
+func (s *CachedService) GetEspnEvent(ctx context.Context, sport string, league string, eventId string) (EspnResponse, error) {
+	cacheKey := cacheKeyCodec.Encode("event", sport, league, eventId)
+	return s.get(ctx, cacheKey, func(ctx context.Context) (EspnResponse, error) {
+		return s.service.GetEspnEvent(ctx, sport, league, eventId)
+	})
+}
+
+func (s *CachedService) get(ctx context.Context, key string, fetch func(ctx context.Context) (EspnResponse, error)) (EspnResponse, error) {
+	now := s.clock.Now()
+	entry, found := s.lookup(ctx, key)
+
+	if found && now.Before(entry.Expires) {
+		return entry.Response, nil
+	}
+
+	if found && now.Before(entry.Until) {
+		return s.revalidate(ctx, key, entry, fetch), nil
+	}
+
+	resp, err := fetch(ctx)
+	if err != nil {
+		return resp, err
+	}
+
+	s.store(ctx, key, resp)
+	return resp, nil
+}
+
+// lookup checks each store in order and copies a hit into the faster stores in front of it.
+func (s *CachedService) lookup(ctx context.Context, key string) (CacheEntry, bool) {
+	for i, store := range s.stores {
+		if entry, ok := store.Get(ctx, key); ok {
+			for _, faster := range s.stores[:i] {
+				faster.Set(ctx, entry)
+			}
+			return entry, true
+		}
+	}
+	return CacheEntry{}, false
+}
+
+func (s *CachedService) store(ctx context.Context, key string, resp EspnResponse) {
+	now := s.clock.Now()
+	expires := now.Add(s.ttl(resp))
+	entry := CacheEntry{
+		Key:      key,
+		Response: resp,
+		Fetched:  now,
+		Expires:  expires,
+		Until:    expires.Add(s.config.StaleFor),
+	}
+
+	for _, store := range s.stores {
+		store.Set(ctx, entry)
+	}
+}
+
+// revalidate refreshes a stale entry before answering with it, since a lambda is frozen
+// as soon as it returns and would never finish a refresh left running behind it. The stale
+// entry is served instead when the refresh fails or another caller is already making it.
+func (s *CachedService) revalidate(ctx context.Context, key string, stale CacheEntry, fetch func(ctx context.Context) (EspnResponse, error)) EspnResponse {
+	if _, running := s.refreshing.LoadOrStore(key, true); running {
+		return stale.Response
+	}
+	defer s.refreshing.Delete(key)
+
+	resp, err := fetch(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cache refresh failed", "key", key, "err", err)
+		return stale.Response
+	}
+	s.store(ctx, key, resp)
+	return resp
+}
+
+// ttl keeps live games fresh and holds on to finished ones for much longer.
+func (s *CachedService) ttl(resp EspnResponse) time.Duration {
+	if len(resp.Sports) == 0 {
+		return s.config.UpcomingTtl
+	}
+
+	ttl := s.config.FinalTtl
+	for _, espnSport := range resp.Sports {
+		for _, league := range espnSport.Leagues {
+			for _, event := range league.Events {
+				switch event.Status {
+				case "post":
+				case "in":
+					return s.config.LiveTtl
+				default:
+					ttl = min(ttl, s.config.UpcomingTtl)
+				}
+			}
+		}
+	}
+	return ttl
+}
+
+type MemoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]CacheEntry
+	clock   clock.Clock
+}
+
+// NewMemoryStore keeps entries for as long as the process lives, so a warm lambda
+// keeps what it already fetched.
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]CacheEntry),
+		clock:   clk,
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (CacheEntry, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.entries[key]
+	if ok && !m.clock.Now().Before(entry.Until) {
+		delete(m.entries, key)
+		return CacheEntry{}, false
+	}
+	return entry, ok
+}
+
+func (m *MemoryStore) Set(ctx context.Context, entry CacheEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries[entry.Key] = entry
+}
+
+type CacheItem struct {
+	CacheEntry
+}
+
+type CacheDynamoItem struct {
+	Id       string `dynamodbav:"id"`
+	SortKey  string `dynamodbav:"sortKey"`
+	Response string `dynamodbav:"response"`
+	Fetched  string `dynamodbav:"fetched"`
+	Expires  string `dynamodbav:"expires"`
+	Ttl      int64  `dynamodbav:"ttl"`
+	Version  int    `dynamodbav:"v"`
+}
+
+func (item CacheItem) GetDynamoItem() database.DynamoItem {
+	response, _ := json.Marshal(item.Response)
+	return CacheDynamoItem{
+		Id:       getCacheId(item.Key),
+		SortKey:  "CACHE",
+		Response: string(response),
+		Fetched:  item.Fetched.Format(time.RFC3339),
+		Expires:  item.Expires.Format(time.RFC3339),
+		Ttl:      item.Until.Unix(),
+		Version:  database.Version,
+	}
+}
+
+func (dynamoItem CacheDynamoItem) GetItem() database.Item {
+	ids, err := cacheIdCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, cacheIdCodec.Parts)
+	}
+
+	var response EspnResponse
+	json.Unmarshal([]byte(dynamoItem.Response), &response)
+	fetched, _ := time.Parse(time.RFC3339, dynamoItem.Fetched)
+	expires, _ := time.Parse(time.RFC3339, dynamoItem.Expires)
+
+	return CacheItem{
+		CacheEntry: CacheEntry{
+			Key:      cacheKeyCodec.Encode(ids...),
+			Response: response,
+			Fetched:  fetched,
+			Expires:  expires,
+			Until:    time.Unix(dynamoItem.Ttl, 0),
+		},
+	}
+}
+
+func getCacheId(cacheKey string) string {
+	return cacheIdCodec.Encode(key.Split(cacheKey)...)
+}
+
+// DynamoStore shares cached responses between lambdas. Expired rows are removed
+// by the table's ttl.
+type DynamoStore struct {
+	databaseService database.Service[CacheDynamoItem, CacheItem]
+	clock           clock.Clock
+}
+
+func NewDynamoStore(databaseService database.Service[CacheDynamoItem, CacheItem], clk clock.Clock) Store {
+	return &DynamoStore{
+		databaseService: databaseService,
+		clock:           clk,
+	}
+}
+
+func (d *DynamoStore) Get(ctx context.Context, key string) (CacheEntry, bool) {
+	items := d.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id and sortKey = :sortKey"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":      &types.AttributeValueMemberS{Value: getCacheId(key)},
+			":sortKey": &types.AttributeValueMemberS{Value: "CACHE"},
+		},
+	})
+
+	// ttl deletes are lazy so the row can outlive its ttl
+	if len(items) == 0 || !d.clock.Now().Before(items[0].Until) {
+		return CacheEntry{}, false
+	}
+	return items[0].CacheEntry, true
+}
+
+func (d *DynamoStore) Set(ctx context.Context, entry CacheEntry) {
+	d.databaseService.Write(ctx, []CacheItem{{CacheEntry: entry}})
+}
@@ -0,0 +1,129 @@
+package espn
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sammy.link/clock"
+)
+
+type fakeService struct {
+	calls  atomic.Int32
+	status string
+	err    error
+}
+
+func (f *fakeService) ConvertKind(kind string) Kind {
+	return Kind{}
+}
+
+func (f *fakeService) GetEspnData(ctx context.Context, sport string, league string, date time.Time, secondDate time.Time) (EspnResponse, error) {
+	return f.GetEspnEvent(ctx, sport, league, "")
+}
+
+func (f *fakeService) GetEspnEvent(ctx context.Context, sport string, league string, eventId string) (EspnResponse, error) {
+	call := f.calls.Add(1)
+	if f.err != nil {
+		return EspnResponse{}, f.err
+	}
+	return EspnResponse{Sports: []EspnSport{{Leagues: []EspnLeague{{Events: []EspnEvent{{Id: eventId, Status: f.status, Week: int(call)}}}}}}}, nil
+}
+
+func testCache(service Service) (*CachedService, *clock.Fake) {
+	now := clock.NewFake(time.Date(2023, time.September, 10, 17, 0, 0, 0, time.UTC))
+	cached := NewCachedService(service, DefaultCacheConfig(), now, NewMemoryStore(now)).(*CachedService)
+	return cached, now
+}
+
+func week(resp EspnResponse) int {
+	return resp.Sports[0].Leagues[0].Events[0].Week
+}
+
+func TestCacheHit(t *testing.T) {
+	service := &fakeService{status: "pre"}
+	cached, _ := testCache(service)
+
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "2")
+
+	if service.calls.Load() != 2 {
+		t.Fatalf("should only call espn once per event but called %d times", service.calls.Load())
+	}
+}
+
+func TestCacheLiveGamesExpireSooner(t *testing.T) {
+	service := &fakeService{status: "in"}
+	cached, now := testCache(service)
+
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	now.Advance(time.Minute)
+
+	if resp, _ := cached.GetEspnEvent(context.TODO(), "football", "nfl", "1"); week(resp) != 2 || service.calls.Load() != 2 {
+		t.Fatalf("should serve the refreshed response but got %+v", resp)
+	}
+}
+
+func TestCacheFinalGamesLast(t *testing.T) {
+	service := &fakeService{status: "post"}
+	cached, now := testCache(service)
+
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	now.Advance(time.Hour)
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+
+	if service.calls.Load() != 1 {
+		t.Fatalf("a final score shouldn't be fetched again within the hour")
+	}
+}
+
+func TestCacheStaleWhileEspnIsDown(t *testing.T) {
+	service := &fakeService{status: "pre"}
+	cached, now := testCache(service)
+
+	cached.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	service.err = ErrCircuitOpen
+	now.Advance(30 * time.Minute)
+
+	if resp, err := cached.GetEspnEvent(context.TODO(), "football", "nfl", "1"); err != nil || week(resp) != 1 {
+		t.Fatalf("should keep serving the stale response but got %+v %v", resp, err)
+	}
+
+	now.Advance(2 * time.Hour)
+	if _, err := cached.GetEspnEvent(context.TODO(), "football", "nfl", "1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("should fail once the stale window has passed but got %v", err)
+	}
+}
+
+func TestCacheDynamoItemRoundTrip(t *testing.T) {
+	now := time.Date(2023, time.September, 10, 17, 0, 0, 0, time.UTC)
+	item := CacheItem{CacheEntry: CacheEntry{
+		Key:      cacheKeyCodec.Encode("event", "football", "nfl", "1"),
+		Response: EspnResponse{Sports: []EspnSport{{Name: "Football"}}},
+		Fetched:  now,
+		Expires:  now.Add(time.Minute),
+		Until:    now.Add(time.Hour),
+	}}
+
+	dynamoItem := item.GetDynamoItem().(CacheDynamoItem)
+	if dynamoItem.Id != "ESPN|event|football|nfl|1" {
+		t.Errorf("ids should keep their layout but got %s", dynamoItem.Id)
+	}
+
+	roundTrip := dynamoItem.GetItem().(CacheItem)
+	if roundTrip.Key != item.Key || !roundTrip.Until.Equal(item.Until) || roundTrip.Response.Sports[0].Name != "Football" {
+		t.Fatalf("expected %+v but got %+v", item, roundTrip)
+	}
+}
+
+func TestCacheKeysEscapeTheSeparator(t *testing.T) {
+	cacheKey := cacheKeyCodec.Encode("event", "football", "n|fl", "1")
+	item := CacheItem{CacheEntry: CacheEntry{Key: cacheKey}}
+
+	if roundTrip := item.GetDynamoItem().(CacheDynamoItem).GetItem().(CacheItem); roundTrip.Key != cacheKey {
+		t.Fatalf("expected %s but got %s", cacheKey, roundTrip.Key)
+	}
+}
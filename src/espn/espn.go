@@ -1,10 +1,17 @@
 package espn
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
+
+	"sammy.link/clock"
+	"sammy.link/logging"
+	"sammy.link/sport"
 )
 
 type EspnResponse struct {
@@ -32,6 +39,9 @@ type EspnEvent struct {
 	Competitors []EspnCompetitor `json:"competitors"`
 	Week        int              `json:"week"`
 	Link        string           `json:"link"`
+	Clock       string           `json:"clock"`
+	Period      int              `json:"period"`
+	Summary     string           `json:"summary"`
 }
 
 type EspnCompetitor struct {
@@ -54,25 +64,76 @@ type Kind struct {
 
 type Service interface {
 	ConvertKind(kind string) Kind
-	GetEspnData(sport string, league string, channel chan EspnResponse, date time.Time, secondDate time.Time)
-	GetEspnEvent(sport string, league string, eventId string) (EspnResponse, error)
+	GetEspnData(ctx context.Context, sport string, league string, date time.Time, secondDate time.Time) (EspnResponse, error)
+	GetEspnEvent(ctx context.Context, sport string, league string, eventId string) (EspnResponse, error)
+}
+
+type Config struct {
+	BaseUrl string
+	// Timeout bounds each attempt, retries get a fresh timeout.
+	Timeout    time.Duration
+	MaxRetries int
+	Backoff    time.Duration
+	Breaker    *Breaker
+}
+
+// StatusError is returned when ESPN answers with anything other than a 200.
+type StatusError struct {
+	StatusCode int
+	Url        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("espn: %s returned %d", e.Url, e.StatusCode)
+}
+
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+const DefaultBaseUrl = "https://site.web.api.espn.com/apis/v2/scoreboard/header"
+
+// the breaker outlives a single invocation so a warm lambda stops hammering ESPN
+// once it is down
+var defaultBreaker = NewBreaker(5, time.Minute)
+
+func DefaultConfig() Config {
+	return Config{
+		BaseUrl:    DefaultBaseUrl,
+		Timeout:    5 * time.Second,
+		MaxRetries: 3,
+		Backoff:    250 * time.Millisecond,
+		Breaker:    defaultBreaker,
+	}
 }
 
 type EspnService struct {
 	client http.Client
+	config Config
 }
 
 func NewService(client http.Client) Service {
+	return NewServiceWithConfig(client, DefaultConfig())
+}
+
+func NewServiceWithConfig(client http.Client, config Config) Service {
+	if config.BaseUrl == "" {
+		config.BaseUrl = DefaultBaseUrl
+	}
+	if config.Breaker == nil {
+		config.Breaker = NewBreaker(5, time.Minute)
+	}
 	return &EspnService{
 		client: client,
+		config: config,
 	}
 }
 
 func (s *EspnService) ConvertKind(kind string) Kind {
-	if kind == "CFB" {
+	if known, ok := sport.Get(kind); ok {
 		return Kind{
-			Sport:  "football",
-			League: "college-football",
+			Sport:  known.Sport,
+			League: known.League,
 		}
 	}
 	return Kind{
@@ -81,50 +142,84 @@ func (s *EspnService) ConvertKind(kind string) Kind {
 	}
 }
 
-func (s *EspnService) GetEspnEvent(sport string, league string, eventId string) (EspnResponse, error) {
-	request, _ := http.NewRequest("GET", fmt.Sprintf("https://site.web.api.espn.com/apis/v2/scoreboard/header?sport=%s&league=%s&event=%s",
-		sport, league, eventId), nil)
+func (s *EspnService) GetEspnEvent(ctx context.Context, sport string, league string, eventId string) (EspnResponse, error) {
+	return s.get(ctx, fmt.Sprintf("%s?sport=%s&league=%s&event=%s", s.config.BaseUrl, sport, league, eventId))
+}
 
-	resp, err := s.client.Do(request)
+func (s *EspnService) GetEspnData(ctx context.Context, sport string, league string, date time.Time, secondDate time.Time) (EspnResponse, error) {
+	// the scoreboard's dates are game days
+	return s.get(ctx, fmt.Sprintf("%s?sport=%s&league=%s&dates=%s-%s",
+		s.config.BaseUrl, sport, league, clock.GameDate(date), clock.GameDate(secondDate)))
+}
 
+// get fetches a scoreboard, retrying throttled and failed requests with exponential
+// backoff. Exhausted retries count against the circuit breaker.
+func (s *EspnService) get(ctx context.Context, url string) (EspnResponse, error) {
 	var response EspnResponse
 
-	if err != nil {
-		return response, err
+	if !s.config.Breaker.Allow() {
+		return response, ErrCircuitOpen
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	var err error
+	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return response, ctx.Err()
+			case <-time.After(s.config.Backoff << (attempt - 1)):
+			}
+		}
 
-	if err != nil {
-		return response, err
+		response, err = s.attempt(ctx, url)
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			// ESPN is up, we just asked for something it doesn't have
+			s.config.Breaker.Success()
+			return response, err
+		}
+
+		if err == nil {
+			s.config.Breaker.Success()
+			return response, nil
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+		logging.FromContext(ctx).Warn("espn request failed", "attempt", attempt, "err", err)
 	}
 
-	return response, nil
+	s.config.Breaker.Failure()
+	return response, err
 }
 
-func (s *EspnService) GetEspnData(sport string, league string, channel chan EspnResponse, date time.Time, secondDate time.Time) {
-	const format = "20060102"
+func (s *EspnService) attempt(ctx context.Context, url string) (EspnResponse, error) {
+	var response EspnResponse
 
-	request, err := http.NewRequest("GET", fmt.Sprintf("https://site.web.api.espn.com/apis/v2/scoreboard/header?sport=%s&league=%s&dates=%s-%s",
-		sport, league, date.Format(format), secondDate.Format(format)), nil)
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
 
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		fmt.Println(err.Error())
+		return response, err
 	}
 
 	resp, err := s.client.Do(request)
-
 	if err != nil {
-		fmt.Println(err.Error())
+		return response, err
 	}
-
 	defer resp.Body.Close()
 
-	var response EspnResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
-
-	if err != nil {
-		fmt.Println(err.Error())
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return response, &StatusError{StatusCode: resp.StatusCode, Url: url}
 	}
-	channel <- response
+
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	return response, err
 }
@@ -1,7 +1,11 @@
 package espn
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -9,10 +13,7 @@ import (
 func TestGetEspnData(t *testing.T) {
 	s := NewService(http.Client{})
 
-	testChan := make(chan EspnResponse, 1)
-	s.GetEspnData("football", "nfl", testChan, time.Now(), time.Now().AddDate(0, 0, 7))
-
-	resp := <-testChan
+	resp, _ := s.GetEspnData(context.TODO(), "football", "nfl", time.Now(), time.Now().AddDate(0, 0, 7))
 
 	if len(resp.Sports) < 1 {
 		t.Fatalf("Sports should be returned but received %+v", resp)
@@ -23,15 +24,114 @@ func TestGetEspnData(t *testing.T) {
 func TestGetEspnEvent(t *testing.T) {
 	s := NewService(http.Client{})
 
-	resp, _ := s.GetEspnEvent("football", "nfl", "401547658")
+	resp, _ := s.GetEspnEvent(context.TODO(), "football", "nfl", "401547658")
 
 	if len(resp.Sports[0].Leagues[0].Events) == 0 {
 		t.Fatalf("Only got this back %+v", resp)
 	}
 
-	resp, _ = s.GetEspnEvent("football", "college-football", "401547658")
+	resp, _ = s.GetEspnEvent(context.TODO(), "football", "college-football", "401547658")
 
 	if len(resp.Sports) > 0 {
 		t.Fatalf("Should be zero response %+v", resp)
 	}
 }
+
+func testService(url string) Service {
+	return NewServiceWithConfig(http.Client{}, Config{
+		BaseUrl:    url,
+		Timeout:    time.Second,
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		Breaker:    NewBreaker(2, time.Minute),
+	})
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"sports":[{"name":"Football"}]}`))
+	}))
+	defer server.Close()
+
+	resp, err := testService(server.URL).GetEspnData(context.TODO(), "football", "nfl", time.Now(), time.Now())
+
+	if err != nil || len(resp.Sports) != 1 || calls.Load() != 3 {
+		t.Fatalf("should succeed on the third try but got %+v %v after %d calls", resp, err, calls.Load())
+	}
+}
+
+func TestDoesNotRetryNotFound(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := testService(server.URL).GetEspnEvent(context.TODO(), "football", "nfl", "1")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound || calls.Load() != 1 {
+		t.Fatalf("should give up on a 404 straight away but got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestCircuitOpens(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := testService(server.URL)
+	for i := 0; i < 2; i++ {
+		s.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+	}
+
+	before := calls.Load()
+	_, err := s.GetEspnEvent(context.TODO(), "football", "nfl", "1")
+
+	if !errors.Is(err, ErrCircuitOpen) || calls.Load() != before {
+		t.Fatalf("the breaker should be open but got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	s := NewServiceWithConfig(http.Client{}, Config{BaseUrl: server.URL, Timeout: 10 * time.Millisecond})
+
+	if _, err := s.GetEspnEvent(context.TODO(), "football", "nfl", "1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should time out but got %v", err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	if b.Allow() {
+		t.Fatalf("should be open right after failing")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !b.Allow() || b.Allow() {
+		t.Fatalf("should let exactly one trial through after the cooldown")
+	}
+
+	b.Success()
+	if !b.Allow() || b.Open() {
+		t.Fatalf("should close after a successful trial")
+	}
+}
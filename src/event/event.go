@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sammy.link/clock"
+	"sammy.link/logging"
+)
+
+type Type string
+
+const (
+	BidPlaced      Type = "BidPlaced"
+	BidMatched     Type = "BidMatched"
+	BidCancelled   Type = "BidCancelled"
+	BetCreated     Type = "BetCreated"
+	BetSettled     Type = "BetSettled"
+	BalanceChanged Type = "BalanceChanged"
+	ListingChanged Type = "ListingChanged"
+)
+
+// Source is what every event is published under, subscribers match on it.
+const Source = "sammy.link.marketplace"
+
+// Event is a change that already happened. Detail is marshalled to JSON, so it is
+// whatever the publishing service's own item is.
+type Event struct {
+	Type   Type
+	Detail any
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Emit publishes events after the write they describe. The write already happened,
+// so a failure is only logged.
+func Emit(ctx context.Context, publisher Publisher, events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	if err := publisher.Publish(ctx, events...); err != nil {
+		logging.FromContext(ctx).Error("publishing events failed", "events", len(events), "err", err)
+	}
+}
+
+type discard struct{}
+
+func (discard) Publish(ctx context.Context, events ...Event) error {
+	return nil
+}
+
+// Discard is for when nothing is listening, such as when no event bus is configured.
+var Discard Publisher = discard{}
+
+// Memory keeps what was published, for tests.
+type Memory struct {
+	mutex     sync.Mutex
+	clock     clock.Clock
+	Published []Published
+}
+
+type Published struct {
+	Event
+	Time time.Time
+}
+
+func NewMemory(clk clock.Clock) *Memory {
+	return &Memory{clock: clk}
+}
+
+func (m *Memory) Publish(ctx context.Context, events ...Event) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, e := range events {
+		m.Published = append(m.Published, Published{Event: e, Time: m.clock.Now()})
+	}
+	return nil
+}
+
+// Of is every event of type t published so far.
+func (m *Memory) Of(t Type) []Event {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	events := make([]Event, 0)
+	for _, published := range m.Published {
+		if published.Type == t {
+			events = append(events, published.Event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,82 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"sammy.link/clock"
+)
+
+type stubClient struct {
+	inputs []*eventbridge.PutEventsInput
+	failed map[int]bool
+}
+
+func (c *stubClient) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	c.inputs = append(c.inputs, params)
+	entries := make([]types.PutEventsResultEntry, len(params.Entries))
+	for i := range entries {
+		if c.failed[len(c.inputs)-1] && i == 0 {
+			entries[i] = types.PutEventsResultEntry{ErrorCode: aws.String("ThrottlingException")}
+		}
+	}
+	return &eventbridge.PutEventsOutput{Entries: entries}, nil
+}
+
+type placed struct {
+	User   string `json:"user"`
+	Amount int64  `json:"amount"`
+}
+
+func TestEventBridge(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	client := &stubClient{failed: map[int]bool{1: true}}
+	publisher := NewEventBridge(client, "marketplace", clock.NewFake(now))
+
+	events := make([]Event, 12)
+	for i := range events {
+		events[i] = Event{Type: BidPlaced, Detail: placed{User: "sam@sam.com", Amount: int64(i)}}
+	}
+
+	err := publisher.Publish(context.TODO(), events...)
+	if err == nil {
+		t.Fatal("a failed entry should be reported")
+	}
+	if len(client.inputs) != 2 || len(client.inputs[0].Entries) != maxEntries || len(client.inputs[1].Entries) != 2 {
+		t.Fatalf("should publish in batches of %d but sent %d batches", maxEntries, len(client.inputs))
+	}
+
+	entry := client.inputs[1].Entries[1]
+	var detail placed
+	json.Unmarshal([]byte(aws.ToString(entry.Detail)), &detail)
+	if aws.ToString(entry.EventBusName) != "marketplace" || aws.ToString(entry.Source) != Source || aws.ToString(entry.DetailType) != "BidPlaced" || !entry.Time.Equal(now) || detail.Amount != 11 {
+		t.Errorf("entry = %+v with detail %s", entry, aws.ToString(entry.Detail))
+	}
+}
+
+type failing struct{}
+
+func (failing) Publish(ctx context.Context, events ...Event) error {
+	return errors.New("unavailable")
+}
+
+func TestMemory(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	memory := NewMemory(clock.NewFake(now))
+
+	Emit(context.TODO(), memory, Event{Type: BidPlaced}, Event{Type: BetCreated}, Event{Type: BidPlaced})
+	Emit(context.TODO(), memory)
+
+	if len(memory.Published) != 3 || !memory.Published[0].Time.Equal(now) || len(memory.Of(BidPlaced)) != 2 || len(memory.Of(BetSettled)) != 0 {
+		t.Errorf("published %+v", memory.Published)
+	}
+
+	// only logged, the write the events are about already happened
+	Emit(context.TODO(), failing{}, Event{Type: BidPlaced})
+}
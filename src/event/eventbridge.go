@@ -0,0 +1,74 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"sammy.link/clock"
+	"sammy.link/util"
+)
+
+// maxEntries is how many events one PutEvents call takes.
+const maxEntries = 10
+
+// Client is the part of the EventBridge client the publisher uses.
+type Client interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+type EventBridgePublisher struct {
+	client  Client
+	busName string
+	clock   clock.Clock
+}
+
+func NewEventBridge(client Client, busName string, clk clock.Clock) *EventBridgePublisher {
+	return &EventBridgePublisher{
+		client:  client,
+		busName: busName,
+		clock:   clk,
+	}
+}
+
+func NewFromConfig(config aws.Config, busName string, clk clock.Clock) Publisher {
+	return NewEventBridge(eventbridge.NewFromConfig(config), busName, clk)
+}
+
+func (p *EventBridgePublisher) Publish(ctx context.Context, events ...Event) error {
+	entries := make([]types.PutEventsRequestEntry, 0, len(events))
+	now := p.clock.Now()
+	for _, e := range events {
+		detail, err := json.Marshal(e.Detail)
+		if err != nil {
+			return fmt.Errorf("marshalling %s: %w", e.Type, err)
+		}
+		entries = append(entries, types.PutEventsRequestEntry{
+			EventBusName: aws.String(p.busName),
+			Source:       aws.String(Source),
+			DetailType:   aws.String(string(e.Type)),
+			Detail:       aws.String(string(detail)),
+			Time:         aws.Time(now),
+		})
+	}
+
+	var errs []error
+	for i := 0; i < len(entries); i += maxEntries {
+		batch := entries[i:util.Min(i+maxEntries, len(entries))]
+		resp, err := p.client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: batch})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for j, entry := range resp.Entries {
+			if entry.ErrorCode != nil {
+				errs = append(errs, fmt.Errorf("publishing %s: %s %s", aws.ToString(batch[j].DetailType), aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
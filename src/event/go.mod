@@ -0,0 +1,3 @@
+module sammy.link/event
+
+go 1.21.0
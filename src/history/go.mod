@@ -0,0 +1,3 @@
+module sammy.link/history
+
+go 1.21.0
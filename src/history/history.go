@@ -0,0 +1,378 @@
+package history
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/logging"
+	"sammy.link/outcome"
+)
+
+const (
+	Win  = "WIN"
+	Loss = "LOSS"
+	Push = "PUSH"
+)
+
+type Item struct {
+	Email        string    `json:"email"`
+	Div          string    `json:"div"`
+	OutcomeId    string    `json:"outcomeId"`
+	EventId      string    `json:"eventId"`
+	Date         time.Time `json:"date"`
+	Season       string    `json:"season"`
+	Week         int       `json:"week"`
+	Kind         string    `json:"kind"`
+	AwayTeam     string    `json:"awayTeam"`
+	HomeTeam     string    `json:"homeTeam"`
+	ChosenTeam   string    `json:"chosenTeam"`
+	Spread       string    `json:"spread"`
+	AwayScore    string    `json:"awayScore"`
+	HomeScore    string    `json:"homeScore"`
+	Opponent     string    `json:"opponent"`
+	OpponentName string    `json:"opponentName"`
+	Result       string    `json:"result"`
+	Amount       int64     `json:"amount"`
+}
+
+type DynamoItem struct {
+	Id         string `dynamodbav:"id"`
+	SortKey    string `dynamodbav:"sortKey"`
+	EventId    string `dynamodbav:"eId"`
+	Season     string `dynamodbav:"season"`
+	Week       int    `dynamodbav:"week"`
+	Kind       string `dynamodbav:"kind"`
+	AwayTeam   string `dynamodbav:"away"`
+	HomeTeam   string `dynamodbav:"home"`
+	ChosenTeam string `dynamodbav:"chosen"`
+	Spread     string `dynamodbav:"spread"`
+	AwayScore  string `dynamodbav:"awayScore"`
+	HomeScore  string `dynamodbav:"homeScore"`
+	Opponent   string `dynamodbav:"opponent"`
+	Result     string `dynamodbav:"result"`
+	Amount     int64  `dynamodbav:"amount"`
+	Version    int    `dynamodbav:"v"`
+}
+
+type RecordItem struct {
+	Email        string `json:"-"`
+	Div          string `json:"-"`
+	Opponent     string `json:"opponent"`
+	OpponentName string `json:"opponentName"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+	Pushes       int    `json:"pushes"`
+	Net          int64  `json:"net"`
+}
+
+type RecordDynamoItem struct {
+	Id      string `dynamodbav:"id"`
+	SortKey string `dynamodbav:"sortKey"`
+	Wins    int    `dynamodbav:"wins"`
+	Losses  int    `dynamodbav:"losses"`
+	Pushes  int    `dynamodbav:"pushes"`
+	Net     int64  `dynamodbav:"net"`
+	Version int    `dynamodbav:"v"`
+}
+
+type Service interface {
+	GetHistory(ctx context.Context, div string, email string, limit int32, cursor string) ([]Item, string, error)
+	GetRecords(ctx context.Context, div string, email string) []RecordItem
+	Record(ctx context.Context, outcomes []outcome.OutcomeItem)
+}
+
+type HistoryService struct {
+	databaseService       database.Service[DynamoItem, Item]
+	recordDatabaseService database.Service[RecordDynamoItem, RecordItem]
+}
+
+func NewService(databaseService database.Service[DynamoItem, Item], recordDatabaseService database.Service[RecordDynamoItem, RecordItem]) Service {
+	return &HistoryService{
+		databaseService:       databaseService,
+		recordDatabaseService: recordDatabaseService,
+	}
+}
+
+var (
+	idCodec       = key.Codec{Prefix: "H", Parts: 2}
+	sortKeyCodec  = key.Codec{Parts: 2}
+	recordIdCodec = key.Codec{Prefix: "H2H", Parts: 2}
+)
+
+func getId(div string, email string) string {
+	return idCodec.Encode(div, email)
+}
+
+func getSortKey(date time.Time, outcomeId string) string {
+	return sortKeyCodec.Encode(key.Time(date), outcomeId)
+}
+
+func getRecordId(div string, email string) string {
+	return recordIdCodec.Encode(div, email)
+}
+
+func (item Item) GetDynamoItem() database.DynamoItem {
+	return DynamoItem{
+		Id:         getId(item.Div, item.Email),
+		SortKey:    getSortKey(item.Date, item.OutcomeId),
+		EventId:    item.EventId,
+		Season:     item.Season,
+		Week:       item.Week,
+		Kind:       item.Kind,
+		AwayTeam:   item.AwayTeam,
+		HomeTeam:   item.HomeTeam,
+		ChosenTeam: item.ChosenTeam,
+		Spread:     item.Spread,
+		AwayScore:  item.AwayScore,
+		HomeScore:  item.HomeScore,
+		Opponent:   item.Opponent,
+		Result:     item.Result,
+		Amount:     item.Amount,
+		Version:    database.Version,
+	}
+}
+
+func (dynamoItem DynamoItem) upgrade() DynamoItem {
+	if dynamoItem.Version < 3 {
+		// keys were joined without escaping, so the last part took whatever was left
+		if ids := strings.SplitN(dynamoItem.Id, "|", 3); len(ids) == 3 {
+			dynamoItem.Id = getId(ids[1], ids[2])
+		}
+		if sortKeys := strings.SplitN(dynamoItem.SortKey, "|", 2); len(sortKeys) == 2 {
+			date, _ := key.ParseTime(sortKeys[0])
+			dynamoItem.SortKey = getSortKey(date, sortKeys[1])
+		}
+	}
+	return dynamoItem
+}
+
+func (dynamoItem DynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
+	ids, err := idCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
+
+	sortKeys, err := sortKeyCodec.Decode(dynamoItem.SortKey)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		sortKeys = make([]string, sortKeyCodec.Parts)
+	}
+	date, _ := key.ParseTime(sortKeys[0])
+
+	return Item{
+		Email:      ids[1],
+		Div:        ids[0],
+		OutcomeId:  sortKeys[1],
+		EventId:    dynamoItem.EventId,
+		Date:       date,
+		Season:     dynamoItem.Season,
+		Week:       dynamoItem.Week,
+		Kind:       dynamoItem.Kind,
+		AwayTeam:   dynamoItem.AwayTeam,
+		HomeTeam:   dynamoItem.HomeTeam,
+		ChosenTeam: dynamoItem.ChosenTeam,
+		Spread:     dynamoItem.Spread,
+		AwayScore:  dynamoItem.AwayScore,
+		HomeScore:  dynamoItem.HomeScore,
+		Opponent:   dynamoItem.Opponent,
+		Result:     dynamoItem.Result,
+		Amount:     dynamoItem.Amount,
+	}
+}
+
+func (item RecordItem) GetDynamoItem() database.DynamoItem {
+	return RecordDynamoItem{
+		Id:      getRecordId(item.Div, item.Email),
+		SortKey: item.Opponent,
+		Wins:    item.Wins,
+		Losses:  item.Losses,
+		Pushes:  item.Pushes,
+		Net:     item.Net,
+		Version: database.Version,
+	}
+}
+
+func (dynamoItem RecordDynamoItem) upgrade() RecordDynamoItem {
+	if dynamoItem.Version < 3 {
+		// the email was joined on without escaping
+		if ids := strings.SplitN(dynamoItem.Id, "|", 3); len(ids) == 3 {
+			dynamoItem.Id = getRecordId(ids[1], ids[2])
+		}
+	}
+	return dynamoItem
+}
+
+func (dynamoItem RecordDynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
+	ids, err := recordIdCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, recordIdCodec.Parts)
+	}
+	return RecordItem{
+		Email:    ids[1],
+		Div:      ids[0],
+		Opponent: dynamoItem.SortKey,
+		Wins:     dynamoItem.Wins,
+		Losses:   dynamoItem.Losses,
+		Pushes:   dynamoItem.Pushes,
+		Net:      dynamoItem.Net,
+	}
+}
+
+// FromOutcome splits a settled outcome into one history entry per side of the bet.
+func FromOutcome(o outcome.OutcomeItem) []Item {
+	base := Item{
+		Div:       o.Div,
+		OutcomeId: o.Id,
+		EventId:   o.EventId,
+		Date:      o.Date,
+		Season:    o.Season,
+		Week:      o.Week,
+		Kind:      o.Kind,
+		AwayTeam:  o.AwayTeam,
+		HomeTeam:  o.HomeTeam,
+		Spread:    o.Spread,
+		AwayScore: o.AwayScore,
+		HomeScore: o.HomeScore,
+		Amount:    o.Amount,
+	}
+
+	away := base
+	away.Email = o.AwayUser
+	away.Opponent = o.HomeUser
+	away.ChosenTeam = o.AwayTeam
+
+	home := base
+	home.Email = o.HomeUser
+	home.Opponent = o.AwayUser
+	home.ChosenTeam = o.HomeTeam
+
+	switch {
+	case o.Push:
+		away.Result = Push
+		home.Result = Push
+	case o.Winner == o.AwayUser:
+		away.Result = Win
+		home.Result = Loss
+	default:
+		away.Result = Loss
+		home.Result = Win
+	}
+
+	return []Item{away, home}
+}
+
+// Record stores the history entries for each outcome and adds them to the
+// running head-to-head record between the two players. Each entry goes in with its
+// record update in one transaction that only succeeds for an entry not yet stored,
+// so recording an outcome again never counts it twice.
+func (s *HistoryService) Record(ctx context.Context, outcomes []outcome.OutcomeItem) {
+	items := make([]Item, 0, len(outcomes)*2)
+	for _, o := range outcomes {
+		if o.AwayUser == "" || o.HomeUser == "" {
+			continue
+		}
+		items = append(items, FromOutcome(o)...)
+	}
+
+	var waitGroup sync.WaitGroup
+	for _, item := range items {
+		waitGroup.Add(1)
+		go func(myItem Item) {
+			defer waitGroup.Done()
+			s.record(ctx, myItem)
+		}(item)
+	}
+	waitGroup.Wait()
+}
+
+func (s *HistoryService) record(ctx context.Context, item Item) {
+	err := s.recordDatabaseService.TransactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: RecordWrites(item)})
+	if slices.Contains(database.CancelledBy(err), 0) {
+		logging.FromContext(ctx).Debug("already recorded", "outcome", item.OutcomeId)
+	} else if err != nil {
+		logging.FromContext(ctx).Error("couldn't record history", "outcome", item.OutcomeId, "err", err)
+	}
+}
+
+// RecordWrites are the history entry, put only if it isn't there yet, and the update
+// to the head-to-head record that goes with it. The entry always comes first.
+func RecordWrites(item Item) []types.TransactWriteItem {
+	table := aws.String(os.Getenv("TABLE_NAME"))
+	entry, _ := attributevalue.MarshalMap(item.GetDynamoItem())
+
+	var wins, losses, pushes, net int64
+	switch item.Result {
+	case Win:
+		wins = 1
+		net = item.Amount
+	case Loss:
+		losses = 1
+		net = -item.Amount
+	default:
+		pushes = 1
+	}
+
+	return []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:           table,
+			Item:                entry,
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		}},
+		{Update: &types.Update{
+			TableName: table,
+			Key: map[string]types.AttributeValue{
+				"id":      &types.AttributeValueMemberS{Value: getRecordId(item.Div, item.Email)},
+				"sortKey": &types.AttributeValueMemberS{Value: item.Opponent},
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":wins":   &types.AttributeValueMemberN{Value: strconv.FormatInt(wins, 10)},
+				":losses": &types.AttributeValueMemberN{Value: strconv.FormatInt(losses, 10)},
+				":pushes": &types.AttributeValueMemberN{Value: strconv.FormatInt(pushes, 10)},
+				":net":    &types.AttributeValueMemberN{Value: strconv.FormatInt(net, 10)},
+				":v":      &types.AttributeValueMemberN{Value: strconv.Itoa(database.Version)},
+			},
+			UpdateExpression: aws.String("ADD wins :wins, losses :losses, pushes :pushes, net :net SET v = :v"),
+		}},
+	}
+}
+
+// GetHistory returns a page of settled bets for a user, newest first. The returned
+// cursor is empty once there is nothing left to read.
+func (s *HistoryService) GetHistory(ctx context.Context, div string, email string, limit int32, cursor string) ([]Item, string, error) {
+	return s.databaseService.QueryPaged(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: getId(div, email)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}, limit, cursor)
+}
+
+func (s *HistoryService) GetRecords(ctx context.Context, div string, email string) []RecordItem {
+	return s.recordDatabaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: getRecordId(div, email)},
+		},
+	})
+}
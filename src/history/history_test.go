@@ -0,0 +1,132 @@
+package history
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/outcome"
+)
+
+func TestFromOutcome(t *testing.T) {
+	items := FromOutcome(outcome.OutcomeItem{
+		Winner:    "greg@greg.com",
+		Loser:     "sam@sam.com",
+		AwayUser:  "sam@sam.com",
+		HomeUser:  "greg@greg.com",
+		AwayTeam:  "Utah",
+		HomeTeam:  "Oregon St",
+		Spread:    "ORST -3.0",
+		AwayScore: "7",
+		HomeScore: "21",
+		Amount:    12,
+		Div:       "default",
+		Id:        "abc",
+		Date:      time.Date(2023, time.September, 30, 1, 0, 0, 0, time.UTC),
+	})
+
+	if len(items) != 2 {
+		t.Fatalf("should return one item per user but got %+v", items)
+	}
+
+	sam, greg := items[0], items[1]
+	if sam.Email != "sam@sam.com" || sam.Opponent != "greg@greg.com" || sam.ChosenTeam != "Utah" || sam.Result != Loss {
+		t.Fatalf("sam took Utah and lost but got %+v", sam)
+	}
+
+	if greg.ChosenTeam != "Oregon St" || greg.Result != Win || greg.HomeScore != "21" {
+		t.Fatalf("greg took Oregon St and won but got %+v", greg)
+	}
+}
+
+func TestFromOutcomePush(t *testing.T) {
+	items := FromOutcome(outcome.OutcomeItem{AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Push: true})
+
+	if items[0].Result != Push || items[1].Result != Push {
+		t.Fatalf("both sides should push but got %+v", items)
+	}
+}
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	item := FromOutcome(outcome.OutcomeItem{
+		Winner:   "sam@sam.com",
+		AwayUser: "sam@sam.com",
+		HomeUser: "greg@greg.com",
+		Div:      "default",
+		Id:       "abc",
+		Date:     time.Date(2023, time.September, 30, 1, 0, 0, 0, time.UTC),
+	})[0]
+
+	roundTrip := item.GetDynamoItem().(DynamoItem).GetItem().(Item)
+	if roundTrip != item {
+		t.Fatalf("expected %+v but got %+v", item, roundTrip)
+	}
+}
+
+func TestKeysEscapeTheSeparator(t *testing.T) {
+	item := Item{Div: "fam|ily", Email: `sam\@sam.com`, OutcomeId: "a|b", Date: time.Date(2023, time.September, 30, 1, 0, 0, 0, time.UTC)}
+	if roundTrip := item.GetDynamoItem().(DynamoItem).GetItem().(Item); roundTrip != item {
+		t.Errorf("expected %+v but got %+v", item, roundTrip)
+	}
+
+	record := RecordItem{Div: "fam|ily", Email: "sam@sam.com", Opponent: "greg@greg.com", Wins: 1}
+	if roundTrip := record.GetDynamoItem().(RecordDynamoItem).GetItem().(RecordItem); roundTrip != record {
+		t.Errorf("expected %+v but got %+v", record, roundTrip)
+	}
+}
+
+func TestLegacyKeys(t *testing.T) {
+	legacy := DynamoItem{Id: "H|default|sam@sam.com", SortKey: "2023-09-30T01:00:00Z|abc"}
+	if item := legacy.GetItem().(Item); item.Div != "default" || item.Email != "sam@sam.com" || item.OutcomeId != "abc" || item.Date.IsZero() {
+		t.Errorf("should read a key from before escaping but got %+v", item)
+	}
+
+	record := RecordDynamoItem{Id: "H2H|default|sam@sam.com", SortKey: "greg@greg.com"}
+	if item := record.GetItem().(RecordItem); item.Div != "default" || item.Email != "sam@sam.com" {
+		t.Errorf("should read a key from before escaping but got %+v", item)
+	}
+}
+
+// table keeps the history entries written and each record's wins, applying the
+// entry's condition the way a transaction would.
+type table struct {
+	database.Service[RecordDynamoItem, RecordItem]
+	mutex   sync.Mutex
+	entries map[string]bool
+	wins    map[string]int
+}
+
+func (t *table) TransactWrite(ctx context.Context, params *dynamodb.TransactWriteItemsInput) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	put, update := params.TransactItems[0].Put, params.TransactItems[1].Update
+	entry := put.Item["id"].(*types.AttributeValueMemberS).Value + " " + put.Item["sortKey"].(*types.AttributeValueMemberS).Value
+	if t.entries[entry] {
+		return &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}}}
+	}
+	t.entries[entry] = true
+
+	wins, _ := strconv.Atoi(update.ExpressionAttributeValues[":wins"].(*types.AttributeValueMemberN).Value)
+	t.wins[update.Key["id"].(*types.AttributeValueMemberS).Value] += wins
+	return nil
+}
+
+func TestRecordCountsAnOutcomeOnce(t *testing.T) {
+	db := &table{entries: make(map[string]bool), wins: make(map[string]int)}
+	service := NewService(nil, db)
+	o := outcome.OutcomeItem{Winner: "sam@sam.com", Loser: "greg@greg.com", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Div: "default", Id: "abc", Amount: 10, Date: time.Date(2023, time.September, 30, 1, 0, 0, 0, time.UTC)}
+
+	service.Record(context.TODO(), []outcome.OutcomeItem{o})
+	service.Record(context.TODO(), []outcome.OutcomeItem{o})
+
+	if len(db.entries) != 2 || db.wins[getRecordId("default", "sam@sam.com")] != 1 || db.wins[getRecordId("default", "greg@greg.com")] != 0 {
+		t.Errorf("should record the outcome once but got %v and %v", db.entries, db.wins)
+	}
+}
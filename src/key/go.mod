@@ -0,0 +1,3 @@
+module sammy.link/key
+
+go 1.21.0
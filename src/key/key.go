@@ -0,0 +1,85 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	Separator = "|"
+	escape    = `\`
+)
+
+var ErrMalformed = errors.New("malformed key")
+
+var escaper = strings.NewReplacer(escape, escape+escape, Separator, escape+Separator)
+
+// Join builds a key out of parts, escaping any separator inside a part so team names
+// or leagues with a "|" in them can be split back out.
+func Join(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = escaper.Replace(part)
+	}
+	return strings.Join(escaped, Separator)
+}
+
+// Split is the reverse of Join.
+func Split(k string) []string {
+	parts := make([]string, 0, strings.Count(k, Separator)+1)
+	var part strings.Builder
+
+	for i := 0; i < len(k); i++ {
+		switch {
+		case k[i] == escape[0] && i+1 < len(k):
+			i++
+			part.WriteByte(k[i])
+		case k[i] == Separator[0]:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(k[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// Codec is the layout of one kind of key, an optional fixed prefix followed by a
+// fixed number of parts.
+type Codec struct {
+	Prefix string
+	Parts  int
+}
+
+func (c Codec) Encode(parts ...string) string {
+	if c.Prefix != "" {
+		parts = append([]string{c.Prefix}, parts...)
+	}
+	return Join(parts...)
+}
+
+func (c Codec) Decode(k string) ([]string, error) {
+	parts := Split(k)
+	if c.Prefix != "" {
+		if parts[0] != c.Prefix {
+			return nil, fmt.Errorf("%w: %q should start with %s", ErrMalformed, k, c.Prefix)
+		}
+		parts = parts[1:]
+	}
+
+	if len(parts) != c.Parts {
+		return nil, fmt.Errorf("%w: %q should have %d parts", ErrMalformed, k, c.Parts)
+	}
+	return parts, nil
+}
+
+// Time formats t in UTC so keys sort in time order.
+func Time(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339, value)
+}
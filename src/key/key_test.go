@@ -0,0 +1,74 @@
+package key
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestSplitReversesJoin(t *testing.T) {
+	roundTrip := func(parts []string) bool {
+		if len(parts) == 0 {
+			return true
+		}
+		return reflect.DeepEqual(Split(Join(parts...)), parts)
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if parts := Split(Join("Texas A&M|Commerce", `back\slash`, "")); len(parts) != 3 || parts[0] != "Texas A&M|Commerce" || parts[1] != `back\slash` {
+		t.Fatalf("separators inside a part should survive but got %q", parts)
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := Codec{Prefix: "BET", Parts: 2}
+
+	roundTrip := func(div string, week string) bool {
+		parts, err := codec.Decode(codec.Encode(div, week))
+		return err == nil && parts[0] == div && parts[1] == week
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := codec.Decode("BID|default|1"); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("the wrong prefix should be rejected")
+	}
+
+	if _, err := codec.Decode("BET|default|1|extra"); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("extra parts should be rejected")
+	}
+}
+
+func TestTimeKeysSortInTimeOrder(t *testing.T) {
+	// keep to four digit years, which is all RFC3339 can hold
+	toTime := func(seconds int64) time.Time {
+		return time.Unix(seconds%253402300799, 0).In(time.FixedZone("EDT", -4*60*60))
+	}
+
+	ordered := func(one int64, two int64) bool {
+		if one < 0 {
+			one = -one
+		}
+		if two < 0 {
+			two = -two
+		}
+		first, second := toTime(one), toTime(two)
+
+		parsed, err := ParseTime(Time(first))
+		if err != nil || !parsed.Equal(first) {
+			return false
+		}
+		return first.Before(second) == (Join(Time(first), "a") < Join(Time(second), "a"))
+	}
+
+	if err := quick.Check(ordered, nil); err != nil {
+		t.Fatal(err)
+	}
+}
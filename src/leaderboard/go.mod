@@ -0,0 +1,3 @@
+module sammy.link/leaderboard
+
+go 1.21.0
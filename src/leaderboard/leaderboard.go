@@ -0,0 +1,403 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/outcome"
+	"sammy.link/season"
+	"sammy.link/util"
+)
+
+const (
+	win  = "W"
+	loss = "L"
+	push = "P"
+)
+
+type Entry struct {
+	Email            string  `json:"email"`
+	Name             string  `json:"name"`
+	Rank             int     `json:"rank"`
+	Wins             int     `json:"wins"`
+	Losses           int     `json:"losses"`
+	Pushes           int     `json:"pushes"`
+	Wagered          int64   `json:"wagered"`
+	Net              int64   `json:"net"`
+	Roi              float64 `json:"roi"`
+	Streak           string  `json:"streak"`
+	LongestWinStreak int     `json:"longestWinStreak"`
+	Results          string  `json:"-"`
+}
+
+type Item struct {
+	League string `json:"league"`
+	Season string `json:"season"`
+	Week   int    `json:"week"`
+	Entry
+}
+
+type DynamoItem struct {
+	Id      string `dynamodbav:"id"`
+	SortKey string `dynamodbav:"sortKey"`
+	Rank    int    `dynamodbav:"rank"`
+	Wins    int    `dynamodbav:"wins"`
+	Losses  int    `dynamodbav:"losses"`
+	Pushes  int    `dynamodbav:"pushes"`
+	Wagered int64  `dynamodbav:"wagered"`
+	Net     int64  `dynamodbav:"net"`
+	Results string `dynamodbav:"results"`
+	Version int    `dynamodbav:"v"`
+}
+
+type Service interface {
+	GetWeek(ctx context.Context, league string, season string, week int) []Item
+	GetSeason(ctx context.Context, league string, season string) []Item
+	Refresh(ctx context.Context, league string, season string, weeks ...int)
+}
+
+type LeaderboardService struct {
+	databaseService database.Service[DynamoItem, Item]
+	outcomeService  outcome.Service
+}
+
+func NewService(databaseService database.Service[DynamoItem, Item], outcomeService outcome.Service) Service {
+	return &LeaderboardService{
+		databaseService: databaseService,
+		outcomeService:  outcomeService,
+	}
+}
+
+// SeasonOf returns the season an outcome was tagged with, falling back to the
+// season its game was played in for outcomes settled before seasons existed.
+func SeasonOf(o outcome.OutcomeItem) string {
+	if o.Season != "" {
+		return o.Season
+	}
+	return season.IdOf(o.Date)
+}
+
+var (
+	idCodec            = key.Codec{Prefix: "LB", Parts: 2}
+	weekSortKeyCodec   = key.Codec{Prefix: "W", Parts: 2}
+	seasonSortKeyCodec = key.Codec{Prefix: "S", Parts: 1}
+)
+
+func getId(league string, season string) string {
+	return idCodec.Encode(league, season)
+}
+
+func getSortKey(week int, email string) string {
+	if week == 0 {
+		return seasonSortKeyCodec.Encode(email)
+	}
+	return weekSortKeyCodec.Encode(fmt.Sprintf("%02d", week), email)
+}
+
+// getPrefix is the start of every sort key for week, or for the season when week is 0.
+func getPrefix(week int) string {
+	return getSortKey(week, "")
+}
+
+func (item Item) GetDynamoItem() database.DynamoItem {
+	return DynamoItem{
+		Id:      getId(item.League, item.Season),
+		SortKey: getSortKey(item.Week, item.Email),
+		Rank:    item.Rank,
+		Wins:    item.Wins,
+		Losses:  item.Losses,
+		Pushes:  item.Pushes,
+		Wagered: item.Wagered,
+		Net:     item.Net,
+		Results: item.Results,
+		Version: database.Version,
+	}
+}
+
+func (dynamoItem DynamoItem) upgrade() DynamoItem {
+	if dynamoItem.Version < 3 {
+		// keys were joined without escaping, so the last part took whatever was left
+		if ids := strings.SplitN(dynamoItem.Id, "|", 3); len(ids) == 3 {
+			dynamoItem.Id = getId(ids[1], ids[2])
+		}
+		if sortKeys := strings.SplitN(dynamoItem.SortKey, "|", 3); sortKeys[0] == "W" && len(sortKeys) == 3 {
+			week, _ := strconv.Atoi(sortKeys[1])
+			dynamoItem.SortKey = getSortKey(week, sortKeys[2])
+		} else if sortKeys := strings.SplitN(dynamoItem.SortKey, "|", 2); len(sortKeys) == 2 {
+			dynamoItem.SortKey = getSortKey(0, sortKeys[1])
+		}
+	}
+	return dynamoItem
+}
+
+func (dynamoItem DynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
+	ids, err := idCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
+
+	item := Item{
+		League: ids[0],
+		Season: ids[1],
+		Entry: Entry{
+			Rank:    dynamoItem.Rank,
+			Wins:    dynamoItem.Wins,
+			Losses:  dynamoItem.Losses,
+			Pushes:  dynamoItem.Pushes,
+			Wagered: dynamoItem.Wagered,
+			Net:     dynamoItem.Net,
+			Results: dynamoItem.Results,
+		},
+	}
+
+	if key.Split(dynamoItem.SortKey)[0] == weekSortKeyCodec.Prefix {
+		sortKeys, err := weekSortKeyCodec.Decode(dynamoItem.SortKey)
+		if err != nil {
+			slog.Warn("malformed key", "err", err)
+			sortKeys = make([]string, weekSortKeyCodec.Parts)
+		}
+		item.Week, _ = strconv.Atoi(sortKeys[0])
+		item.Email = sortKeys[1]
+	} else {
+		sortKeys, err := seasonSortKeyCodec.Decode(dynamoItem.SortKey)
+		if err != nil {
+			slog.Warn("malformed key", "err", err)
+			sortKeys = make([]string, seasonSortKeyCodec.Parts)
+		}
+		item.Email = sortKeys[0]
+	}
+
+	item.Entry = withStats(item.Entry)
+	return item
+}
+
+func (s *LeaderboardService) GetWeek(ctx context.Context, league string, season string, week int) []Item {
+	return s.query(ctx, league, season, getPrefix(week), false)
+}
+
+func (s *LeaderboardService) GetSeason(ctx context.Context, league string, season string) []Item {
+	return s.query(ctx, league, season, getPrefix(0), false)
+}
+
+func (s *LeaderboardService) query(ctx context.Context, league string, season string, prefix string, consistent bool) []Item {
+	items := s.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		ConsistentRead:         aws.Bool(consistent),
+		KeyConditionExpression: aws.String("id = :id and begins_with(sortKey, :prefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":     &types.AttributeValueMemberS{Value: getId(league, season)},
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		},
+	})
+
+	slices.SortFunc(items, func(a Item, b Item) int {
+		return a.Rank - b.Rank
+	})
+	return items
+}
+
+// Refresh recomputes the standings for the given weeks from their outcomes and then
+// rolls every week of the season up into the season standings. The weeks just computed
+// are rolled up from memory and the rest are read consistently, so a week written a
+// moment ago is never left out.
+func (s *LeaderboardService) Refresh(ctx context.Context, league string, seasonId string, weeks ...int) {
+	refreshed := make([]Item, 0)
+	for _, week := range weeks {
+		outcomes := util.Filter(s.outcomeService.GetByWeek(ctx, league, week), func(o outcome.OutcomeItem) bool {
+			return SeasonOf(o) == seasonId
+		})
+		refreshed = append(refreshed, toItems(Compute(outcomes), league, seasonId, week)...)
+	}
+	s.write(ctx, refreshed)
+
+	stored := util.Filter(s.query(ctx, league, seasonId, weekSortKeyCodec.Prefix+key.Separator, true), func(item Item) bool {
+		return !slices.Contains(weeks, item.Week)
+	})
+	all := append(stored, refreshed...)
+	slices.SortStableFunc(all, func(a Item, b Item) int {
+		return a.Week - b.Week
+	})
+
+	entries := make([]Entry, len(all))
+	for i, item := range all {
+		entries[i] = item.Entry
+	}
+
+	s.write(ctx, toItems(Combine(entries), league, seasonId, 0))
+}
+
+func (s *LeaderboardService) write(ctx context.Context, items []Item) {
+	var waitGroup sync.WaitGroup
+	for i := 0; i < len(items); i += 25 {
+		waitGroup.Add(1)
+		go func(myItems []Item) {
+			defer waitGroup.Done()
+			s.databaseService.Write(ctx, myItems)
+		}(items[i:util.Min(i+25, len(items))])
+	}
+	waitGroup.Wait()
+}
+
+func toItems(entries []Entry, league string, season string, week int) []Item {
+	items := make([]Item, len(entries))
+	for i, entry := range entries {
+		items[i] = Item{
+			League: league,
+			Season: season,
+			Week:   week,
+			Entry:  entry,
+		}
+	}
+	return items
+}
+
+// Compute builds ranked standings from settled outcomes, replaying them in game order
+// so streaks come out right.
+func Compute(outcomes []outcome.OutcomeItem) []Entry {
+	sorted := slices.Clone(outcomes)
+	slices.SortStableFunc(sorted, func(a outcome.OutcomeItem, b outcome.OutcomeItem) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	entryMap := make(map[string]*Entry)
+	getEntry := func(email string) *Entry {
+		if entry, ok := entryMap[email]; ok {
+			return entry
+		}
+		entry := &Entry{Email: email}
+		entryMap[email] = entry
+		return entry
+	}
+
+	for _, o := range sorted {
+		winner := getEntry(o.Winner)
+		loser := getEntry(o.Loser)
+
+		if o.Push {
+			winner.Pushes++
+			winner.Results += push
+			loser.Pushes++
+			loser.Results += push
+			continue
+		}
+
+		winner.Wins++
+		winner.Wagered += o.Amount
+		winner.Net += o.Amount
+		winner.Results += win
+
+		loser.Losses++
+		loser.Wagered += o.Amount
+		loser.Net -= o.Amount
+		loser.Results += loss
+	}
+
+	entries := make([]Entry, 0, len(entryMap))
+	for _, entry := range entryMap {
+		entries = append(entries, *entry)
+	}
+	return rank(entries)
+}
+
+// Combine merges standings that are already in game order, e.g. every week of a season.
+func Combine(entries []Entry) []Entry {
+	entryMap := make(map[string]*Entry)
+	order := make([]string, 0)
+
+	for _, entry := range entries {
+		existing, ok := entryMap[entry.Email]
+		if !ok {
+			existing = &Entry{Email: entry.Email, Name: entry.Name}
+			entryMap[entry.Email] = existing
+			order = append(order, entry.Email)
+		}
+		existing.Wins += entry.Wins
+		existing.Losses += entry.Losses
+		existing.Pushes += entry.Pushes
+		existing.Wagered += entry.Wagered
+		existing.Net += entry.Net
+		existing.Results += entry.Results
+	}
+
+	combined := make([]Entry, len(order))
+	for i, email := range order {
+		combined[i] = *entryMap[email]
+	}
+	return rank(combined)
+}
+
+func rank(entries []Entry) []Entry {
+	for i := range entries {
+		entries[i] = withStats(entries[i])
+	}
+
+	slices.SortFunc(entries, func(a Entry, b Entry) int {
+		if a.Net != b.Net {
+			if a.Net > b.Net {
+				return -1
+			}
+			return 1
+		}
+		if a.Roi != b.Roi {
+			if a.Roi > b.Roi {
+				return -1
+			}
+			return 1
+		}
+		if a.Wins != b.Wins {
+			return b.Wins - a.Wins
+		}
+		return strings.Compare(a.Email, b.Email)
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Net == entries[i-1].Net && entries[i].Roi == entries[i-1].Roi {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
+
+func withStats(entry Entry) Entry {
+	if entry.Wagered > 0 {
+		entry.Roi = float64(entry.Net) / float64(entry.Wagered)
+	} else {
+		entry.Roi = 0
+	}
+
+	// pushes neither extend nor break a streak
+	decided := strings.ReplaceAll(entry.Results, push, "")
+
+	entry.Streak = ""
+	if len(decided) > 0 {
+		last := decided[len(decided)-1:]
+		count := len(decided) - len(strings.TrimRight(decided, last))
+		entry.Streak = fmt.Sprintf("%s%d", last, count)
+	}
+
+	entry.LongestWinStreak = 0
+	for _, run := range strings.Split(decided, loss) {
+		entry.LongestWinStreak = max(entry.LongestWinStreak, len(run))
+	}
+
+	return entry
+}
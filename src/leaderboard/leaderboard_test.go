@@ -0,0 +1,168 @@
+package leaderboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"sammy.link/database"
+	"sammy.link/outcome"
+)
+
+func game(day int, winner string, loser string, amount int64, isPush bool) outcome.OutcomeItem {
+	return outcome.OutcomeItem{
+		Winner: winner,
+		Loser:  loser,
+		Amount: amount,
+		Push:   isPush,
+		Date:   time.Date(2023, time.September, day, 17, 0, 0, 0, time.UTC),
+		Week:   1,
+		Div:    "default",
+	}
+}
+
+func TestCompute(t *testing.T) {
+	entries := Compute([]outcome.OutcomeItem{
+		game(12, "sam", "greg", 20, false),
+		game(10, "sam", "greg", 10, false),
+		game(11, "greg", "sam", 5, false),
+		game(13, "sam", "greg", 15, true),
+	})
+
+	if len(entries) != 2 {
+		t.Fatalf("should have two entries but got %+v", entries)
+	}
+
+	sam := entries[0]
+	if sam.Email != "sam" || sam.Rank != 1 || sam.Wins != 2 || sam.Losses != 1 || sam.Pushes != 1 {
+		t.Fatalf("sam should lead with 2-1-1 but got %+v", sam)
+	}
+
+	if sam.Net != 25 || sam.Wagered != 35 {
+		t.Fatalf("sam should be up 25 on 35 wagered but got %+v", sam)
+	}
+
+	if sam.Streak != "W1" || sam.LongestWinStreak != 1 || sam.Results != "WLWP" {
+		t.Fatalf("sam's results should replay in game order but got %+v", sam)
+	}
+
+	greg := entries[1]
+	if greg.Rank != 2 || greg.Net != -25 || greg.Streak != "L1" {
+		t.Fatalf("greg should be second and down 25 but got %+v", greg)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	weekOne := Compute([]outcome.OutcomeItem{game(10, "sam", "greg", 10, false)})
+	weekTwo := Compute([]outcome.OutcomeItem{game(17, "sam", "greg", 10, false), game(17, "greg", "pat", 30, false)})
+
+	season := Combine(append(weekOne, weekTwo...))
+
+	if len(season) != 3 {
+		t.Fatalf("should have three entries but got %+v", season)
+	}
+
+	if season[0].Email != "sam" || season[0].Net != 20 || season[0].Streak != "W2" || season[0].LongestWinStreak != 2 {
+		t.Fatalf("sam should lead the season on a two game win streak but got %+v", season[0])
+	}
+
+	if season[1].Email != "greg" || season[1].Net != 10 || season[1].Results != "LLW" {
+		t.Fatalf("greg should be second but got %+v", season[1])
+	}
+
+	if season[2].Roi != -1 {
+		t.Fatalf("pat should have lost everything wagered but got %+v", season[2])
+	}
+}
+
+func TestRankTies(t *testing.T) {
+	entries := Compute([]outcome.OutcomeItem{game(10, "sam", "greg", 10, true)})
+
+	if entries[0].Rank != 1 || entries[1].Rank != 1 {
+		t.Fatalf("a push should leave both players tied but got %+v", entries)
+	}
+}
+
+func TestSeasonOf(t *testing.T) {
+	superBowl := game(1, "sam", "greg", 10, false)
+	superBowl.Date = time.Date(2024, time.February, 11, 0, 0, 0, 0, time.UTC)
+
+	if season := SeasonOf(superBowl); season != "2023" {
+		t.Fatalf("the super bowl belongs to the 2023 season but got %s", season)
+	}
+
+	superBowl.Season = "2024"
+	if season := SeasonOf(superBowl); season != "2024" {
+		t.Fatalf("a tagged season should win but got %s", season)
+	}
+}
+
+type outcomes struct {
+	outcome.Service
+	byWeek map[int][]outcome.OutcomeItem
+}
+
+func (o *outcomes) GetByWeek(ctx context.Context, div string, week int) []outcome.OutcomeItem {
+	return o.byWeek[week]
+}
+
+// lagging only has week 1 stored, as though week 2's write hadn't landed yet.
+type lagging struct {
+	database.Service[DynamoItem, Item]
+	consistent []bool
+	written    []Item
+}
+
+func (l *lagging) Query(ctx context.Context, params *dynamodb.QueryInput) []Item {
+	l.consistent = append(l.consistent, *params.ConsistentRead)
+	return toItems(Compute([]outcome.OutcomeItem{game(10, "sam", "greg", 10, false)}), "default", "2023", 1)
+}
+
+func (l *lagging) Write(ctx context.Context, items []Item) {
+	l.written = append(l.written, items...)
+}
+
+func TestRefreshRollsUpFromMemory(t *testing.T) {
+	weekTwo := game(17, "greg", "pat", 30, false)
+	weekTwo.Week = 2
+	db := &lagging{}
+	service := NewService(db, &outcomes{byWeek: map[int][]outcome.OutcomeItem{2: {weekTwo}}})
+
+	service.Refresh(context.TODO(), "default", "2023", 2)
+
+	if len(db.consistent) != 1 || !db.consistent[0] {
+		t.Errorf("should read the stored weeks consistently but read %v", db.consistent)
+	}
+
+	season := make(map[string]Item)
+	for _, item := range db.written {
+		if item.Week == 0 {
+			season[item.Email] = item
+		}
+	}
+	if len(season) != 3 || season["greg"].Net != 20 || season["pat"].Net != -30 || season["sam"].Net != 10 {
+		t.Errorf("should roll up both weeks but wrote %+v", season)
+	}
+}
+
+func TestKeysEscapeTheSeparator(t *testing.T) {
+	for _, week := range []int{0, 3} {
+		item := Item{League: "fam|ily", Season: "2023", Week: week, Entry: withStats(Entry{Email: `sam\@sam.com`, Wins: 1, Results: win})}
+		if roundTrip := item.GetDynamoItem().(DynamoItem).GetItem().(Item); roundTrip != item {
+			t.Errorf("expected %+v but got %+v", item, roundTrip)
+		}
+	}
+}
+
+func TestLegacyKeys(t *testing.T) {
+	week := DynamoItem{Id: "LB|default|2023", SortKey: "W|03|sam@sam.com"}.GetItem().(Item)
+	if week.League != "default" || week.Season != "2023" || week.Week != 3 || week.Email != "sam@sam.com" {
+		t.Errorf("should read a week key from before escaping but got %+v", week)
+	}
+
+	season := DynamoItem{Id: "LB|default|2023", SortKey: "S|sam@sam.com"}.GetItem().(Item)
+	if season.Week != 0 || season.Email != "sam@sam.com" {
+		t.Errorf("should read a season key from before escaping but got %+v", season)
+	}
+}
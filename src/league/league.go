@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -11,13 +12,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/sport"
+	"sammy.link/util"
 )
 
 type UserInLeagueDynamoItem struct {
 	Id      string `dynamodbav:"id"`
 	SortKey string `dynamodbav:"sortKey"`
-	Name    string `dynamodbav:"na"`
+	Name    string `dynamodbav:"name"`
 	Total   int64  `dynamodbav:"amount"`
+	Version int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyName string `dynamodbav:"na,omitempty"`
 }
 
 type UserInLeagueItem struct {
@@ -28,40 +35,87 @@ type UserInLeagueItem struct {
 }
 
 type LeagueDynamoItem struct {
-	Id        string `dynamodbav:"id"`
-	SortKey   string `dynamodbav:"sortKey"`
-	AdminUser string `dynamodbav:"name"`
+	Id        string   `dynamodbav:"id"`
+	SortKey   string   `dynamodbav:"sortKey"`
+	AdminUser string   `dynamodbav:"adminUser"`
+	Bankroll  int64    `dynamodbav:"bankroll"`
+	Sports    []string `dynamodbav:"sports"`
+	Version   int      `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyAdminUser string `dynamodbav:"name,omitempty"`
 }
 
 type LeagueItem struct {
-	Name      string `json:"name"`
-	AdminUser string `json:"adminUser"`
+	Name      string   `json:"name"`
+	AdminUser string   `json:"adminUser"`
+	Bankroll  int64    `json:"bankroll"`
+	Sports    []string `json:"sports"`
+}
+
+// EnabledSports returns the kinds of games the league bets on, falling back to
+// football for leagues that never opted into anything else.
+func (item LeagueItem) EnabledSports() []string {
+	if len(item.Sports) == 0 {
+		return sport.DefaultKinds
+	}
+	return item.Sports
+}
+
+func (item LeagueItem) Allows(kind string) bool {
+	return slices.Contains(item.EnabledSports(), kind)
 }
 
 type Service interface {
 	AddUser(ctx context.Context, email UserInLeagueItem)
 	GetUsers(ctx context.Context, league string) []UserInLeagueItem
+	GetUsersPage(ctx context.Context, league string, limit int32, cursor string) ([]UserInLeagueItem, string, error)
 	Create(ctx context.Context, league LeagueItem)
+	GetLeagues(ctx context.Context) []LeagueItem
+	GetLeague(ctx context.Context, league string) (LeagueItem, bool)
+	SetSports(ctx context.Context, league string, sports []string)
 	UpdateUserName(ctx context.Context, league string, email string, name string)
-	UpdateUserAmount(ctx context.Context, league string, email string, amount int64)
+	Pay(ctx context.Context, league string, changes map[string]int64, writes ...types.TransactWriteItem) error
+	SetUserAmount(ctx context.Context, league string, email string, amount int64) error
 }
 
-func NewService(leagueDatabaseService database.Service[LeagueDynamoItem, LeagueItem], userDatabaseService database.Service[UserInLeagueDynamoItem, UserInLeagueItem]) Service {
+func NewService(leagueDatabaseService database.Service[LeagueDynamoItem, LeagueItem], userDatabaseService database.Service[UserInLeagueDynamoItem, UserInLeagueItem], publisher event.Publisher) Service {
 	return &LeagueService{
 		leagueDatabaseService: leagueDatabaseService,
 		userDatabaseService:   userDatabaseService,
+		publisher:             publisher,
 	}
 }
 
 type LeagueService struct {
 	leagueDatabaseService database.Service[LeagueDynamoItem, LeagueItem]
 	userDatabaseService   database.Service[UserInLeagueDynamoItem, UserInLeagueItem]
+	publisher             event.Publisher
+}
+
+// BalanceChange is the detail of a BalanceChanged event. Settling a bet changes a
+// balance by Change, a season rollover sets it to Balance.
+type BalanceChange struct {
+	League  string `json:"league"`
+	Email   string `json:"email"`
+	Change  int64  `json:"change,omitempty"`
+	Balance *int64 `json:"balance,omitempty"`
+}
+
+func (dynamoItem LeagueDynamoItem) upgrade() LeagueDynamoItem {
+	if dynamoItem.Version < 1 {
+		dynamoItem.AdminUser = util.Coalesce(dynamoItem.AdminUser, dynamoItem.LegacyAdminUser)
+	}
+	return dynamoItem
 }
 
 func (dynamoItem LeagueDynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
 	return LeagueItem{
 		Name:      dynamoItem.SortKey,
 		AdminUser: dynamoItem.AdminUser,
+		Bankroll:  dynamoItem.Bankroll,
+		Sports:    dynamoItem.Sports,
 	}
 }
 
@@ -70,10 +124,22 @@ func (item LeagueItem) GetDynamoItem() database.DynamoItem {
 		Id:        "LEAGUE",
 		SortKey:   item.Name,
 		AdminUser: item.AdminUser,
+		Bankroll:  item.Bankroll,
+		Sports:    item.Sports,
+		Version:   database.Version,
+	}
+}
+
+func (dynamoItem UserInLeagueDynamoItem) upgrade() UserInLeagueDynamoItem {
+	if dynamoItem.Version < 1 {
+		dynamoItem.Name = util.Coalesce(dynamoItem.Name, dynamoItem.LegacyName)
 	}
+	return dynamoItem
 }
 
 func (dynamoItem UserInLeagueDynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
 	return UserInLeagueItem{
 		Email:  dynamoItem.SortKey,
 		Name:   dynamoItem.Name,
@@ -88,6 +154,7 @@ func (item UserInLeagueItem) GetDynamoItem() database.DynamoItem {
 		SortKey: item.Email,
 		Name:    item.Name,
 		Total:   item.Total,
+		Version: database.Version,
 	}
 }
 
@@ -96,7 +163,21 @@ func getUserId(league string) string {
 }
 
 func (s *LeagueService) UpdateUserName(ctx context.Context, league string, email string, name string) {
+	update := NameUpdate(league, email, name)
 	s.userDatabaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key:                       update.Key,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+		TableName:                 update.TableName,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ConditionExpression:       update.ConditionExpression,
+		UpdateExpression:          update.UpdateExpression,
+	})
+}
+
+// NameUpdate renames a member of league, for use in a transaction. It fails for
+// anyone who isn't a member rather than adding them.
+func NameUpdate(league string, email string, name string) *types.Update {
+	return &types.Update{
 		Key: map[string]types.AttributeValue{
 			"id":      &types.AttributeValueMemberS{Value: getUserId(league)},
 			"sortKey": &types.AttributeValueMemberS{Value: email},
@@ -104,14 +185,17 @@ func (s *LeagueService) UpdateUserName(ctx context.Context, league string, email
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":name": &types.AttributeValueMemberS{Value: name},
 		},
-		TableName:        aws.String(os.Getenv("TABLE_NAME")),
-		UpdateExpression: aws.String("SET na = :name"),
-	})
+		TableName:                aws.String(os.Getenv("TABLE_NAME")),
+		ExpressionAttributeNames: map[string]string{"#name": "name"},
+		ConditionExpression:      aws.String("attribute_exists(sortKey)"),
+		// drops the name items written before version 1 kept under "na"
+		UpdateExpression: aws.String("SET #name = :name REMOVE na"),
+	}
 }
 
-func (s *LeagueService) UpdateUserAmount(ctx context.Context, league string, email string, amount int64) {
-	fmt.Printf("amount is %s; id = %s; sortKey =%s\n", strconv.FormatInt(amount, 10), getUserId(league), email)
-	s.userDatabaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+// AmountUpdate changes a member's balance by amount, for use in a transaction.
+func AmountUpdate(league string, email string, amount int64) *types.Update {
+	return &types.Update{
 		Key: map[string]types.AttributeValue{
 			"id":      &types.AttributeValueMemberS{Value: getUserId(league)},
 			"sortKey": &types.AttributeValueMemberS{Value: email},
@@ -121,22 +205,124 @@ func (s *LeagueService) UpdateUserAmount(ctx context.Context, league string, ema
 		},
 		TableName:        aws.String(os.Getenv("TABLE_NAME")),
 		UpdateExpression: aws.String("add amount :amount"),
+	}
+}
+
+// Pay changes members' balances by changes, keyed by email, in one transaction with
+// writes, which come first so their cancellation reasons keep their index.
+// BalanceChanged is published for each member once it commits.
+func (s *LeagueService) Pay(ctx context.Context, league string, changes map[string]int64, writes ...types.TransactWriteItem) error {
+	items := slices.Clone(writes)
+	for email, amount := range changes {
+		if amount != 0 {
+			items = append(items, types.TransactWriteItem{Update: AmountUpdate(league, email, amount)})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	if err := s.userDatabaseService.TransactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items}); err != nil {
+		return err
+	}
+
+	events := make([]event.Event, 0, len(changes))
+	for email, amount := range changes {
+		if amount != 0 {
+			events = append(events, event.Event{Type: event.BalanceChanged, Detail: BalanceChange{League: league, Email: email, Change: amount}})
+		}
+	}
+	event.Emit(ctx, s.publisher, events...)
+	return nil
+}
+
+func (s *LeagueService) SetUserAmount(ctx context.Context, league string, email string, amount int64) error {
+	_, err := s.userDatabaseService.UpdateReturning(ctx, &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: getUserId(league)},
+			"sortKey": &types.AttributeValueMemberS{Value: email},
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":amount": &types.AttributeValueMemberN{Value: strconv.FormatInt(amount, 10)},
+		},
+		TableName:        aws.String(os.Getenv("TABLE_NAME")),
+		UpdateExpression: aws.String("SET amount = :amount"),
 	})
+	if err != nil {
+		return err
+	}
+	event.Emit(ctx, s.publisher, event.Event{Type: event.BalanceChanged, Detail: BalanceChange{League: league, Email: email, Balance: &amount}})
+	return nil
 }
 
 func (s *LeagueService) AddUser(ctx context.Context, item UserInLeagueItem) {
 	s.userDatabaseService.Write(ctx, []UserInLeagueItem{item})
 }
 
-func (s *LeagueService) GetUsers(ctx context.Context, league string) []UserInLeagueItem {
-	return s.userDatabaseService.Query(ctx, &dynamodb.QueryInput{
+func usersQuery(league string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
 		TableName:              aws.String(os.Getenv("TABLE_NAME")),
 		KeyConditionExpression: aws.String("id = :id"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":id": &types.AttributeValueMemberS{Value: fmt.Sprintf("L|%s", league)},
 		},
+	}
+}
+
+// GetUsers reads consistently, since a season is closed from what it returns.
+func (s *LeagueService) GetUsers(ctx context.Context, league string) []UserInLeagueItem {
+	query := usersQuery(league)
+	query.ConsistentRead = aws.Bool(true)
+	return s.userDatabaseService.Query(ctx, query)
+}
+
+func (s *LeagueService) GetUsersPage(ctx context.Context, league string, limit int32, cursor string) ([]UserInLeagueItem, string, error) {
+	return s.userDatabaseService.QueryPaged(ctx, usersQuery(league), limit, cursor)
+}
+
+func (s *LeagueService) GetLeagues(ctx context.Context) []LeagueItem {
+	return s.leagueDatabaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: "LEAGUE"},
+		},
 	})
+}
+
+func (s *LeagueService) GetLeague(ctx context.Context, league string) (LeagueItem, bool) {
+	leagues := s.leagueDatabaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id and sortKey = :name"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":   &types.AttributeValueMemberS{Value: "LEAGUE"},
+			":name": &types.AttributeValueMemberS{Value: league},
+		},
+	})
+
+	if len(leagues) == 0 {
+		return LeagueItem{}, false
+	}
+	return leagues[0], true
+}
+
+func (s *LeagueService) SetSports(ctx context.Context, league string, sports []string) {
+	sportList := make([]types.AttributeValue, len(sports))
+	for i, kind := range sports {
+		sportList[i] = &types.AttributeValueMemberS{Value: kind}
+	}
 
+	s.leagueDatabaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: "LEAGUE"},
+			"sortKey": &types.AttributeValueMemberS{Value: league},
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sports": &types.AttributeValueMemberL{Value: sportList},
+		},
+		TableName:        aws.String(os.Getenv("TABLE_NAME")),
+		UpdateExpression: aws.String("SET sports = :sports"),
+	})
 }
 
 func (s *LeagueService) Create(ctx context.Context, league LeagueItem) {
@@ -0,0 +1,3 @@
+module sammy.link/logging
+
+go 1.21.0
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Attributes every Lambda tags its logger with, see app.Logged.
+const (
+	RequestIdKey = "requestId"
+	UserKey      = "user"
+	LeagueKey    = "league"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are attributes that are never logged, whatever they hold.
+var secretKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"ticket":        true,
+	"authorization": true,
+	"auth":          true,
+	"p256dh":        true,
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// HashUser stands in for an email in logs. The same member always gets the same hash,
+// so their requests can still be followed.
+func HashUser(email string) string {
+	if email == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "u_" + hex.EncodeToString(sum[:8])
+}
+
+// Redact replaces emails anywhere in a string with their hash.
+func Redact(value string) string {
+	return emailPattern.ReplaceAllStringFunc(value, HashUser)
+}
+
+// redact runs on every attribute, the message included, before it's written. Values
+// that aren't strings are redacted as the JSON they'd be logged as.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && (a.Key == slog.LevelKey || a.Key == slog.TimeKey || a.Key == slog.SourceKey) {
+		return a
+	}
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+		marshalled, err := json.Marshal(a.Value.Any())
+		if err != nil {
+			return slog.String(a.Key, redacted)
+		}
+		return slog.Any(a.Key, json.RawMessage(Redact(string(marshalled))))
+	}
+	return a
+}
+
+// New logs JSON lines to w, which CloudWatch can query by field.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redact}))
+}
+
+// LevelFromEnv reads LOG_LEVEL, one of debug, info, warn or error. Anything else is
+// info.
+func LevelFromEnv() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+type contextKey struct{}
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext is the logger ctx was tagged with, or the default one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With tags everything logged under ctx with args.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
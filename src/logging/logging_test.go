@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+type bid struct {
+	User   string `json:"user"`
+	Amount int64  `json:"amount"`
+}
+
+func TestRedaction(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelInfo)
+	ctx := WithLogger(context.TODO(), logger)
+	ctx = With(ctx, RequestIdKey, "abc", UserKey, HashUser("Sam@Sam.com"), LeagueKey, "default")
+
+	FromContext(ctx).Error("couldn't rename sam@sam.com",
+		"err", errors.New("name taken for sam@sam.com"),
+		"bid", bid{User: "sam@sam.com", Amount: 10},
+		"ticket", "eyJlbWFpbCI6InNhbUBzYW0uY29tIn0.abc",
+	)
+
+	line := out.String()
+	if strings.Contains(line, "sam@sam.com") || strings.Contains(line, "eyJ") {
+		t.Fatalf("leaked %s", line)
+	}
+
+	var logged struct {
+		Msg       string `json:"msg"`
+		Err       string `json:"err"`
+		Bid       bid    `json:"bid"`
+		RequestId string `json:"requestId"`
+		User      string `json:"user"`
+		League    string `json:"league"`
+		Ticket    string `json:"ticket"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &logged); err != nil {
+		t.Fatal(err)
+	}
+	hash := HashUser("sam@sam.com")
+	if logged.User != hash || logged.Msg != "couldn't rename "+hash || logged.Err != "name taken for "+hash || logged.Bid.User != hash || logged.Bid.Amount != 10 {
+		t.Errorf("logged %+v", logged)
+	}
+	if logged.RequestId != "abc" || logged.League != "default" || logged.Ticket != redacted {
+		t.Errorf("logged %+v", logged)
+	}
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	for value, want := range map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "": slog.LevelInfo, "loud": slog.LevelInfo} {
+		os.Setenv("LOG_LEVEL", value)
+		if got := LevelFromEnv(); got != want {
+			t.Errorf("LOG_LEVEL=%s is %s, want %s", value, got, want)
+		}
+	}
+	os.Unsetenv("LOG_LEVEL")
+
+	var out bytes.Buffer
+	FromContext(WithLogger(context.TODO(), New(&out, slog.LevelWarn))).Info("quiet")
+	if out.Len() != 0 {
+		t.Errorf("logged below the level: %s", out.String())
+	}
+}
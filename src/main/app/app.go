@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"sammy.link/auth"
+	"sammy.link/bet"
+	"sammy.link/bid"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/espn"
+	"sammy.link/event"
+	"sammy.link/history"
+	"sammy.link/leaderboard"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/marketplace"
+	"sammy.link/notification"
+	"sammy.link/outcome"
+	"sammy.link/profile"
+	"sammy.link/realtime"
+	"sammy.link/scheduler"
+	"sammy.link/scores"
+	"sammy.link/season"
+	"sammy.link/user"
+	"sammy.link/util"
+)
+
+type Handler func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+type WebsocketHandler func(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error)
+
+type Config struct {
+	// ResolveLambdaArn is what per game resolve schedules invoke.
+	ResolveLambdaArn string
+	// ResolveRoleArn is the role those schedules invoke it as.
+	ResolveRoleArn string
+	// EventBusName is where domain events are published, none are if it's empty.
+	EventBusName string
+	// WebsocketEndpoint is the WebSocket API's management endpoint, nothing is
+	// broadcast if it's empty.
+	WebsocketEndpoint string
+	// TicketSecret signs the tickets WebSocket connections are opened with.
+	TicketSecret string
+	Notification notification.Config
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		ResolveLambdaArn:  os.Getenv("RESOLVE_LAMBDA_ARN"),
+		ResolveRoleArn:    os.Getenv("RESOLVE_ROLE_ARN"),
+		EventBusName:      os.Getenv("EVENT_BUS_NAME"),
+		WebsocketEndpoint: os.Getenv("WEBSOCKET_ENDPOINT"),
+		TicketSecret:      os.Getenv("TICKET_SECRET"),
+		Notification:      notification.ConfigFromEnv(),
+	}
+}
+
+// Dependencies are the outside world the services are built on. Tests fill them in
+// with fakes.
+type Dependencies struct {
+	Database  database.Client
+	Espn      espn.Service
+	Clock     clock.Clock
+	Scheduler func(targetArn string) scheduler.Scheduler
+	// Senders are the channels notifications go out on, none if left empty.
+	Senders []notification.Sender
+	// Publisher gets the domain events, which are dropped if left empty.
+	Publisher event.Publisher
+	// Poster sends to WebSocket connections, broadcasts are dropped if left empty.
+	Poster realtime.Poster
+}
+
+// App holds every service, built once per Lambda container and shared by its
+// invocations.
+type App struct {
+	Config       Config
+	Clock        clock.Clock
+	Scheduler    func(targetArn string) scheduler.Scheduler
+	Espn         espn.Service
+	Scores       scores.Provider
+	Bet          bet.Service
+	Bid          bid.Service
+	Marketplace  marketplace.Service
+	Outcome      outcome.Service
+	User         user.Service
+	Profile      profile.Service
+	Notification notification.Service
+	Auth         auth.Service
+	League       league.Service
+	Season       season.Service
+	History      history.Service
+	Leaderboard  leaderboard.Service
+	Realtime     realtime.Service
+	Broadcaster  *realtime.Broadcaster
+}
+
+// New builds the App against AWS and ESPN, and logs at LOG_LEVEL from then on.
+func New(ctx context.Context, config Config) (*App, error) {
+	slog.SetDefault(logging.New(os.Stdout, logging.LevelFromEnv()))
+
+	awsConfig, err := util.GetAwsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := dynamodb.NewFromConfig(awsConfig)
+	clk := clock.System
+
+	senders, err := notification.NewSenders(config.Notification, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher := event.Discard
+	if config.EventBusName != "" {
+		publisher = event.NewFromConfig(awsConfig, config.EventBusName, clk)
+	}
+
+	poster := realtime.Discard
+	if config.WebsocketEndpoint != "" {
+		poster = realtime.NewApiGatewayPoster(awsConfig, config.WebsocketEndpoint, clk)
+	}
+
+	return NewWith(config, Dependencies{
+		Database: client,
+		// the app lives as long as the lambda, so warm invocations keep what was fetched
+		Espn: espn.NewCachedService(espn.NewService(http.Client{}), espn.DefaultCacheConfig(), clk,
+			espn.NewMemoryStore(clk), espn.NewDynamoStore(database.NewDatabaseService[espn.CacheDynamoItem, espn.CacheItem](client, clk), clk)),
+		Clock: clk,
+		Scheduler: func(targetArn string) scheduler.Scheduler {
+			return scheduler.NewFromConfig(awsConfig, targetArn, config.ResolveRoleArn, clk)
+		},
+		Senders:   senders,
+		Publisher: publisher,
+		Poster:    poster,
+	}), nil
+}
+
+// Must is for mains, which can't do anything without their services.
+func Must(app *App, err error) *App {
+	if err != nil {
+		panic(err)
+	}
+	return app
+}
+
+func NewWith(config Config, dependencies Dependencies) *App {
+	client := dependencies.Database
+	clk := dependencies.Clock
+	publisher := dependencies.Publisher
+	if publisher == nil {
+		publisher = event.Discard
+	}
+	poster := dependencies.Poster
+	if poster == nil {
+		poster = realtime.Discard
+	}
+	userService := user.NewService(database.NewDatabaseService[user.DynamoItem, user.Item](client, clk))
+	profileService := profile.NewService(database.NewDatabaseService[profile.DynamoItem, profile.Item](client, clk))
+	outcomeService := outcome.NewService(database.NewDatabaseService[outcome.OutcomeDynamoItem, outcome.OutcomeItem](client, clk), publisher)
+	realtimeService := realtime.NewService(database.NewDatabaseService[realtime.ConnectionDynamoItem, realtime.Connection](client, clk),
+		database.NewDatabaseService[realtime.SubscriptionDynamoItem, realtime.Subscription](client, clk))
+
+	return &App{
+		Config:       config,
+		Clock:        clk,
+		Scheduler:    dependencies.Scheduler,
+		Espn:         dependencies.Espn,
+		Scores:       scores.NewEspnProvider(dependencies.Espn),
+		Bet:          bet.NewService(database.NewDatabaseService[bet.BetDynamoItem, bet.Bet](client, clk), publisher),
+		Bid:          bid.NewService(database.NewDatabaseService[bid.DyanmoBidItem, bid.Bid](client, clk), publisher),
+		Marketplace:  marketplace.NewService(database.NewDatabaseService[marketplace.MarketplaceDynamoDbItem, marketplace.MarketplaceItem](client, clk), publisher),
+		Outcome:      outcomeService,
+		User:         userService,
+		Profile:      profileService,
+		Notification: notification.NewService(profileService, dependencies.Senders),
+		Auth:         auth.NewService(userService),
+		League: league.NewService(database.NewDatabaseService[league.LeagueDynamoItem, league.LeagueItem](client, clk),
+			database.NewDatabaseService[league.UserInLeagueDynamoItem, league.UserInLeagueItem](client, clk), publisher),
+		Season: season.NewService(database.NewDatabaseService[season.DynamoItem, season.Item](client, clk),
+			database.NewDatabaseService[season.StandingDynamoItem, season.StandingItem](client, clk)),
+		History: history.NewService(database.NewDatabaseService[history.DynamoItem, history.Item](client, clk),
+			database.NewDatabaseService[history.RecordDynamoItem, history.RecordItem](client, clk)),
+		Leaderboard: leaderboard.NewService(database.NewDatabaseService[leaderboard.DynamoItem, leaderboard.Item](client, clk), outcomeService),
+		Realtime:    realtimeService,
+		Broadcaster: realtime.NewBroadcaster(realtimeService, poster),
+	}
+}
+
+func (app *App) Now() time.Time {
+	return app.Clock.Now()
+}
+
+// ResolveScheduler schedules per game resolution against the configured Lambda.
+func (app *App) ResolveScheduler() scheduler.Scheduler {
+	return app.Scheduler(app.Config.ResolveLambdaArn)
+}
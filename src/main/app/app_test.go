@@ -0,0 +1,130 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/auth"
+	"sammy.link/bid"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/main/bid/getByUser/handler"
+	"sammy.link/scheduler"
+)
+
+// stubClient answers queries with items and fails the test on anything else it is
+// asked to do.
+type stubClient struct {
+	database.Client
+	items   []map[string]types.AttributeValue
+	queries []*dynamodb.QueryInput
+}
+
+func (c *stubClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.queries = append(c.queries, params)
+	return &dynamodb.QueryOutput{Items: c.items}, nil
+}
+
+func TestNewWith(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	placed := bid.Bid{
+		Kind:             "nfl",
+		AwayTeam:         "Jets",
+		HomeTeam:         "Chiefs",
+		ChosenCompetitor: "Jets",
+		Spread:           "KC -9.5",
+		Amount:           10,
+		Date:             now,
+		CreateDate:       now.Add(-time.Hour),
+		User:             "someone@sammy.link",
+		Div:              "league",
+	}
+	item, err := attributevalue.MarshalMap(placed.GetDynamoItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &stubClient{items: []map[string]types.AttributeValue{item}}
+	fake := &scheduler.FakeScheduler{}
+	a := app.NewWith(app.Config{ResolveLambdaArn: "arn:resolve"}, app.Dependencies{
+		Database: client,
+		Clock:    clock.NewFake(now),
+		Scheduler: func(targetArn string) scheduler.Scheduler {
+			if targetArn != "arn:resolve" {
+				t.Errorf("scheduler built for %s", targetArn)
+			}
+			return fake
+		},
+	})
+
+	if !a.Now().Equal(now) {
+		t.Errorf("Now() = %v, want %v", a.Now(), now)
+	}
+	if a.ResolveScheduler() != fake {
+		t.Error("ResolveScheduler() did not use the injected scheduler")
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "someone@sammy.link"},
+				},
+			},
+		},
+	}
+	resp, err := handler.New(a)(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, body = %s", resp.StatusCode, resp.Body)
+	}
+	if len(client.queries) != 1 {
+		t.Fatalf("made %d queries through the stub client", len(client.queries))
+	}
+
+	var bids []bid.Bid
+	if err := json.Unmarshal([]byte(resp.Body), &bids); err != nil {
+		t.Fatal(err)
+	}
+	if len(bids) != 1 || bids[0].User != placed.User || bids[0].Amount != placed.Amount {
+		t.Errorf("bids = %+v", bids)
+	}
+}
+
+func TestLogged(t *testing.T) {
+	var out bytes.Buffer
+	ctx := logging.WithLogger(context.TODO(), logging.New(&out, slog.LevelInfo))
+
+	h := app.Logged(func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		logging.FromContext(ctx).Info("renamed sam@sam.com")
+		return events.APIGatewayV2HTTPResponse{StatusCode: 200}, nil
+	})
+	h(ctx, events.APIGatewayV2HTTPRequest{
+		PathParameters: map[string]string{"league": "default"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID: "abc",
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+				Claims: map[string]string{auth.EmailClaim: "sam@sam.com"},
+			}},
+		},
+	})
+
+	var logged map[string]string
+	json.Unmarshal(out.Bytes(), &logged)
+	hash := logging.HashUser("sam@sam.com")
+	if logged["requestId"] != "abc" || logged["user"] != hash || logged["league"] != "default" || logged["msg"] != "renamed "+hash {
+		t.Errorf("logged %s", out.String())
+	}
+}
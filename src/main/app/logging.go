@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"sammy.link/auth"
+	"sammy.link/logging"
+	"sammy.link/util"
+)
+
+// Logged tags everything h logs with the API Gateway request id, the caller's hash
+// and the league in the path or query, if there is one. Handlers that read the league
+// from the body tag it themselves.
+func Logged(h Handler) Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		args := []any{
+			logging.RequestIdKey, request.RequestContext.RequestID,
+			logging.UserKey, logging.HashUser(auth.GetEmail(request)),
+		}
+		if league := util.Coalesce(request.PathParameters["league"], request.QueryStringParameters["league"], request.QueryStringParameters["div"]); league != "" {
+			args = append(args, logging.LeagueKey, league)
+		}
+		return h(logging.With(ctx, args...), request)
+	}
+}
+
+// LoggedWebsocket is Logged for WebSocket routes, which only know their connection
+// until it's looked up.
+func LoggedWebsocket(h WebsocketHandler) WebsocketHandler {
+	return func(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx = logging.With(ctx, logging.RequestIdKey, request.RequestContext.RequestID, "connectionId", request.RequestContext.ConnectionID)
+		return h(ctx, request)
+	}
+}
+
+// WithInvocation tags ctx's logger with the Lambda request id, for functions that
+// aren't behind API Gateway.
+func WithInvocation(ctx context.Context) context.Context {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return logging.With(ctx, logging.RequestIdKey, lc.AwsRequestID)
+	}
+	return ctx
+}
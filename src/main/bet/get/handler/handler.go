@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/bet"
+	"sammy.link/bid"
+	"sammy.link/main/app"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, betService bet.Service, bidService bid.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	var bets []bet.Bet
+	div := request.QueryStringParameters["div"]
+
+	v := validation.New()
+	limit, cursor, paged := v.Page(request.QueryStringParameters)
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+	next := ""
+	var err error
+
+	if week, ok := request.PathParameters["date"]; ok {
+		// the route calls it a date but it's the week number
+		v.Number("date", week).Min(1)
+		v.String("div", div).Required()
+		if !v.Valid() {
+			return validation.BadRequest(v.Err())
+		}
+		if _, ok := authService.Authorize(ctx, request, div); !ok {
+			return auth.ForbiddenResponse()
+		}
+		if paged {
+			bets, next, err = betService.GetBetsByWeekPage(ctx, div, week, limit, cursor)
+		} else {
+			bets = betService.GetBetsByWeek(ctx, div, week)
+		}
+	} else if paged {
+		user := auth.GetEmail(request)
+		bets, next, err = betService.GetBetsByUserPage(ctx, user, limit, cursor)
+
+		// unmatched bids are short lived, so they all come with the first page
+		if err == nil && cursor == "" {
+			bets = append(getUnmatched(user, bidService.GetBidsByUser(ctx, user)), bets...)
+		}
+	} else {
+		user := auth.GetEmail(request)
+		betChannel := make(chan []bet.Bet, 3)
+
+		go func() {
+			myBets := betService.GetBetsByUser(ctx, user, true)
+			betChannel <- myBets
+		}()
+
+		go func() {
+			myBets := betService.GetBetsByUser(ctx, user, false)
+			betChannel <- myBets
+		}()
+
+		go func() {
+			betChannel <- getUnmatched(user, bidService.GetBidsByUser(ctx, user))
+		}()
+
+		for i := 0; i < 3; i++ {
+			channelBets := <-betChannel
+			bets = append(bets, channelBets...)
+		}
+
+	}
+
+	if err != nil {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+		return util.ApigatewayResponse(string(resp), 400)
+	}
+
+	slices.SortFunc[[]bet.Bet](bets, func(betOne bet.Bet, betTwo bet.Bet) int {
+		if betTwo.Date.Before(betOne.Date) {
+			return -1
+		} else if betOne.Date.Before(betTwo.Date) {
+			return 1
+		}
+		return 0
+	})
+
+	if paged {
+		jsonBets, _ := json.Marshal(util.Page[bet.Bet]{Items: bets, Cursor: next})
+		return util.ApigatewayResponse(string(jsonBets), 200)
+	}
+
+	jsonBets, _ := json.Marshal(bets)
+	return util.ApigatewayResponse(string(jsonBets), 200)
+}
+
+// getUnmatched shows a user's open bids alongside their bets.
+func getUnmatched(user string, bids []bid.Bid) []bet.Bet {
+	notBets := make([]bet.Bet, len(bids))
+
+	for i, badBid := range bids {
+		notBets[i] = bet.Bet{
+			Div:              badBid.Div,
+			Amount:           badBid.Amount,
+			Status:           "BAD",
+			AwayTeam:         badBid.AwayTeam,
+			HomeTeam:         badBid.HomeTeam,
+			Spread:           badBid.Spread,
+			Kind:             badBid.Kind,
+			Week:             badBid.Week,
+			CreateDate:       badBid.CreateDate,
+			Date:             badBid.Date,
+			HomeAbbreviation: badBid.HomeAbbreviation,
+			AwayAbbreviation: badBid.AwayAbbreviation,
+		}
+
+		if badBid.ChosenCompetitor == badBid.AwayTeam {
+			notBets[i].AwayUser = user
+		} else {
+			notBets[i].HomeUser = user
+		}
+	}
+	return notBets
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Bet, a.Bid, a.Auth)
+	}
+}
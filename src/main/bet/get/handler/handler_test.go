@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/main/app"
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
+		PathParameters: map[string]string{
+			"date": "1",
+		},
+		QueryStringParameters: map[string]string{
+			"div": "default",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.Bet,
+		a.Bid,
+		a.Auth)
+	fmt.Printf("your boy %s", resp.Body)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/bet"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/scores"
+	"sammy.link/sport"
+	"sammy.link/util"
+)
+
+const (
+	Winning = "WINNING"
+	Losing  = "LOSING"
+	Push    = "PUSH"
+)
+
+type LiveBet struct {
+	bet.Bet
+	ChosenTeam string `json:"chosenTeam"`
+	EventId    string `json:"eventId"`
+	State      string `json:"state"`
+	Clock      string `json:"clock"`
+	Period     int    `json:"period"`
+	Summary    string `json:"summary"`
+	AwayScore  string `json:"awayScore"`
+	HomeScore  string `json:"homeScore"`
+	Cover      string `json:"cover"`
+}
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, now time.Time, betService bet.Service, provider scores.Provider) (events.APIGatewayV2HTTPResponse, error) {
+
+	email := auth.GetEmail(request)
+
+	// a game that started up to eight hours ago could still be going
+	from := now.Add(-8 * time.Hour)
+	to := now.Add(12 * time.Hour)
+
+	betChannel := make(chan []bet.Bet, 2)
+	for _, isGsi2 := range []bool{true, false} {
+		go func(myIsGsi2 bool) {
+			betChannel <- betService.GetBetsByUserBetween(ctx, email, myIsGsi2, from, to)
+		}(isGsi2)
+	}
+
+	bets := make([]bet.Bet, 0)
+	for i := 0; i < 2; i++ {
+		bets = append(bets, <-betChannel...)
+	}
+
+	games := getGames(ctx, provider, bets, from, to)
+
+	resp, _ := json.Marshal(buildLiveBets(email, bets, games))
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+func getGames(ctx context.Context, provider scores.Provider, bets []bet.Bet, from time.Time, to time.Time) []scores.Game {
+	kinds := make([]string, 0)
+	for _, b := range bets {
+		if !slices.Contains(kinds, b.Kind) {
+			kinds = append(kinds, b.Kind)
+		}
+	}
+
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	games := make([]scores.Game, 0)
+
+	for _, kind := range kinds {
+		kindSport, ok := sport.Get(kind)
+		if !ok {
+			continue
+		}
+
+		waitGroup.Add(1)
+		go func(mySport sport.Sport) {
+			defer waitGroup.Done()
+			kindGames, err := provider.Games(ctx, mySport, from, to)
+			if err != nil {
+				logging.FromContext(ctx).Warn("no live scores", "kind", mySport.Kind, "err", err)
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			games = append(games, kindGames...)
+		}(kindSport)
+	}
+
+	waitGroup.Wait()
+	return games
+}
+
+// buildLiveBets pairs each bet with its game and works out whether the user is
+// currently beating the spread.
+func buildLiveBets(email string, bets []bet.Bet, games []scores.Game) []LiveBet {
+	gameMap := make(map[string]scores.Game)
+	for _, game := range games {
+		gameMap[fmt.Sprintf("%s|%s|%s", game.Kind, game.Away.Name, game.Home.Name)] = game
+	}
+
+	liveBets := make([]LiveBet, 0, len(bets))
+	for _, b := range bets {
+		liveBet := LiveBet{Bet: b, State: scores.Scheduled}
+
+		side := sport.Home
+		liveBet.ChosenTeam = b.HomeTeam
+		if b.AwayUser == email {
+			side = sport.Away
+			liveBet.ChosenTeam = b.AwayTeam
+		}
+
+		if game, ok := gameMap[fmt.Sprintf("%s|%s|%s", b.Kind, b.AwayTeam, b.HomeTeam)]; ok {
+			liveBet.EventId = game.Id
+			liveBet.State = game.Status
+			liveBet.Clock = game.Clock
+			liveBet.Period = game.Period
+			liveBet.Summary = game.Summary
+			liveBet.AwayScore = game.Away.Score
+			liveBet.HomeScore = game.Home.Score
+
+			if game.Status != scores.Scheduled {
+				awayScore, _ := strconv.ParseFloat(game.Away.Score, 64)
+				homeScore, _ := strconv.ParseFloat(game.Home.Score, 64)
+
+				switch sport.Cover(b.Spread, b.AwayAbbreviation, awayScore, homeScore) {
+				case sport.Push:
+					liveBet.Cover = Push
+				case side:
+					liveBet.Cover = Winning
+				default:
+					liveBet.Cover = Losing
+				}
+			}
+		}
+
+		liveBets = append(liveBets, liveBet)
+	}
+
+	slices.SortStableFunc(liveBets, func(a LiveBet, b LiveBet) int {
+		return a.Date.Compare(b.Date)
+	})
+	return liveBets
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Now(), a.Bet, a.Scores)
+	}
+}
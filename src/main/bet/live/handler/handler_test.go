@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/bet"
+	"sammy.link/main/app"
+	"sammy.link/scores"
+)
+
+func TestGet(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.Now(), a.Bet, a.Scores)
+	fmt.Printf("your boy %s", resp.Body)
+}
+
+func TestBuildLiveBets(t *testing.T) {
+	bets := []bet.Bet{
+		{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Spread: "KC -6.5", AwayAbbreviation: "DET"},
+		{Kind: "NFL", AwayTeam: "Philadelphia Eagles", HomeTeam: "New England Patriots", AwayUser: "greg@greg.com", HomeUser: "sam@sam.com", Spread: "PHI -3.0", AwayAbbreviation: "PHI"},
+		{Kind: "NFL", AwayTeam: "Miami Dolphins", HomeTeam: "Buffalo Bills", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Spread: "BUF -2.5", AwayAbbreviation: "MIA"},
+	}
+
+	games := []scores.Game{
+		{Id: "1", Kind: "NFL", Status: scores.InProgress, Clock: "7:42", Period: 3, Away: scores.Team{Name: "Detroit Lions", Score: "14"}, Home: scores.Team{Name: "Kansas City Chiefs", Score: "17"}},
+		{Id: "2", Kind: "NFL", Status: scores.Final, Away: scores.Team{Name: "Philadelphia Eagles", Score: "25"}, Home: scores.Team{Name: "New England Patriots", Score: "22"}},
+	}
+
+	liveBets := buildLiveBets("sam@sam.com", bets, games)
+
+	if liveBets[0].Cover != Winning || liveBets[0].ChosenTeam != "Detroit Lions" || liveBets[0].Clock != "7:42" || liveBets[0].EventId != "1" {
+		t.Fatalf("sam has the lions with 6.5 and should be winning but got %+v", liveBets[0])
+	}
+
+	if liveBets[1].Cover != Push || liveBets[1].State != scores.Final {
+		t.Fatalf("a three point win against three should push but got %+v", liveBets[1])
+	}
+
+	if liveBets[2].Cover != "" || liveBets[2].State != scores.Scheduled {
+		t.Fatalf("a game without scores shouldn't have a cover status but got %+v", liveBets[2])
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"sammy.link/main/app"
+	"sammy.link/main/bet/live/handler"
+)
+
+func main() {
+	lambda.Start(app.Logged(handler.New(app.Must(app.New(context.Background(), app.ConfigFromEnv())))))
+}
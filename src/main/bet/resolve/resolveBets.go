@@ -3,158 +3,347 @@ package main
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"regexp"
+	"slices"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 	"sammy.link/bet"
+	"sammy.link/clock"
 	"sammy.link/database"
-	"sammy.link/espn"
+	"sammy.link/history"
+	"sammy.link/leaderboard"
 	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/main/app"
 	"sammy.link/outcome"
+	"sammy.link/scheduler"
+	"sammy.link/scores"
+	"sammy.link/season"
+	"sammy.link/sport"
 	"sammy.link/util"
 )
 
+// retryAfter is how long to wait before checking again on games that weren't final
+// when their trigger fired. After maxAttempts the daily run picks them up instead.
+const (
+	retryAfter  = time.Hour
+	maxAttempts = 12
+)
+
 func main() {
+	a := app.Must(app.New(context.Background(), app.ConfigFromEnv()))
 	lambda.Start(
-		func(ctx context.Context) {
-			handler(ctx, outcome.NewService(database.GetDatabaseService[outcome.OutcomeDynamoItem, outcome.OutcomeItem](ctx)),
-				bet.NewService(database.GetDatabaseService[bet.BetDynamoItem, bet.Bet](ctx)), espn.NewService(http.Client{}),
-				league.NewService(database.GetDatabaseService[league.LeagueDynamoItem, league.LeagueItem](ctx),
-					database.GetDatabaseService[league.UserInLeagueDynamoItem, league.UserInLeagueItem](ctx)))
+		func(ctx context.Context, target scheduler.Target) {
+			ctx = app.WithInvocation(ctx)
+			resolveArn := ""
+			if lc, ok := lambdacontext.FromContext(ctx); ok {
+				resolveArn = lc.InvokedFunctionArn
+			}
+
+			handler(ctx, target, a.Now(), a.Outcome, a.Bet, a.Scores, a.League, a.Season, a.Leaderboard, a.History, a.Scheduler(resolveArn))
 		})
 }
 
-func handler(ctx context.Context, outcomeService outcome.Service, betService bet.Service, espnServce espn.Service, leagueService league.Service) {
-	fivehours, _ := time.ParseDuration("-5h")
-	yesterday := time.Now().Add(fivehours)
-	bets := betService.GetBetsByEventDate(ctx, yesterday.Format("20060102"))
-	twentyFourHours, _ := time.ParseDuration("-24h")
-	yesterday = time.Now().Add(twentyFourHours)
-	if len(bets) > 0 {
-		kinds := getBetKinds(bets)
-		spreads := getSpreads(bets)
-
-		outcomeChan := make(chan []outcome.OutcomeItem, len(kinds))
-		userLeagueChannel := make(chan league.UserInLeagueItem, 10)
-
-		for _, kind := range kinds {
-
-			go func(myKind string, myBets []bet.Bet, myOutcomeChan chan []outcome.OutcomeItem) {
-				kindResp := espnServce.ConvertKind(myKind)
-				espnResponseChannel := make(chan espn.EspnResponse, 1)
-				espnServce.GetEspnData(kindResp.Sport, kindResp.League, espnResponseChannel, yesterday, yesterday)
-				espnResp := <-espnResponseChannel
-				winners := getWinners(spreads, espnResp)
-				outcomeSlice := make([]outcome.OutcomeItem, 0, len(myBets))
-
-				for _, bet := range bets {
-					if bet.Kind == myKind {
-						gameName := fmt.Sprintf("%s|%s", bet.AwayTeam, bet.HomeTeam)
-						gameResult := winners[gameName]
-						week, _ := strconv.Atoi(gameResult.week)
-						if gameResult.team == bet.AwayTeam {
-							outcomeSlice = append(outcomeSlice, outcome.OutcomeItem{
-								Winner:  bet.AwayUser,
-								Loser:   bet.HomeUser,
-								EventId: gameResult.eventId,
-								Week:    week,
-								Amount:  bet.Amount,
-								Id:      uuid.NewString(),
-								Div:     bet.Div,
-							})
-							sendUpdateUserInfo(bet.AwayUser, bet.HomeUser, bet.Amount, bet.Div, userLeagueChannel)
-						} else if gameResult.team == bet.HomeTeam {
-							outcomeSlice = append(outcomeSlice, outcome.OutcomeItem{
-								Winner:  bet.HomeUser,
-								Loser:   bet.AwayUser,
-								EventId: gameResult.eventId,
-								Week:    week,
-								Amount:  bet.Amount,
-								Id:      uuid.NewString(),
-								Div:     bet.Div,
-							})
-							sendUpdateUserInfo(bet.HomeUser, bet.AwayUser, bet.Amount, bet.Div, userLeagueChannel)
-						} else {
-							sendUpdateUserInfo("", "", 0, bet.Div, userLeagueChannel)
-						}
-
-					}
-
-				}
-				myOutcomeChan <- outcomeSlice
-			}(kind, bets, outcomeChan)
+// handler settles the games in target when a per game schedule fires. The daily rule
+// sends no events, in which case it catches up on anything from the previous game day
+// that is still pending.
+func handler(ctx context.Context, target scheduler.Target, now time.Time, outcomeService outcome.Service, betService bet.Service, provider scores.Provider, leagueService league.Service, seasonService season.Service, leaderboardService leaderboard.Service, historyService history.Service, resolveScheduler scheduler.Scheduler) {
+	if len(target.Events) == 0 {
+		day := clock.PreviousGameDay(now)
+		bets := make([]bet.Bet, 0)
+		for _, date := range clock.UTCDates(day) {
+			bets = append(bets, betService.GetBetsByEventDate(ctx, date)...)
+		}
+		bets = getPendingBets(onGameDay(bets, day), nil, now)
+		settle(ctx, bets, getGamesByDate(ctx, provider, getBetKinds(bets), day), now, outcomeService, betService, leagueService, seasonService, leaderboardService, historyService)
+		return
+	}
+
+	bets := make([]bet.Bet, 0)
+	for _, date := range getEventDates(target.Events) {
+		bets = append(bets, betService.GetBetsByEventDate(ctx, date)...)
+	}
+	bets = getPendingBets(bets, target.Events, now)
+
+	games := getGamesByEvent(ctx, provider, target.Events)
+	settle(ctx, bets, games, now, outcomeService, betService, leagueService, seasonService, leaderboardService, historyService)
+
+	if unfinished := getUnfinished(target.Events, games); len(unfinished) > 0 {
+		if target.Attempt+1 < maxAttempts {
+			err := resolveScheduler.Schedule(ctx, now.Add(retryAfter), scheduler.Target{Events: unfinished, Attempt: target.Attempt + 1})
+			if err != nil {
+				logging.FromContext(ctx).Error("couldn't schedule a retry", "err", err)
+			}
+		} else {
+			logging.FromContext(ctx).Warn("leaving unfinished games for the daily run", "count", len(unfinished))
+		}
+	}
+
+	// schedules delete themselves, only rules made before them are left to clean up
+	if target.RuleName != "" {
+		if err := resolveScheduler.Delete(ctx, target.RuleName); err != nil {
+			logging.FromContext(ctx).Error("couldn't delete the rule", "rule", target.RuleName, "err", err)
 		}
+	}
+}
 
-		var waitGroup sync.WaitGroup
+// settlement is a bet this run claimed and the outcome it settles with.
+type settlement struct {
+	bet     bet.Bet
+	outcome outcome.OutcomeItem
+}
+
+// settle claims the bets on finished games and pays them out. Everything written
+// before the payout is keyed by the bet, so a run that takes over a claim from one
+// that died partway through rewrites it harmlessly. The payout itself only goes
+// through for the run holding the claim.
+func settle(ctx context.Context, bets []bet.Bet, games map[string][]scores.Game, now time.Time, outcomeService outcome.Service, betService bet.Service, leagueService league.Service, seasonService season.Service, leaderboardService leaderboard.Service, historyService history.Service) {
+	spreads := getSpreads(bets)
+	winners := make(map[string]map[string]winner)
+	for _, kind := range getBetKinds(bets) {
+		winners[kind] = getWinners(spreads, games[kind])
+	}
 
-		writeOutcomes(ctx, kinds, outcomeChan, &waitGroup, outcomeService)
+	settlements := make([]settlement, 0, len(bets))
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	for _, b := range bets {
+		gameResult, found := winners[b.Kind][fmt.Sprintf("%s|%s", b.AwayTeam, b.HomeTeam)]
+		if !found {
+			continue
+		}
 
 		waitGroup.Add(1)
-		go updateUserTotals(ctx, leagueService, userLeagueChannel, bets, &waitGroup)
-		waitGroup.Wait()
+		go func(myBet bet.Bet, myResult winner) {
+			defer waitGroup.Done()
+			// a run that overlaps this one may already be settling the bet
+			claimed, ok := betService.Claim(ctx, myBet, now)
+			if !ok {
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			settlements = append(settlements, settlement{bet: claimed, outcome: buildOutcome(claimed, myResult)})
+		}(b, gameResult)
+	}
+	waitGroup.Wait()
+
+	if len(settlements) == 0 {
+		return
+	}
 
+	outcomes := make([]outcome.OutcomeItem, len(settlements))
+	for i, s := range settlements {
+		outcomes[i] = s.outcome
 	}
+	writeOutcomes(ctx, outcomeService, outcomes)
+	historyService.Record(ctx, outcomes)
+	refreshLeaderboards(ctx, leaderboardService, outcomes)
 
+	// one at a time, transactions on the same balance would conflict
+	for _, s := range settlements {
+		payOut(ctx, leagueService, seasonService, s)
+	}
 }
 
-func sendUpdateUserInfo(winner string, loser string, amount int64, div string, userLeagueChannel chan league.UserInLeagueItem) {
-	userLeagueChannel <- league.UserInLeagueItem{
-		Email:  winner,
-		Total:  amount,
-		League: div,
+// payOut moves the stake from the loser to the winner and marks the bet settled in
+// the same transaction. Bets placed in a season that has since started closing are
+// paid into its standings, the balances belong to the next one.
+func payOut(ctx context.Context, leagueService league.Service, seasonService season.Service, s settlement) {
+	changes := map[string]int64{}
+	if !s.outcome.Push {
+		changes[s.outcome.Winner] += s.outcome.Amount
+		changes[s.outcome.Loser] -= s.outcome.Amount
+	}
+
+	writes := []types.TransactWriteItem{bet.SettledWrite(s.bet)}
+	if s.bet.Season != "" {
+		writes = append(writes, season.OpenCheck(s.bet.Div, s.bet.Season))
+	}
+
+	err := leagueService.Pay(ctx, s.bet.Div, changes, writes...)
+	if failed := database.CancelledBy(err); !slices.Contains(failed, 0) && slices.Contains(failed, 1) {
+		err = seasonService.Pay(ctx, s.bet.Div, s.bet.Season, changes, bet.SettledWrite(s.bet))
 	}
-	userLeagueChannel <- league.UserInLeagueItem{
-		Email:  loser,
-		Total:  -1 * amount,
-		League: div,
+
+	if slices.Contains(database.CancelledBy(err), 0) {
+		logging.FromContext(ctx).Warn("another run took over the bet", "outcome", s.outcome.Id)
+	} else if err != nil {
+		logging.FromContext(ctx).Error("couldn't pay out", "outcome", s.outcome.Id, "err", err)
 	}
 }
 
-func updateUserTotals(ctx context.Context, leagueService league.Service, userLeagueChannel chan league.UserInLeagueItem, bets []bet.Bet, waitGroup *sync.WaitGroup) {
-	defer waitGroup.Done()
-	userMap := make(map[string]int64)
-	for i := 0; i < len(bets)*2; i++ {
-		updateUser := <-userLeagueChannel
-		if updateUser.Email == "" {
-			continue
+// onGameDay keeps the bets on games that kicked off on day. Bets are stored by their
+// UTC kickoff date, so a game day's bets are split across two of them.
+func onGameDay(bets []bet.Bet, day time.Time) []bet.Bet {
+	kept := make([]bet.Bet, 0, len(bets))
+	for _, b := range bets {
+		if clock.OnGameDay(b.Date, day) {
+			kept = append(kept, b)
 		}
-		key := fmt.Sprintf("%s|%s", updateUser.League, updateUser.Email)
-		if existingUser, ok := userMap[key]; ok {
-			userMap[key] = existingUser + updateUser.Total
-		} else {
-			userMap[key] = updateUser.Total
+	}
+	return kept
+}
+
+func getGamesByDate(ctx context.Context, provider scores.Provider, kinds []string, date time.Time) map[string][]scores.Game {
+	games := make(map[string][]scores.Game)
+	for _, kind := range kinds {
+		if kindSport, ok := sport.Get(kind); ok {
+			kindGames, err := provider.Games(ctx, kindSport, date, date)
+			if err != nil {
+				// settle nothing for this kind rather than guess at results
+				logging.FromContext(ctx).Warn("skipping sport", "kind", kind, "err", err)
+			}
+			games[kind] = kindGames
+		}
+	}
+	return games
+}
+
+func getGamesByEvent(ctx context.Context, provider scores.Provider, events []scheduler.Event) map[string][]scores.Game {
+	games := make(map[string][]scores.Game)
+	for _, event := range events {
+		if eventSport, ok := sport.Get(event.Kind); ok {
+			game, err := provider.Game(ctx, eventSport, event.Id)
+			if err != nil {
+				logging.FromContext(ctx).Warn("skipping game", "event", event.Id, "err", err)
+				continue
+			}
+			games[event.Kind] = append(games[event.Kind], game)
+		}
+	}
+	return games
+}
+
+func getEventDates(events []scheduler.Event) []string {
+	dates := make([]string, 0, 1)
+	for _, event := range events {
+		date := event.Date.UTC().Format("20060102")
+		if !slices.Contains(dates, date) {
+			dates = append(dates, date)
 		}
 	}
+	return dates
+}
+
+// getPendingBets drops bets that have been settled or are being settled by another
+// run and, when events are given, bets on any other game.
+func getPendingBets(bets []bet.Bet, events []scheduler.Event, now time.Time) []bet.Bet {
+	return util.Filter(bets, func(item bet.Bet) bool {
+		if !item.Unsettled(now) {
+			return false
+		}
+		if events == nil {
+			return true
+		}
+		return slices.ContainsFunc(events, func(event scheduler.Event) bool {
+			return event.Kind == item.Kind && event.AwayTeam == item.AwayTeam && event.HomeTeam == item.HomeTeam
+		})
+	})
+}
 
-	for userLeagueAndEmail, total := range userMap {
-		if total != 0 {
-			waitGroup.Add(1)
-			splits := strings.Split(userLeagueAndEmail, "|")
-			go func(league string, email string, amount int64) {
-				defer waitGroup.Done()
-				leagueService.UpdateUserAmount(ctx, league, email, amount)
-			}(splits[0], splits[1], total)
+func getUnfinished(events []scheduler.Event, games map[string][]scores.Game) []scheduler.Event {
+	unfinished := make([]scheduler.Event, 0)
+	for _, event := range events {
+		final := slices.ContainsFunc(games[event.Kind], func(game scores.Game) bool {
+			return game.Id == event.Id && game.Final()
+		})
+		if !final {
+			unfinished = append(unfinished, event)
 		}
 	}
+	return unfinished
+}
+
+func buildOutcome(settledBet bet.Bet, gameResult winner) outcome.OutcomeItem {
+	week, _ := strconv.Atoi(gameResult.week)
+	if week == 0 {
+		week = settledBet.Week
+	}
+
+	newOutcome := outcome.OutcomeItem{
+		EventId:   gameResult.eventId,
+		Week:      week,
+		Amount:    settledBet.Amount,
+		Id:        outcomeId(settledBet),
+		Div:       settledBet.Div,
+		Date:      gameResult.date,
+		Season:    settledBet.Season,
+		Kind:      settledBet.Kind,
+		AwayTeam:  settledBet.AwayTeam,
+		HomeTeam:  settledBet.HomeTeam,
+		AwayUser:  settledBet.AwayUser,
+		HomeUser:  settledBet.HomeUser,
+		Spread:    settledBet.Spread,
+		AwayScore: gameResult.awayScore,
+		HomeScore: gameResult.homeScore,
+	}
+
+	switch gameResult.team {
+	case settledBet.AwayTeam:
+		newOutcome.Winner = settledBet.AwayUser
+		newOutcome.Loser = settledBet.HomeUser
+	case settledBet.HomeTeam:
+		newOutcome.Winner = settledBet.HomeUser
+		newOutcome.Loser = settledBet.AwayUser
+	default:
+		newOutcome.Winner = settledBet.AwayUser
+		newOutcome.Loser = settledBet.HomeUser
+		newOutcome.Amount = 0
+		newOutcome.Push = true
+	}
+
+	return newOutcome
 }
 
-func writeOutcomes(ctx context.Context, kinds []string, outcomeChan chan []outcome.OutcomeItem, waitGroup *sync.WaitGroup, o outcome.Service) {
-	for range kinds {
-		outcomeSlice := <-outcomeChan
-		for i := 0; i < len(outcomeSlice) && len(outcomeSlice) > 0; i += 25 {
-			waitGroup.Add(1)
-			go func(mySlice []outcome.OutcomeItem) {
-				defer waitGroup.Done()
-				o.Write(ctx, mySlice)
-			}(outcomeSlice[i:util.Min(i+25, len(outcomeSlice))])
+// outcomeId is the same for every run that settles a bet, so writing its outcome
+// again replaces it instead of adding another.
+func outcomeId(settledBet bet.Bet) string {
+	betKey := bet.BuildId(settledBet.Div, strconv.Itoa(settledBet.Week)) + "|" + bet.BuildSortKey(settledBet)
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(betKey)).String()
+}
+
+func refreshLeaderboards(ctx context.Context, leaderboardService leaderboard.Service, outcomes []outcome.OutcomeItem) {
+	type leaderboardKey struct {
+		league string
+		season string
+	}
+
+	// a season's weeks are refreshed together so each roll-up sees all of them
+	weeks := make(map[leaderboardKey][]int)
+	for _, o := range outcomes {
+		key := leaderboardKey{league: o.Div, season: leaderboard.SeasonOf(o)}
+		if !slices.Contains(weeks[key], o.Week) {
+			weeks[key] = append(weeks[key], o.Week)
 		}
 	}
+
+	var waitGroup sync.WaitGroup
+	for key, keyWeeks := range weeks {
+		waitGroup.Add(1)
+		go func(myKey leaderboardKey, myWeeks []int) {
+			defer waitGroup.Done()
+			leaderboardService.Refresh(ctx, myKey.league, myKey.season, myWeeks...)
+		}(key, keyWeeks)
+	}
+	waitGroup.Wait()
+}
+
+func writeOutcomes(ctx context.Context, o outcome.Service, outcomes []outcome.OutcomeItem) {
+	var waitGroup sync.WaitGroup
+	for i := 0; i < len(outcomes); i += 25 {
+		waitGroup.Add(1)
+		go func(mySlice []outcome.OutcomeItem) {
+			defer waitGroup.Done()
+			o.Write(ctx, mySlice)
+		}(outcomes[i:util.Min(i+25, len(outcomes))])
+	}
+	waitGroup.Wait()
 }
 
 func getBetKinds(bets []bet.Bet) []string {
@@ -164,12 +353,10 @@ func getBetKinds(bets []bet.Bet) []string {
 		kindMap[bet.Kind] = true
 	}
 
-	stringSlice := make([]string, 2)
+	stringSlice := make([]string, 0, len(kindMap))
 
-	i := 0
 	for key := range kindMap {
-		stringSlice[i] = key
-		i++
+		stringSlice = append(stringSlice, key)
 	}
 
 	return stringSlice
@@ -185,68 +372,43 @@ func getSpreads(bets []bet.Bet) map[string]string {
 	return resp
 }
 
-func getWinners(spreads map[string]string, espnResp espn.EspnResponse) map[string]winner {
+func getWinners(spreads map[string]string, games []scores.Game) map[string]winner {
 
 	winnersMap := make(map[string]winner)
 
-	for _, sport := range espnResp.Sports {
-		for _, league := range sport.Leagues {
-			for _, event := range league.Events {
-				awayTeam := event.Competitors[0]
-				homeTeam := event.Competitors[1]
-
-				awayScore, _ := strconv.ParseFloat(awayTeam.Score, 64)
-				homeScore, _ := strconv.ParseFloat(homeTeam.Score, 64)
-				gameName := fmt.Sprintf("%s|%s", awayTeam.Name, homeTeam.Name)
-				spread := spreads[gameName]
-
-				spreadRegexp := regexp.MustCompile(`^(?P<Team>\S+)\s+(?P<Points>\S+)$`)
-
-				doesMatch := spreadRegexp.MatchString(spread)
-
-				if doesMatch {
-					paramsMap := make(map[string]string)
-					match := spreadRegexp.FindStringSubmatch(spread)
-					for i, name := range spreadRegexp.SubexpNames() {
-						if i > 0 && i <= len(match) {
-							paramsMap[name] = match[i]
-						}
-					}
-
-					points, _ := strconv.ParseFloat(paramsMap["Points"], 64)
-					if paramsMap["Team"] == awayTeam.Abbreviation {
-						awayScore += points
-					} else {
-						homeScore += points
-					}
-				}
-				if awayScore > homeScore {
-					winnersMap[gameName] = winner{
-						team:    awayTeam.Name,
-						eventId: event.Id,
-						week:    fmt.Sprintf("%d", event.Week),
-					}
-				} else if homeScore > awayScore {
-					winnersMap[gameName] = winner{
-						team:    homeTeam.Name,
-						eventId: event.Id,
-						week:    fmt.Sprintf("%d", event.Week),
-					}
-				} else {
-					winnersMap[gameName] = winner{
-						team:    "",
-						eventId: "",
-						week:    fmt.Sprintf("%d", event.Week),
-					}
-				}
-			}
+	for _, game := range games {
+		if !game.Final() {
+			continue
+		}
+
+		awayScore, _ := strconv.ParseFloat(game.Away.Score, 64)
+		homeScore, _ := strconv.ParseFloat(game.Home.Score, 64)
+		gameName := fmt.Sprintf("%s|%s", game.Away.Name, game.Home.Name)
+
+		result := winner{
+			eventId:   game.Id,
+			week:      fmt.Sprintf("%d", game.Week),
+			date:      game.Date,
+			awayScore: game.Away.Score,
+			homeScore: game.Home.Score,
+		}
+
+		switch sport.Cover(spreads[gameName], game.Away.Abbreviation, awayScore, homeScore) {
+		case sport.Away:
+			result.team = game.Away.Name
+		case sport.Home:
+			result.team = game.Home.Name
 		}
+		winnersMap[gameName] = result
 	}
 	return winnersMap
 }
 
 type winner struct {
-	team    string
-	eventId string
-	week    string
+	team      string
+	eventId   string
+	week      string
+	date      time.Time
+	awayScore string
+	homeScore string
 }
@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
-	"net/http"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"sammy.link/bet"
-	"sammy.link/database"
-	"sammy.link/espn"
+	"sammy.link/clock"
+	"sammy.link/history"
+	"sammy.link/key"
+	"sammy.link/leaderboard"
 	"sammy.link/league"
+	"sammy.link/main/app"
 	"sammy.link/outcome"
+	"sammy.link/scheduler"
+	"sammy.link/scores"
+	"sammy.link/season"
+	"sammy.link/sport"
 )
 
 func TestGetBetKinds(t *testing.T) {
@@ -68,8 +78,280 @@ type MockOutcomeService struct {
 
 func TestHandler(t *testing.T) {
 	ctx := context.TODO()
-	handler(ctx, outcome.NewService(database.GetDatabaseService[outcome.OutcomeDynamoItem, outcome.OutcomeItem](ctx)),
-		bet.NewService(database.GetDatabaseService[bet.BetDynamoItem, bet.Bet](ctx)), espn.NewService(http.Client{}),
-		league.NewService(database.GetDatabaseService[league.LeagueDynamoItem, league.LeagueItem](ctx),
-			database.GetDatabaseService[league.UserInLeagueDynamoItem, league.UserInLeagueItem](ctx)))
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	handler(ctx, scheduler.Target{}, a.Now(), a.Outcome,
+		a.Bet, a.Scores,
+		a.League,
+		a.Season,
+		a.Leaderboard,
+		a.History,
+		&scheduler.FakeScheduler{})
+}
+
+func TestBuildOutcome(t *testing.T) {
+	settledBet := bet.Bet{
+		AwayTeam: "Utah",
+		HomeTeam: "Florida",
+		AwayUser: "sam@sam.com",
+		HomeUser: "greg@greg.com",
+		Spread:   "UTAH -3.0",
+		Amount:   10,
+		Kind:     "CFB",
+	}
+
+	win := buildOutcome(settledBet, winner{team: "Utah", week: "1", awayScore: "24", homeScore: "11"})
+	if win.Winner != "sam@sam.com" || win.Loser != "greg@greg.com" || win.Amount != 10 || win.Week != 1 || win.AwayScore != "24" {
+		t.Fatalf("sam should win 10 but got %+v", win)
+	}
+
+	push := buildOutcome(settledBet, winner{team: "", week: "1"})
+	if !push.Push || push.Amount != 0 || push.HomeUser != "greg@greg.com" {
+		t.Fatalf("should be a push but got %+v", push)
+	}
+}
+
+func TestGetWinners(t *testing.T) {
+	spreads := map[string]string{
+		"New York Yankees|Boston Red Sox":  "NYY -1.5",
+		"Detroit Lions|Kansas City Chiefs": "KC -6.5",
+	}
+
+	winners := getWinners(spreads, []scores.Game{
+		{
+			Id:     "1",
+			Status: scores.Final,
+			Away:   scores.Team{Name: "New York Yankees", Abbreviation: "NYY", Score: "4"},
+			Home:   scores.Team{Name: "Boston Red Sox", Abbreviation: "BOS", Score: "3"},
+		},
+	})
+
+	if result := winners["New York Yankees|Boston Red Sox"]; result.team != "Boston Red Sox" {
+		t.Fatalf("winning by one shouldn't cover the run line but got %+v", result)
+	}
+
+	nfl, _ := sport.Get("NFL")
+	games, _ := scores.NewFixtureProvider(scores.Fixtures()).Games(context.TODO(), nfl, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, time.September, 10, 0, 0, 0, 0, time.UTC))
+	winners = getWinners(spreads, games)
+
+	if result := winners["Detroit Lions|Kansas City Chiefs"]; result.team != "Detroit Lions" || result.eventId != "401547353" || result.week != "1" {
+		t.Fatalf("the lions should cover but got %+v", result)
+	}
+
+	if _, ok := winners["Philadelphia Eagles|New England Patriots"]; ok {
+		t.Fatalf("games that haven't finished shouldn't be settled")
+	}
+}
+
+func TestGetPendingBets(t *testing.T) {
+	now := time.Date(2023, time.September, 8, 4, 0, 0, 0, time.UTC)
+	bets := []bet.Bet{
+		{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", Status: bet.Pending},
+		{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", Status: bet.Settled},
+		{Kind: "NFL", AwayTeam: "Philadelphia Eagles", HomeTeam: "New England Patriots", Status: bet.Pending},
+		{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", Status: bet.Settling, ClaimedAt: now.Add(-time.Minute)},
+		{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", Status: bet.Settling, ClaimedAt: now.Add(-bet.ClaimTimeout)},
+	}
+
+	if pending := getPendingBets(bets, nil, now); len(pending) != 3 || pending[2].Status != bet.Settling {
+		t.Fatalf("settled bets and ones another run is settling shouldn't be paid out twice but got %+v", pending)
+	}
+
+	events := []scheduler.Event{{Id: "401547353", Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs"}}
+	if pending := getPendingBets(bets, events, now); len(pending) != 2 || pending[0].AwayTeam != "Detroit Lions" {
+		t.Fatalf("should only settle bets on the targeted games but got %+v", pending)
+	}
+}
+
+func TestOnGameDay(t *testing.T) {
+	// the daily run just after midnight Eastern on Monday settles Sunday
+	day := clock.PreviousGameDay(time.Date(2023, time.October, 2, 4, 0, 0, 0, time.UTC))
+	bets := []bet.Bet{
+		{AwayTeam: "Saturday late game", Date: time.Date(2023, time.October, 1, 2, 0, 0, 0, time.UTC)},
+		{AwayTeam: "Sunday early game", Date: time.Date(2023, time.October, 1, 17, 0, 0, 0, time.UTC)},
+		{AwayTeam: "Sunday night game", Date: time.Date(2023, time.October, 2, 0, 20, 0, 0, time.UTC)},
+		{AwayTeam: "Monday night game", Date: time.Date(2023, time.October, 3, 0, 15, 0, 0, time.UTC)},
+	}
+
+	kept := onGameDay(bets, day)
+	if len(kept) != 2 || kept[0].AwayTeam != "Sunday early game" || kept[1].AwayTeam != "Sunday night game" {
+		t.Fatalf("should only settle Sunday's games but got %+v", kept)
+	}
+	if dates := clock.UTCDates(day); len(dates) != 2 || dates[0] != "20231001" || dates[1] != "20231002" {
+		t.Fatalf("should look up bets on both UTC days but got %s", dates)
+	}
+}
+
+func TestGetUnfinished(t *testing.T) {
+	events := []scheduler.Event{
+		{Id: "401547353", Kind: "NFL", Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)},
+		{Id: "401547397", Kind: "NFL", Date: time.Date(2023, time.September, 10, 17, 0, 0, 0, time.UTC)},
+	}
+
+	if dates := getEventDates(events); len(dates) != 2 || dates[0] != "20230908" {
+		t.Fatalf("should look up bets on both days but got %s", dates)
+	}
+
+	games := getGamesByEvent(context.TODO(), scores.NewFixtureProvider(scores.Fixtures()), events)
+	unfinished := getUnfinished(events, games)
+
+	if len(unfinished) != 1 || unfinished[0].Id != "401547397" {
+		t.Fatalf("only the game that hasn't been played should be rescheduled but got %+v", unfinished)
+	}
+}
+
+// claims keeps bets by sort key and claims them the way the table's condition does.
+type claims struct {
+	bet.Service
+	bets map[string]bet.Bet
+}
+
+func (c *claims) Claim(ctx context.Context, item bet.Bet, now time.Time) (bet.Bet, bool) {
+	stored := c.bets[bet.BuildSortKey(item)]
+	if !stored.Unsettled(now) {
+		return item, false
+	}
+	stored.Status = bet.Settling
+	stored.ClaimedAt = now
+	c.bets[bet.BuildSortKey(item)] = stored
+	return stored, true
+}
+
+func (c *claims) list() []bet.Bet {
+	bets := make([]bet.Bet, 0, len(c.bets))
+	for _, b := range c.bets {
+		bets = append(bets, b)
+	}
+	return bets
+}
+
+// ledger pays out against claims, failing every payout while down and every one
+// checking that a season in closing is open.
+type ledger struct {
+	league.Service
+	claims   *claims
+	balances map[string]int64
+	down     bool
+	closing  map[string]bool
+}
+
+func (l *ledger) Pay(ctx context.Context, div string, changes map[string]int64, writes ...types.TransactWriteItem) error {
+	if l.down {
+		return errors.New("timed out")
+	}
+
+	settled := writes[0].Update
+	sortKey := settled.Key["sortKey"].(*types.AttributeValueMemberS).Value
+	claimed := settled.ExpressionAttributeValues[":claimed"].(*types.AttributeValueMemberS).Value
+	stored := l.claims.bets[sortKey]
+	if stored.Status != bet.Settling || key.Time(stored.ClaimedAt) != claimed {
+		return &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}}}
+	}
+	if len(writes) > 1 && l.closing[writes[1].ConditionCheck.Key["sortKey"].(*types.AttributeValueMemberS).Value] {
+		return &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("ConditionalCheckFailed")}}}
+	}
+
+	stored.Status = bet.Settled
+	l.claims.bets[sortKey] = stored
+	for email, amount := range changes {
+		l.balances[email] += amount
+	}
+	return nil
+}
+
+// standings pays into the final standings of a closing season.
+type standings struct {
+	season.Service
+	ledger *ledger
+	totals map[string]int64
+}
+
+func (s *standings) Pay(ctx context.Context, div string, seasonId string, changes map[string]int64, writes ...types.TransactWriteItem) error {
+	sortKey := writes[0].Update.Key["sortKey"].(*types.AttributeValueMemberS).Value
+	stored := s.ledger.claims.bets[sortKey]
+	stored.Status = bet.Settled
+	s.ledger.claims.bets[sortKey] = stored
+	for email, amount := range changes {
+		s.totals[seasonId+"|"+email] += amount
+	}
+	return nil
+}
+
+type outcomes struct {
+	outcome.Service
+	written map[string]outcome.OutcomeItem
+}
+
+func (o *outcomes) Write(ctx context.Context, items []outcome.OutcomeItem) {
+	for _, item := range items {
+		o.written[item.Id] = item
+	}
+}
+
+type leaderboards struct{ leaderboard.Service }
+
+func (leaderboards) Refresh(ctx context.Context, league string, season string, weeks ...int) {}
+
+type histories struct{ history.Service }
+
+func (histories) Record(ctx context.Context, outcomes []outcome.OutcomeItem) {}
+
+func TestSettleAfterAFailedRun(t *testing.T) {
+	ctx := context.TODO()
+	lions := bet.Bet{Div: "default", Kind: "NFL", Week: 1, AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Spread: "KC -6.5", Amount: 10, Status: bet.Pending, Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)}
+	betService := &claims{bets: map[string]bet.Bet{bet.BuildSortKey(lions): lions}}
+	leagueService := &ledger{claims: betService, balances: map[string]int64{}, down: true}
+	outcomeService := &outcomes{written: map[string]outcome.OutcomeItem{}}
+
+	nfl, _ := sport.Get("NFL")
+	games, _ := scores.NewFixtureProvider(scores.Fixtures()).Games(ctx, nfl, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, time.September, 10, 0, 0, 0, 0, time.UTC))
+	run := func(now time.Time) {
+		settle(ctx, getPendingBets(betService.list(), nil, now), map[string][]scores.Game{"NFL": games}, now, outcomeService, betService, leagueService, &standings{}, leaderboards{}, histories{})
+	}
+
+	now := time.Date(2023, time.September, 8, 4, 20, 0, 0, time.UTC)
+	run(now)
+	if status := betService.bets[bet.BuildSortKey(lions)].Status; status != bet.Settling || len(leagueService.balances) != 0 {
+		t.Fatalf("a failed payout should leave the bet claimed and unpaid but got %s %v", status, leagueService.balances)
+	}
+
+	leagueService.down = false
+	run(now.Add(time.Minute))
+	if len(leagueService.balances) != 0 {
+		t.Fatalf("a fresh claim shouldn't be taken over but got %v", leagueService.balances)
+	}
+
+	run(now.Add(bet.ClaimTimeout))
+	run(now.Add(2 * bet.ClaimTimeout))
+	if status := betService.bets[bet.BuildSortKey(lions)].Status; status != bet.Settled {
+		t.Fatalf("the next run should settle the bet but it's %s", status)
+	}
+	if leagueService.balances["sam@sam.com"] != 10 || leagueService.balances["greg@greg.com"] != -10 {
+		t.Fatalf("the bet should be paid out once but got %v", leagueService.balances)
+	}
+	if len(outcomeService.written) != 1 {
+		t.Fatalf("both runs should write the same outcome but got %v", outcomeService.written)
+	}
+}
+
+func TestSettleAfterTheSeasonCloses(t *testing.T) {
+	ctx := context.TODO()
+	lions := bet.Bet{Div: "default", Season: "2023", Kind: "NFL", Week: 1, AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", AwayUser: "sam@sam.com", HomeUser: "greg@greg.com", Spread: "KC -6.5", Amount: 10, Status: bet.Pending, Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)}
+	betService := &claims{bets: map[string]bet.Bet{bet.BuildSortKey(lions): lions}}
+	leagueService := &ledger{claims: betService, balances: map[string]int64{}, closing: map[string]bool{"2023": true}}
+	seasonService := &standings{ledger: leagueService, totals: map[string]int64{}}
+
+	nfl, _ := sport.Get("NFL")
+	games, _ := scores.NewFixtureProvider(scores.Fixtures()).Games(ctx, nfl, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, time.September, 10, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2023, time.September, 8, 4, 20, 0, 0, time.UTC)
+	settle(ctx, []bet.Bet{lions}, map[string][]scores.Game{"NFL": games}, now, &outcomes{written: map[string]outcome.OutcomeItem{}}, betService, leagueService, seasonService, leaderboards{}, histories{})
+
+	if len(leagueService.balances) != 0 {
+		t.Fatalf("the next season's balances shouldn't change but got %v", leagueService.balances)
+	}
+	if seasonService.totals["2023|sam@sam.com"] != 10 || seasonService.totals["2023|greg@greg.com"] != -10 {
+		t.Fatalf("the bet should count towards the 2023 standings but got %v", seasonService.totals)
+	}
+	if status := betService.bets[bet.BuildSortKey(lions)].Status; status != bet.Settled {
+		t.Fatalf("the bet should be settled but it's %s", status)
+	}
 }
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/bet"
+	"sammy.link/bid"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/season"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+type Response struct {
+	util.DefaultResponse
+}
+
+func handleCreate(ctx context.Context, request events.APIGatewayV2HTTPRequest, now time.Time, bidService bid.Service, marketplaceService marketplace.Service, authService auth.Service, seasonService season.Service, leagueService league.Service) (events.APIGatewayV2HTTPResponse, error) {
+	resp := Response{}
+
+	var orders = []bid.Order{}
+	betMap := make(map[string]bet.Bet)
+	v := validation.New()
+	if !v.Decode(request.Body, &orders) || !checkOrders(v, orders) {
+		return validation.BadRequest(v.Err())
+	}
+
+	divs := getDivs(orders)
+	ctx = logging.With(ctx, logging.LeagueKey, strings.Join(divs, ","))
+	user, ok := authService.Authorize(ctx, request, divs...)
+	if !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	body, ok := buildBids(ctx, v, marketplaceService, orders, now)
+	if !ok {
+		return validation.BadRequest(v.Err())
+	}
+
+	if kind, div, ok := getDisallowedKind(ctx, leagueService, body, divs); !ok {
+		resp.Message = fmt.Sprintf("%s is not enabled in %s", kind, div)
+		jsonResp, _ := json.Marshal(resp)
+		return util.ApigatewayResponse(string(jsonResp), 400)
+	}
+
+	seasons := getSeasons(ctx, seasonService, divs, now)
+	for i := range body {
+		body[i].Season = seasons[body[i].Div]
+	}
+
+	//replace to leagues name later
+	keyName := "@TODO"
+	bidService.Lock(ctx, keyName)
+
+	var waitGroup sync.WaitGroup
+
+	newBidChannel := make(chan bid.Bid, 4)
+	betChannel := make(chan []bet.Bet, 4)
+	deleteBidChannel := make(chan []bid.Bid, 4)
+
+	writeBids(ctx, body, user, &waitGroup, marketplaceService, bidService, betChannel, deleteBidChannel, newBidChannel, betMap, now)
+
+	waitGroup.Add(1)
+	handleBetsAndBidDeletes(ctx, body, betChannel, deleteBidChannel, newBidChannel, &waitGroup, bidService)
+	waitGroup.Wait()
+
+	jsonResp, _ := json.Marshal(betMap)
+
+	bidService.ReleaseLock(ctx, keyName)
+
+	return util.ApigatewayResponse(string(jsonResp), 200)
+}
+
+// maxBids caps how many bids one request can place, each of which reads its listing.
+const maxBids = 25
+
+func checkOrders(v *validation.Validator, orders []bid.Order) bool {
+	if !v.Check(len(orders) > 0 && len(orders) <= maxBids, "body", fmt.Sprintf("must have between 1 and %d bids", maxBids)) {
+		return false
+	}
+	for i, order := range orders {
+		bid.CheckOrder(v.At(fmt.Sprintf("[%d]", i)), order)
+	}
+	return v.Valid()
+}
+
+// buildBids fills in each order from the listing it's on, so nothing about the game
+// comes from the client.
+func buildBids(ctx context.Context, v *validation.Validator, marketplaceService marketplace.Service, orders []bid.Order, now time.Time) ([]bid.Bid, bool) {
+	listings := make(map[string]marketplace.MarketplaceItem)
+	bids := make([]bid.Bid, 0, len(orders))
+
+	for i, order := range orders {
+		listing, found := listings[order.EventId]
+		if !found {
+			if listing, found = marketplaceService.GetByEventId(ctx, order.EventId); found {
+				listings[order.EventId] = listing
+			}
+		}
+
+		if marketplace.CheckListing(v.At(fmt.Sprintf("[%d]", i)), listing, found, now) {
+			bids = append(bids, marketplace.NewBid(listing, order))
+		}
+	}
+	return bids, v.Valid()
+}
+
+func getDivs(orders []bid.Order) []string {
+	divs := make([]string, 0, len(orders))
+	for _, item := range orders {
+		if !slices.Contains(divs, item.Div) {
+			divs = append(divs, item.Div)
+		}
+	}
+	return divs
+}
+
+func getDisallowedKind(ctx context.Context, leagueService league.Service, bids []bid.Bid, divs []string) (string, string, bool) {
+	leagues := make(map[string]league.LeagueItem)
+	for _, div := range divs {
+		leagues[div], _ = leagueService.GetLeague(ctx, div)
+	}
+
+	for _, item := range bids {
+		if !leagues[item.Div].Allows(item.Kind) {
+			return item.Kind, item.Div, false
+		}
+	}
+	return "", "", true
+}
+
+func getSeasons(ctx context.Context, seasonService season.Service, divs []string, now time.Time) map[string]string {
+	seasons := make(map[string]string)
+	for _, div := range divs {
+		if current, ok := seasonService.GetCurrent(ctx, div, now); ok {
+			seasons[div] = current.Id
+		} else {
+			seasons[div] = season.IdOf(now)
+		}
+	}
+	return seasons
+}
+
+func handleBetsAndBidDeletes(ctx context.Context, bids []bid.Bid, betChannel chan []bet.Bet, deleteBidChannel chan []bid.Bid, newBidChannel chan bid.Bid, waitGroup *sync.WaitGroup, bidService bid.Service) {
+
+	bidsAndBets := make([]bid.BidAndBet, 0)
+	for _, item := range bids {
+		if item.Amount > 0 && item.Amount <= 100 {
+			// if time.Now().Before(item.Date) && item.Amount > 0 && item.Amount <= 100 {
+			incomingBidSlice := <-deleteBidChannel
+			incomingBet := <-betChannel
+			newBid := <-newBidChannel
+			if len(incomingBidSlice) > 0 {
+				bidsAndBets = append(bidsAndBets, bid.BidAndBet{
+					MyBids:   incomingBidSlice,
+					IsBid:    true,
+					IsDelete: true,
+				})
+			}
+
+			if len(incomingBet) > 0 {
+				bidsAndBets = append(bidsAndBets, bid.BidAndBet{
+					MyBets: incomingBet,
+					IsBid:  false,
+				})
+			}
+
+			if newBid.Amount > 0 {
+				bidsAndBets = append(bidsAndBets, bid.BidAndBet{
+					MyBids:   []bid.Bid{newBid},
+					IsBid:    true,
+					IsDelete: false,
+				})
+			}
+		}
+	}
+
+	go handleBidsAndBets(ctx, bidsAndBets, waitGroup, bidService)
+
+}
+
+func handleBidsAndBets(ctx context.Context, bidsAndBets []bid.BidAndBet, waitGroup *sync.WaitGroup, service bid.Service) {
+
+	go service.WriteBidsAndBets(ctx, bidsAndBets, waitGroup)
+}
+
+func writeBids(ctx context.Context, bids []bid.Bid, user string, waitGroup *sync.WaitGroup, marketplaceService marketplace.Service, bidService bid.Service, betChannel chan []bet.Bet, deleteBidChannel chan []bid.Bid, newBidChannel chan bid.Bid, betMap map[string]bet.Bet, now time.Time) {
+
+	for _, item := range bids {
+
+		if item.Amount > 0 && item.Amount <= 100 {
+			// if time.Now().Before(item.Date) && item.Amount > 0 && item.Amount <= 100 {
+			item.User = user
+			waitGroup.Add(1)
+			go func(modifyItem bid.Bid) {
+				defer waitGroup.Done()
+				marketplaceService.ModifyAmount(ctx, modifyItem)
+			}(item)
+			item.CreateDate = now
+
+			go func(newBid bid.Bid) {
+				//query all bids for that event
+				bids := bidService.GetBidsByEvent(ctx, bid.GetEventKey(newBid.Kind, newBid.Date, newBid.AwayTeam, newBid.HomeTeam), newBid.Div)
+				slices.SortFunc[[]bid.Bid](bids, func(bidOne bid.Bid, bidTwo bid.Bid) int {
+					if bidOne.CreateDate.Before(bidTwo.CreateDate) {
+						return -1
+					} else if bidTwo.CreateDate.Before(bidOne.CreateDate) {
+						return 1
+					}
+					return 0
+				})
+				bidsThatNeedDeleting := make([]bid.Bid, 0)
+
+				newBets := make([]bet.Bet, 0)
+
+				for _, existingBid := range bids {
+					if existingBid.ChosenCompetitor != newBid.ChosenCompetitor && user != existingBid.User {
+						lessAmount := util.Min(newBid.Amount, existingBid.Amount)
+
+						var awayUser, homeUser string
+
+						if newBid.ChosenCompetitor == newBid.AwayTeam {
+							awayUser = user
+							homeUser = existingBid.User
+						} else {
+							awayUser = existingBid.User
+							homeUser = user
+						}
+						newBet := bet.Bet{
+							AwayUser:         awayUser,
+							HomeUser:         homeUser,
+							Amount:           lessAmount,
+							AwayTeam:         existingBid.AwayTeam,
+							HomeTeam:         existingBid.HomeTeam,
+							Status:           bet.Pending,
+							Spread:           existingBid.Spread,
+							Kind:             existingBid.Kind,
+							Date:             existingBid.Date,
+							Week:             newBid.Week,
+							HomeAbbreviation: newBid.HomeAbbreviation,
+							AwayAbbreviation: newBid.AwayAbbreviation,
+							Div:              newBid.Div,
+							Season:           newBid.Season,
+						}
+						betKey := fmt.Sprintf("%s|%s|%s|%s", awayUser, homeUser, existingBid.AwayTeam, existingBid.HomeTeam)
+
+						if v, ok := betMap[betKey]; ok {
+							v.Amount += newBet.Amount
+							betMap[betKey] = v
+
+							for i, oldBet := range newBets {
+								if oldBet.AwayUser == newBet.AwayUser && oldBet.HomeUser == newBet.HomeUser && oldBet.AwayTeam == newBet.HomeTeam {
+									oldBet.Amount += newBet.Amount
+									newBets[i] = oldBet
+								}
+							}
+						} else {
+							betMap[betKey] = newBet
+							newBets = append(newBets, newBet)
+						}
+
+						existingBid.Amount -= lessAmount
+						newBid.Amount -= lessAmount
+
+						if existingBid.Amount > lessAmount {
+							existingBid.Amount -= lessAmount
+							waitGroup.Add(1)
+							go func(bidToUpdate bid.Bid, matched int64) {
+								defer waitGroup.Done()
+								bidService.Update(ctx, bidToUpdate, matched)
+							}(existingBid, lessAmount)
+						} else {
+							// deleting only needs the key, the amount is what's published as matched
+							existingBid.Amount = lessAmount
+							bidsThatNeedDeleting = append(bidsThatNeedDeleting, existingBid)
+						}
+
+						if newBid.Amount == 0 {
+							break
+						}
+					}
+				}
+				newBidChannel <- newBid
+				betChannel <- newBets
+				deleteBidChannel <- bidsThatNeedDeleting
+			}(item)
+		}
+	}
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleCreate(ctx, request, a.Now(), a.Bid, a.Marketplace, a.Auth, a.Season, a.League)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/bid"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/validation"
+)
+
+//NFL|2023-09-15T00:15:00Z|Vikings|Eagles
+
+func TestCreate(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleCreate(ctx, events.APIGatewayV2HTTPRequest{Body: `[
+		{
+			"div": "default",
+			"eventId": "401520281",
+			"side": "HOME",
+			"amount": 12
+		}
+	]`,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					// Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+					Claims: map[string]string{"https://sammy.link/email": "sam@sam.com"},
+				},
+			},
+		},
+	},
+		a.Now(),
+		a.Bid,
+		a.Marketplace,
+		a.Auth,
+		a.Season,
+		a.League,
+	)
+	fmt.Printf("dat resp %s", resp.Body)
+}
+
+type listings struct {
+	marketplace.Service
+	items []marketplace.MarketplaceItem
+	reads int
+}
+
+func (l *listings) GetByEventId(ctx context.Context, eventId string) (marketplace.MarketplaceItem, bool) {
+	l.reads++
+	for _, item := range l.items {
+		if item.Id == eventId {
+			return item, true
+		}
+	}
+	return marketplace.MarketplaceItem{}, false
+}
+
+func TestBuildBids(t *testing.T) {
+	kickoff := time.Date(2023, time.September, 30, 1, 0, 0, 0, time.UTC)
+	listed := &listings{items: []marketplace.MarketplaceItem{
+		{Id: "401520281", Kind: "CFB", AwayTeam: "Utah", HomeTeam: "Oregon St", AwayAbbreviation: "UTAH", HomeAbbreviation: "ORST", Spread: "ORST -3.0", Date: kickoff, Week: 5},
+	}}
+	orders := []bid.Order{
+		{Div: "default", EventId: "401520281", Side: "HOME", Amount: 12},
+		{Div: "other", EventId: "401520281", Side: "AWAY", Amount: 5},
+	}
+
+	v := validation.New()
+	if !checkOrders(v, orders) {
+		t.Fatalf("orders should be valid but got %v", v.Err())
+	}
+	bids, ok := buildBids(context.TODO(), v, listed, orders, kickoff.Add(-time.Hour))
+	if !ok || len(bids) != 2 {
+		t.Fatalf("should build a bid per order but got %+v %v", bids, v.Err())
+	}
+	if bids[0].ChosenCompetitor != "Oregon St" || bids[0].Spread != "ORST -3.0" || bids[0].Week != 5 || !bids[0].Date.Equal(kickoff) || bids[0].Div != "default" || bids[0].Amount != 12 {
+		t.Fatalf("should fill the bid in from the listing but got %+v", bids[0])
+	}
+	if bids[1].ChosenCompetitor != "Utah" || listed.reads != 1 {
+		t.Fatalf("should read the listing once for both orders but got %+v after %d reads", bids[1], listed.reads)
+	}
+
+	if checkOrders(validation.New(), []bid.Order{}) {
+		t.Fatalf("an empty request should be rejected")
+	}
+
+	v = validation.New()
+	buildBids(context.TODO(), v, listed, []bid.Order{orders[0], {Div: "default", EventId: "1", Side: "HOME", Amount: 1}}, kickoff.Add(-time.Hour))
+
+	var errs validation.Errors
+	if !errors.As(v.Err(), &errs) || len(errs) != 1 || errs[0].Field != "[1].eventId" {
+		t.Fatalf("should only reject the unlisted game but got %v", v.Err())
+	}
+
+	if _, ok := buildBids(context.TODO(), validation.New(), listed, orders, kickoff); ok {
+		t.Fatalf("bids should close at kickoff")
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/bid"
+	"sammy.link/main/app"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, bidService bid.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	div := request.QueryStringParameters["div"]
+	v := validation.New()
+	v.String("div", div).Required()
+	v.String("event", request.PathParameters["event"]).Required()
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	if _, ok := authService.Authorize(ctx, request, div); !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	bids := bidService.GetBidsByEvent(ctx, request.PathParameters["event"], div)
+
+	jsonBids, _ := json.Marshal(bids)
+
+	return util.ApigatewayResponse(string(jsonBids), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Bid, a.Auth)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/bid"
+	"sammy.link/main/app"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, bidService bid.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	user := request.RequestContext.Authorizer.JWT.Claims["https://sammy.link/email"]
+
+	v := validation.New()
+	limit, cursor, paged := v.Page(request.QueryStringParameters)
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	if paged {
+		bids, next, err := bidService.GetBidsByUserPage(ctx, user, limit, cursor)
+		if err != nil {
+			resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+			return util.ApigatewayResponse(string(resp), 400)
+		}
+
+		jsonBids, _ := json.Marshal(util.Page[bid.Bid]{Items: bids, Cursor: next})
+		return util.ApigatewayResponse(string(jsonBids), 200)
+	}
+
+	bids := bidService.GetBidsByUser(ctx, user)
+
+	jsonBids, _ := json.Marshal(bids)
+
+	return util.ApigatewayResponse(string(jsonBids), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Bid)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package handler
 
 import (
 	"context"
@@ -6,12 +6,12 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	"sammy.link/bid"
-	"sammy.link/database"
+	"sammy.link/main/app"
 )
 
 func TestHandler(t *testing.T) {
 	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
 	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
 		RequestContext: events.APIGatewayV2HTTPRequestContext{
 			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
@@ -20,7 +20,7 @@ func TestHandler(t *testing.T) {
 				},
 			},
 		},
-	}, bid.NewService(database.GetDatabaseService[bid.DyanmoBidItem, bid.Bid](ctx)))
+	}, a.Bid)
 
 	fmt.Println(resp.Body)
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/bid"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func update(ctx context.Context, request events.APIGatewayV2HTTPRequest, now time.Time, bidService bid.Service, marketplaceService marketplace.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	var order = bid.Order{}
+	v := validation.New()
+	if !v.Decode(request.Body, &order) || !bid.CheckOrder(v, order) {
+		return validation.BadRequest(v.Err())
+	}
+
+	ctx = logging.With(ctx, logging.LeagueKey, order.Div)
+	user, ok := authService.Authorize(ctx, request, order.Div)
+	if !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	listing, found := marketplaceService.GetByEventId(ctx, order.EventId)
+	if !marketplace.CheckListing(v, listing, found, now) {
+		return validation.BadRequest(v.Err())
+	}
+	input := marketplace.NewBid(listing, order)
+	input.User = user
+	input.CreateDate = now
+
+	bidService.Lock(ctx, input.Div)
+
+	// bidService.Delete(ctx, input)
+	input.Amount = -1 * input.Amount
+	marketplaceService.ModifyAmount(ctx, input)
+
+	bidService.ReleaseLock(ctx, input.Div)
+
+	jsonUser, _ := json.Marshal(map[string]string{
+		"message": "success",
+	})
+
+	return util.ApigatewayResponse(string(jsonUser), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return update(ctx, request, a.Now(), a.Bid, a.Marketplace, a.Auth)
+	}
+}
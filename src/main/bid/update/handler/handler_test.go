@@ -1,4 +1,4 @@
-package main
+package handler
 
 /*
 {
@@ -27,33 +27,29 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	"sammy.link/bid"
-	"sammy.link/database"
-	"sammy.link/marketplace"
+	"sammy.link/main/app"
 )
 
 func TestUpdate(t *testing.T) {
 	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
 	resp, _ := update(ctx, events.APIGatewayV2HTTPRequest{
 		Body: `{
 			"div": "default",
-			"awayUser": "",
-			"homeUser": "pgreene864@gmail.com",
-			"amount": 0,
-			"awayTeam": "Utah",
-			"homeTeam": "Oregon St",
-			"status": "BAD",
-			"spread": "ORST -3.0",
-			"kind": "CFB",
-			"week": 5,
-			"createDate": "2023-09-29T15:38:39Z",
-			"date": "2023-09-30T01:00:00Z",
-			"homeAbbreviation": "ORST",
-			"awayAbbreviation": "UTAH",
-			"chosenCompetitor": "Oregon St",
-			"user": "pgreene864@gmail.com"
+			"eventId": "401520281",
+			"side": "HOME",
+			"amount": 5
 		}`,
-	}, bid.NewService(database.GetDatabaseService[bid.DyanmoBidItem, bid.Bid](ctx)),
-		marketplace.NewService(database.GetDatabaseService[marketplace.MarketplaceDynamoDbItem, marketplace.MarketplaceItem](ctx)))
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.Now(),
+		a.Bid,
+		a.Marketplace,
+		a.Auth)
 	fmt.Printf("your boy %s", resp.Body)
 }
@@ -7,6 +7,7 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.21.0
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.21.5
 	github.com/aws/aws-sdk-go-v2/service/eventbridge v1.20.5
+	github.com/aws/aws-sdk-go-v2/service/scheduler v1.2.5
 	sammy.link/bid v0.0.0-00010101000000-000000000000
 	sammy.link/marketplace v0.0.0-00010101000000-000000000000
 )
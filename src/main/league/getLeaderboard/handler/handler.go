@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/leaderboard"
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/season"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, now time.Time, leaderboardService leaderboard.Service, leagueService league.Service, authService auth.Service, seasonService season.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	l := request.PathParameters["league"]
+
+	if _, ok := authService.Authorize(ctx, request, l); !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	seasonId := request.QueryStringParameters["season"]
+	if seasonId == "" {
+		if current, ok := seasonService.GetCurrent(ctx, l, now); ok {
+			seasonId = current.Id
+		} else {
+			seasonId = season.IdOf(now)
+		}
+	}
+
+	var items []leaderboard.Item
+	if weekParam, ok := request.QueryStringParameters["week"]; ok {
+		v := validation.New()
+		week := v.Number("week", weekParam).Min(1)
+		if !v.Valid() {
+			return validation.BadRequest(v.Err())
+		}
+		items = leaderboardService.GetWeek(ctx, l, seasonId, int(week.Value()))
+	} else {
+		items = leaderboardService.GetSeason(ctx, l, seasonId)
+	}
+
+	names := make(map[string]string)
+	for _, u := range leagueService.GetUsers(ctx, l) {
+		names[u.Email] = u.Name
+	}
+
+	for i := range items {
+		items[i].Name = names[items[i].Email]
+	}
+
+	resp, _ := json.Marshal(items)
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Now(), a.Leaderboard, a.League, a.Auth, a.Season)
+	}
+}
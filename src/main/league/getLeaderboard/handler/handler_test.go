@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/main/app"
+)
+
+func TestGet(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
+		PathParameters: map[string]string{
+			"league": "default",
+		},
+		QueryStringParameters: map[string]string{
+			"season": "2023",
+			"week":   "5",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.Now(), a.Leaderboard,
+		a.League,
+		a.Auth,
+		a.Season)
+	fmt.Printf("your boy %s", resp.Body)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/main/app"
+	"sammy.link/season"
+	"sammy.link/util"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, seasonService season.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	l := request.PathParameters["league"]
+
+	if _, ok := authService.Authorize(ctx, request, l); !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	var resp []byte
+	if seasonId, ok := request.QueryStringParameters["season"]; ok {
+		resp, _ = json.Marshal(seasonService.GetStandings(ctx, l, seasonId))
+	} else {
+		resp, _ = json.Marshal(seasonService.GetSeasons(ctx, l))
+	}
+
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Season, a.Auth)
+	}
+}
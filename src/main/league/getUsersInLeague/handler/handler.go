@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, service league.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	l := request.PathParameters["league"]
+
+	if _, ok := authService.Authorize(ctx, request, l); !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	v := validation.New()
+	limit, cursor, paged := v.Page(request.QueryStringParameters)
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	if paged {
+		users, next, err := service.GetUsersPage(ctx, l, limit, cursor)
+		if err != nil {
+			resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+			return util.ApigatewayResponse(string(resp), 400)
+		}
+
+		resp, _ := json.Marshal(util.Page[league.UserInLeagueItem]{Items: users, Cursor: next})
+		return util.ApigatewayResponse(string(resp), 200)
+	}
+
+	users := service.GetUsers(ctx, l)
+
+	resp, _ := json.Marshal(users)
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.League, a.Auth)
+	}
+}
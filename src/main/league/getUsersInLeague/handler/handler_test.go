@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/main/app"
+)
+
+func TestUpdate(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
+		PathParameters: map[string]string{
+			"league": "default",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.League,
+		a.Auth)
+	fmt.Printf("your boy %s", resp.Body)
+}
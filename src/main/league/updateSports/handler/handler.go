@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/sport"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+type Input struct {
+	Sports []string `json:"sports"`
+}
+
+func update(ctx context.Context, request events.APIGatewayV2HTTPRequest, leagueService league.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	l := request.PathParameters["league"]
+
+	email, ok := authService.Authorize(ctx, request, l)
+	if !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	existing, ok := leagueService.GetLeague(ctx, l)
+	if !ok || existing.AdminUser != email {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: "only the league admin can change its sports"})
+		return util.ApigatewayResponse(string(resp), 403)
+	}
+
+	var input = Input{}
+	v := validation.New()
+	if v.Decode(request.Body, &input) && v.Check(len(input.Sports) > 0, "sports", "must list at least one sport") {
+		for i, kind := range input.Sports {
+			_, known := sport.Get(kind)
+			v.Check(known, fmt.Sprintf("sports[%d]", i), fmt.Sprintf("%s is not a supported sport", kind))
+		}
+	}
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	leagueService.SetSports(ctx, l, input.Sports)
+
+	resp, _ := json.Marshal(input)
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return update(ctx, request, a.League, a.Auth)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"sammy.link/auth"
+	"sammy.link/main/app"
+	betGet "sammy.link/main/bet/get/handler"
+	betLive "sammy.link/main/bet/live/handler"
+	bidCreate "sammy.link/main/bid/create/handler"
+	bidGetByEvent "sammy.link/main/bid/getByEvent/handler"
+	bidGetByUser "sammy.link/main/bid/getByUser/handler"
+	bidUpdate "sammy.link/main/bid/update/handler"
+	leagueGetLeaderboard "sammy.link/main/league/getLeaderboard/handler"
+	leagueGetSeasons "sammy.link/main/league/getSeasons/handler"
+	leagueGetUsersInLeague "sammy.link/main/league/getUsersInLeague/handler"
+	leagueUpdateSports "sammy.link/main/league/updateSports/handler"
+	"sammy.link/main/local/server"
+	marketplaceGet "sammy.link/main/marketplace/get/handler"
+	marketplaceGetEspnInfo "sammy.link/main/marketplace/getEspnInfo/handler"
+	outcomeGetByUser "sammy.link/main/outcome/getByUser/handler"
+	outcomeGetHistory "sammy.link/main/outcome/getHistory/handler"
+	profileGet "sammy.link/main/profile/get/handler"
+	profileUpdate "sammy.link/main/profile/update/handler"
+	realtimeTicket "sammy.link/main/realtime/ticket/handler"
+	userGetUser "sammy.link/main/user/getUser/handler"
+	userUpdateUsername "sammy.link/main/user/updateUsername/handler"
+)
+
+// Serves every API route on one port, against the table in TABLE_NAME.
+//
+//	TABLE_NAME=... CURSOR_SECRET=... go run ./local -email you@example.com
+//
+// Requests skip the JWT authorizer and carry the claims given on the command line.
+// Send an X-Local-Email header to act as someone else.
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	email := flag.String("email", "local@sammy.link", "email claim injected into every request")
+	claims := flag.String("claims", "", `more claims to inject as a JSON object, e.g. {"sub":"local"}`)
+	flag.Parse()
+
+	injected := map[string]string{}
+	if *claims != "" {
+		if err := json.Unmarshal([]byte(*claims), &injected); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+	}
+	injected[auth.EmailClaim] = *email
+
+	a, err := app.New(context.Background(), app.ConfigFromEnv())
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	served := routes(a)
+	for i := range served {
+		served[i].Handler = app.Logged(served[i].Handler)
+	}
+
+	fmt.Printf("listening on http://%s as %s\n", *addr, *email)
+	err = http.ListenAndServe(*addr, server.New(served, server.FakeClaims(injected)))
+	fmt.Println(err.Error())
+	os.Exit(1)
+}
+
+// routes mirrors lib/api/gateway/routes.
+func routes(a *app.App) []server.Route {
+	return []server.Route{
+		{Method: http.MethodGet, Path: "/bet", Handler: betGet.New(a)},
+		{Method: http.MethodGet, Path: "/bet/date/{date}", Handler: betGet.New(a)},
+		{Method: http.MethodGet, Path: "/bet/live", Handler: betLive.New(a)},
+		{Method: http.MethodPost, Path: "/bid", Handler: bidCreate.New(a)},
+		{Method: http.MethodGet, Path: "/bid/event/{event}", Handler: bidGetByEvent.New(a)},
+		{Method: http.MethodGet, Path: "/bid", Handler: bidGetByUser.New(a)},
+		{Method: http.MethodPut, Path: "/bid", Handler: bidUpdate.New(a)},
+		{Method: http.MethodGet, Path: "/league/users/{league}", Handler: leagueGetUsersInLeague.New(a)},
+		{Method: http.MethodGet, Path: "/league/leaderboard/{league}", Handler: leagueGetLeaderboard.New(a)},
+		{Method: http.MethodPut, Path: "/league/sports/{league}", Handler: leagueUpdateSports.New(a)},
+		{Method: http.MethodGet, Path: "/league/seasons/{league}", Handler: leagueGetSeasons.New(a)},
+		{Method: http.MethodGet, Path: "/marketplace", Handler: marketplaceGet.New(a)},
+		{Method: http.MethodGet, Path: "/espn-info", Handler: marketplaceGetEspnInfo.New(a)},
+		{Method: http.MethodGet, Path: "/my-outcomes", Handler: outcomeGetByUser.New(a)},
+		{Method: http.MethodGet, Path: "/outcome/history/{league}", Handler: outcomeGetHistory.New(a)},
+		{Method: http.MethodGet, Path: "/profile", Handler: profileGet.New(a)},
+		{Method: http.MethodPut, Path: "/profile", Handler: profileUpdate.New(a)},
+		{Method: http.MethodPost, Path: "/realtime/ticket", Handler: realtimeTicket.New(a)},
+		{Method: http.MethodGet, Path: "/user", Handler: userGetUser.New(a)},
+		{Method: http.MethodPut, Path: "/user", Handler: userUpdateUsername.New(a)},
+	}
+}
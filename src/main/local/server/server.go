@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/util"
+)
+
+type Handler = func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// Route mounts a handler on a method and an API gateway path, where {name} matches a
+// single path segment and is passed on as a path parameter.
+type Route struct {
+	Method  string
+	Path    string
+	Handler Handler
+}
+
+// ClaimsInjector stands in for the JWT authorizer, returning the claims a request is
+// made with.
+type ClaimsInjector func(r *http.Request) map[string]string
+
+// EmailHeader lets a local request act as someone other than the default user.
+const EmailHeader = "X-Local-Email"
+
+// FakeClaims injects claims into every request, with the email claim taken from the
+// EmailHeader when one is sent.
+func FakeClaims(claims map[string]string) ClaimsInjector {
+	return func(r *http.Request) map[string]string {
+		injected := make(map[string]string, len(claims)+1)
+		for name, value := range claims {
+			injected[name] = value
+		}
+		if email := r.Header.Get(EmailHeader); email != "" {
+			injected[auth.EmailClaim] = email
+		}
+		return injected
+	}
+}
+
+type Server struct {
+	routes   []Route
+	claims   ClaimsInjector
+	requests atomic.Int64
+}
+
+func New(routes []Route, claims ClaimsInjector) *Server {
+	return &Server{
+		routes: routes,
+		claims: claims,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the same preflight the gateway answers for browsers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS, POST, PUT")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Amz-Date, Authorization, X-Api-Key, X-Amz-Security-Token, X-Amz-User-Agent, "+EmailHeader)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	route, params, found := s.match(r.Method, r.URL.Path)
+	if route == nil {
+		status := http.StatusNotFound
+		if found {
+			status = http.StatusMethodNotAllowed
+		}
+		writeMessage(w, status, http.StatusText(status))
+		return
+	}
+
+	request, err := s.ToRequest(r, *route, params)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := route.Handler(r.Context(), request)
+	if err != nil {
+		fmt.Println(err.Error())
+		writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	WriteResponse(w, resp)
+}
+
+// match picks the route with the most literal segments matching path, and reports
+// whether any route had the path under another method.
+func (s *Server) match(method string, path string) (*Route, map[string]string, bool) {
+	var best *Route
+	var bestParams map[string]string
+	bestLiterals := -1
+	found := false
+
+	for i, route := range s.routes {
+		params, literals, ok := matchPath(route.Path, path)
+		if !ok {
+			continue
+		}
+		found = true
+		if route.Method != method || literals <= bestLiterals {
+			continue
+		}
+		best, bestParams, bestLiterals = &s.routes[i], params, literals
+	}
+
+	return best, bestParams, found
+}
+
+func matchPath(pattern string, path string) (map[string]string, int, bool) {
+	patterns := strings.Split(strings.Trim(pattern, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patterns) != len(segments) {
+		return nil, 0, false
+	}
+
+	params := make(map[string]string)
+	literals := 0
+	for i, segment := range patterns {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+// ToRequest translates r into the payload API gateway sends a Lambda integration.
+// Repeated headers and query parameters are joined with commas the way the gateway
+// does it.
+func (s *Server) ToRequest(r *http.Request, route Route, params map[string]string) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+
+	headers := make(map[string]string)
+	for name, values := range r.Header {
+		if name == "Cookie" {
+			continue
+		}
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	cookies := make([]string, 0)
+	for _, cookie := range r.Cookies() {
+		cookies = append(cookies, cookie.String())
+	}
+
+	query := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		query[name] = strings.Join(values, ",")
+	}
+
+	routeKey := fmt.Sprintf("%s %s", route.Method, route.Path)
+	now := time.Now()
+
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              routeKey,
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		PathParameters:        params,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   routeKey,
+			AccountID:  "local",
+			Stage:      "$default",
+			RequestID:  fmt.Sprintf("local-%d", s.requests.Add(1)),
+			APIID:      "local",
+			DomainName: r.Host,
+			Time:       now.UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  now.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	if s.claims != nil {
+		request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+				Claims: s.claims(r),
+				Scopes: []string{"openid"},
+			},
+		}
+	}
+
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+
+	return request, nil
+}
+
+func WriteResponse(w http.ResponseWriter, resp events.APIGatewayV2HTTPResponse) {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range resp.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			fmt.Println(err.Error())
+			writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		body = decoded
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp, _ := json.Marshal(util.DefaultResponse{Message: message})
+	w.Write(resp)
+}
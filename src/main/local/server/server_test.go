@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+)
+
+func record(requests *[]events.APIGatewayV2HTTPRequest, status int) Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		*requests = append(*requests, request)
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: status,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       `{"route":"` + request.RouteKey + `"}`,
+		}, nil
+	}
+}
+
+func newTestServer(requests *[]events.APIGatewayV2HTTPRequest) *Server {
+	return New([]Route{
+		{Method: http.MethodGet, Path: "/bet", Handler: record(requests, 200)},
+		{Method: http.MethodGet, Path: "/bet/date/{date}", Handler: record(requests, 200)},
+		{Method: http.MethodGet, Path: "/bet/live", Handler: record(requests, 200)},
+		{Method: http.MethodGet, Path: "/bet/{id}", Handler: record(requests, 200)},
+		{Method: http.MethodPost, Path: "/bid", Handler: record(requests, 201)},
+	}, FakeClaims(map[string]string{auth.EmailClaim: "local@sammy.link", "sub": "local"}))
+}
+
+func TestServeHTTP(t *testing.T) {
+	requests := make([]events.APIGatewayV2HTTPRequest, 0)
+	s := newTestServer(&requests)
+
+	tests := []struct {
+		method string
+		target string
+		status int
+		route  string
+	}{
+		{http.MethodGet, "/bet", 200, "GET /bet"},
+		{http.MethodGet, "/bet/date/2023-10-01", 200, "GET /bet/date/{date}"},
+		{http.MethodGet, "/bet/live", 200, "GET /bet/live"},
+		{http.MethodGet, "/bet/123", 200, "GET /bet/{id}"},
+		{http.MethodPost, "/bid", 201, "POST /bid"},
+		{http.MethodPut, "/bid", 405, ""},
+		{http.MethodGet, "/nope", 404, ""},
+		{http.MethodOptions, "/bid", 204, ""},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+
+		if w.Code != test.status {
+			t.Errorf("%s %s = %d, want %d", test.method, test.target, w.Code, test.status)
+		}
+		if test.route != "" && !strings.Contains(w.Body.String(), test.route) {
+			t.Errorf("%s %s served by %s, want %s", test.method, test.target, w.Body.String(), test.route)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("%s %s is missing the CORS header", test.method, test.target)
+		}
+	}
+}
+
+func TestToRequest(t *testing.T) {
+	requests := make([]events.APIGatewayV2HTTPRequest, 0)
+	s := newTestServer(&requests)
+
+	r := httptest.NewRequest(http.MethodGet, "/bet/date/2023-10-01?div=league&kind=nfl&kind=ncaaf", nil)
+	r.Header.Add("Accept", "application/json")
+	r.Header.Add("X-Test", "one")
+	r.Header.Add("X-Test", "two")
+	r.Header.Set(EmailHeader, "other@sammy.link")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	s.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(requests) != 1 {
+		t.Fatalf("handled %d requests", len(requests))
+	}
+	request := requests[0]
+
+	if request.PathParameters["date"] != "2023-10-01" {
+		t.Errorf("path parameters = %v", request.PathParameters)
+	}
+	if request.QueryStringParameters["div"] != "league" || request.QueryStringParameters["kind"] != "nfl,ncaaf" {
+		t.Errorf("query parameters = %v", request.QueryStringParameters)
+	}
+	if request.Headers["x-test"] != "one,two" || request.Headers["accept"] != "application/json" {
+		t.Errorf("headers = %v", request.Headers)
+	}
+	if _, ok := request.Headers["cookie"]; ok || len(request.Cookies) != 1 || request.Cookies[0] != "session=abc" {
+		t.Errorf("cookies = %v, headers = %v", request.Cookies, request.Headers)
+	}
+	if request.RequestContext.HTTP.Method != http.MethodGet || request.RawPath != "/bet/date/2023-10-01" {
+		t.Errorf("request context = %+v", request.RequestContext.HTTP)
+	}
+	if request.RequestContext.RequestID == "" {
+		t.Error("missing request id")
+	}
+
+	claims := request.RequestContext.Authorizer.JWT.Claims
+	if auth.GetEmail(request) != "other@sammy.link" || claims["sub"] != "local" {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestToRequestBody(t *testing.T) {
+	s := New(nil, nil)
+
+	request, err := s.ToRequest(httptest.NewRequest(http.MethodPost, "/bid", strings.NewReader(`[{"amount":10}]`)), Route{Method: http.MethodPost, Path: "/bid"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Body != `[{"amount":10}]` || request.IsBase64Encoded {
+		t.Errorf("body = %s, base64 = %t", request.Body, request.IsBase64Encoded)
+	}
+	if auth.GetEmail(request) != "" {
+		t.Error("claims injected without an injector")
+	}
+
+	binary := []byte{0xff, 0xfe, 0x00}
+	request, err = s.ToRequest(httptest.NewRequest(http.MethodPost, "/bid", strings.NewReader(string(binary))), Route{Method: http.MethodPost, Path: "/bid"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Body != base64.StdEncoding.EncodeToString(binary) || !request.IsBase64Encoded {
+		t.Errorf("body = %s, base64 = %t", request.Body, request.IsBase64Encoded)
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteResponse(w, events.APIGatewayV2HTTPResponse{
+		StatusCode:      400,
+		Headers:         map[string]string{"Content-Type": "application/json"},
+		Cookies:         []string{"a=1", "b=2"},
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"message":"bad"}`)),
+		IsBase64Encoded: true,
+	})
+
+	if w.Code != 400 || w.Header().Get("Content-Type") != "application/json" || len(w.Header().Values("Set-Cookie")) != 2 {
+		t.Errorf("response = %d %v", w.Code, w.Header())
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["message"] != "bad" {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
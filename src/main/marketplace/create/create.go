@@ -2,34 +2,32 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
-	"sammy.link/database"
-	"sammy.link/espn"
+	"sammy.link/logging"
+	"sammy.link/main/app"
 	"sammy.link/marketplace"
+	"sammy.link/scheduler"
+	"sammy.link/scores"
+	"sammy.link/season"
+	"sammy.link/sport"
 	"sammy.link/util"
 )
 
 func main() {
+	a := app.Must(app.New(context.Background(), app.ConfigFromEnv()))
 	lambda.Start(func(ctx context.Context) {
-		handler(ctx, marketplace.NewService(database.GetDatabaseService[marketplace.MarketplaceDynamoDbItem, marketplace.MarketplaceItem](ctx)))
+		handler(app.WithInvocation(ctx), a.Now(), a.Marketplace, a.Scores, a.ResolveScheduler())
 	})
 }
 
-func handler(ctx context.Context, service marketplace.Service) {
+func handler(ctx context.Context, now time.Time, service marketplace.Service, provider scores.Provider, resolveScheduler scheduler.Scheduler) {
 
 	marketplaceDbItems := service.GetItems(ctx)
-
-	fmt.Printf("%d is len", len(marketplaceDbItems))
+	logger := logging.FromContext(ctx)
+	logger.Debug("read listings", "count", len(marketplaceDbItems))
 
 	marketplaceCache := make(map[string]bool)
 
@@ -37,64 +35,27 @@ func handler(ctx context.Context, service marketplace.Service) {
 		marketplaceCache[marketplace.BuildMarketplaceDynamoId(item.Kind, item.Date, item.AwayTeam, item.HomeTeam)] = true
 	}
 
-	espnResponseChannel := make(chan espn.EspnResponse, 4)
-
-	sportMap := map[string][]string{
-		"football": {"nfl", "college-football"},
-	}
+	gamesChannel := make(chan []scores.Game, len(sport.Sports))
 
-	firstDate := time.Now()
+	firstDate := now
 	secondDate := firstDate.AddDate(0, 0, 7)
-	//@TODO use the espn service from the handler parameter
-	espnService := espn.NewService(http.Client{})
-	for sport, leagues := range sportMap {
-		for _, league := range leagues {
-			go espnService.GetEspnData(sport, league, espnResponseChannel, firstDate, secondDate)
-		}
+	for _, s := range sport.Sports {
+		go func(mySport sport.Sport) {
+			games, err := provider.Games(ctx, mySport, firstDate, secondDate)
+			if err != nil {
+				logger.Warn("skipping sport", "kind", mySport.Kind, "err", err)
+			}
+			gamesChannel <- games
+		}(s)
 	}
 
 	events := make([]marketplace.MarketplaceItem, 0, 25)
 
-	for _, leagues := range sportMap {
-		for range leagues {
-			response := <-espnResponseChannel
-
-			for _, sport := range response.Sports {
-				for _, league := range sport.Leagues {
-					for _, event := range league.Events {
-
-						awayTeam := event.Competitors[0]
-						homeTeam := event.Competitors[1]
-
-						kind := getKind(league.Name)
-						fmt.Printf("week is %d", event.Week)
-						if !marketplaceCache[marketplace.BuildMarketplaceDynamoId(kind, event.Date, awayTeam.Name, homeTeam.Name)] && event.Date.After(time.Now()) && event.Odds.Details != "" && event.Odds.Details != "OFF" {
-
-							spread := event.Odds.Details
-
-							if spread == "EVEN" {
-								spread = fmt.Sprintf("%s %d", awayTeam.Abbreviation, 0)
-							}
-
-							item := marketplace.MarketplaceItem{AwayTeam: awayTeam.Name,
-								HomeTeam: homeTeam.Name, Date: event.Date, Kind: kind,
-								AwayAbbreviation: awayTeam.Abbreviation,
-								HomeAbbreviation: homeTeam.Abbreviation,
-								AwayRecord:       awayTeam.Record,
-								HomeRecord:       homeTeam.Record,
-								Id:               event.Id,
-								Week:             event.Week,
-								Spread:           spread}
-
-							events = append(events, item)
-						}
-					}
-				}
-			}
-		}
+	for range sport.Sports {
+		events = append(events, buildItems(<-gamesChannel, marketplaceCache, now)...)
 	}
 
-	fmt.Printf("saving %d events\n", len(events))
+	logger.Info("saving events", "count", len(events))
 	var waitGroup sync.WaitGroup
 
 	for i := 0; i < len(events); i += 25 {
@@ -106,53 +67,67 @@ func handler(ctx context.Context, service marketplace.Service) {
 	}
 
 	waitGroup.Wait()
-}
-
-func createRuleAndTarget(ctx context.Context, bridge *eventbridge.Client, myEventDate int64, myNowUnix int64, myEvents []marketplace.MarketplaceItem) {
-	ruleName := aws.String(fmt.Sprintf("%d-%d", myEventDate, myNowUnix))
 
-	firstEventDate := myEvents[0].Date
-	cronExpression := fmt.Sprintf("cron(%d %d %d %d ? %d)", firstEventDate.Minute(), firstEventDate.Hour(), firstEventDate.Day(), int(firstEventDate.Month()), firstEventDate.Year())
+	scheduleResolution(ctx, resolveScheduler, events)
+}
 
-	input := &eventbridge.PutRuleInput{
-		Name:               ruleName,
-		ScheduleExpression: aws.String(cronExpression),
-		State:              types.RuleStateEnabled,
+// scheduleResolution sets up one resolve schedule per kickoff, a few hours after the
+// games should have finished. Games it couldn't schedule are left for the daily run.
+func scheduleResolution(ctx context.Context, resolveScheduler scheduler.Scheduler, items []marketplace.MarketplaceItem) {
+	events := make([]scheduler.Event, 0, len(items))
+	for _, item := range items {
+		events = append(events, scheduler.Event{
+			Id:       item.Id,
+			Kind:     item.Kind,
+			Date:     item.Date,
+			AwayTeam: item.AwayTeam,
+			HomeTeam: item.HomeTeam,
+		})
 	}
 
-	_, err := bridge.PutRule(ctx, input)
-
-	if err != nil {
-		fmt.Println(err.Error())
+	kickoffs := scheduler.GroupByKickoff(events)
+	failed := 0
+	for kickoff, kickoffEvents := range kickoffs {
+		err := resolveScheduler.Schedule(ctx, kickoff.Add(scheduler.ResolveAfter), scheduler.Target{Events: kickoffEvents})
+		if err != nil {
+			failed++
+			logging.FromContext(ctx).Error("couldn't schedule resolution", "kickoff", kickoff, "err", err)
+		}
+	}
+	if failed > 0 {
+		logging.FromContext(ctx).Warn("some kickoffs are left for the daily run", "failed", failed, "kickoffs", len(kickoffs))
 	}
+}
 
-	jsonInput, _ := json.Marshal(TargetInput{
-		RuleName: *ruleName,
-		Events:   myEvents,
-	})
+// buildItems turns games into marketplace items, skipping the ones already listed,
+// already started, or without a line.
+func buildItems(games []scores.Game, marketplaceCache map[string]bool, now time.Time) []marketplace.MarketplaceItem {
+	items := make([]marketplace.MarketplaceItem, 0, len(games))
+	for _, game := range games {
+		gameSport, ok := sport.Get(game.Kind)
+		if !ok {
+			continue
+		}
 
-	targetInput := &eventbridge.PutTargetsInput{
-		Rule: ruleName,
-		Targets: []types.Target{
-			{
-				Arn:   aws.String(os.Getenv("CREATE_LAMBDA_ARN")),
-				Id:    aws.String("my-target"),
-				Input: aws.String(string(jsonInput)),
-			},
-		},
-	}
+		spread := gameSport.Spread(game.Odds, game.Away.Abbreviation)
+		if marketplaceCache[marketplace.BuildMarketplaceDynamoId(game.Kind, game.Date, game.Away.Name, game.Home.Name)] || !game.Date.After(now) || spread == "" {
+			continue
+		}
 
-	bridge.PutTargets(ctx, targetInput)
-}
+		week := game.Week
+		if week == 0 {
+			week = season.WeekOf(game.Date)
+		}
 
-func getKind(league string) string {
-	if league == "NCAA - Football" {
-		return "CFB"
+		items = append(items, marketplace.MarketplaceItem{AwayTeam: game.Away.Name,
+			HomeTeam: game.Home.Name, Date: game.Date, Kind: game.Kind,
+			AwayAbbreviation: game.Away.Abbreviation,
+			HomeAbbreviation: game.Home.Abbreviation,
+			AwayRecord:       game.Away.Record,
+			HomeRecord:       game.Home.Record,
+			Id:               game.Id,
+			Week:             week,
+			Spread:           spread})
 	}
-	return "NFL"
-}
-
-type TargetInput struct {
-	Events   []marketplace.MarketplaceItem `json:"events"`
-	RuleName string                        `json:"ruleName"`
+	return items
 }
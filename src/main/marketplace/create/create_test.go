@@ -3,12 +3,55 @@ package main
 import (
 	"context"
 	"testing"
+	"time"
 
-	"sammy.link/database"
+	"sammy.link/main/app"
 	"sammy.link/marketplace"
+	"sammy.link/scheduler"
+	"sammy.link/scores"
+	"sammy.link/sport"
 )
 
 func TestCreate(t *testing.T) {
 	ctx := context.TODO()
-	handler(ctx, marketplace.NewService(database.GetDatabaseService[marketplace.MarketplaceDynamoDbItem, marketplace.MarketplaceItem](ctx)))
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	handler(ctx, a.Now(), a.Marketplace, a.Scores, &scheduler.FakeScheduler{})
+}
+
+func TestBuildItems(t *testing.T) {
+	nfl, _ := sport.Get("NFL")
+	games, _ := scores.NewFixtureProvider(scores.Fixtures()).Games(context.TODO(), nfl, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, time.September, 14, 0, 0, 0, 0, time.UTC))
+
+	now := time.Date(2023, time.September, 9, 0, 0, 0, 0, time.UTC)
+	items := buildItems(games, map[string]bool{}, now)
+
+	if len(items) != 1 || items[0].Id != "401547397" || items[0].Spread != "PHI -3.5" || items[0].Kind != "NFL" || items[0].Week != 1 {
+		t.Fatalf("should only list the game that hasn't started but got %+v", items)
+	}
+
+	listed := map[string]bool{marketplace.BuildMarketplaceDynamoId("NFL", items[0].Date, items[0].AwayTeam, items[0].HomeTeam): true}
+	if items := buildItems(games, listed, now); len(items) != 0 {
+		t.Fatalf("games already in the marketplace shouldn't be listed twice but got %+v", items)
+	}
+}
+
+func TestScheduleResolution(t *testing.T) {
+	kickoff := time.Date(2023, time.September, 10, 17, 0, 0, 0, time.UTC)
+	fake := &scheduler.FakeScheduler{}
+
+	scheduleResolution(context.TODO(), fake, []marketplace.MarketplaceItem{
+		{Id: "1", Kind: "NFL", Date: kickoff, AwayTeam: "Philadelphia Eagles", HomeTeam: "New England Patriots"},
+		{Id: "2", Kind: "NFL", Date: kickoff, AwayTeam: "Miami Dolphins", HomeTeam: "Buffalo Bills"},
+		{Id: "3", Kind: "NFL", Date: kickoff.Add(3 * time.Hour), AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs"},
+	})
+
+	if len(fake.Scheduled) != 2 {
+		t.Fatalf("should schedule one trigger per kickoff but got %+v", fake.Scheduled)
+	}
+
+	for _, scheduled := range fake.Scheduled {
+		if len(scheduled.Target.Events) == 2 && !scheduled.At.Equal(kickoff.Add(scheduler.ResolveAfter)) {
+			t.Fatalf("should resolve the early games after they finish but got %s", scheduled.At)
+		}
+	}
 }
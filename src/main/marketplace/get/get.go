@@ -2,30 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"time"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"sammy.link/database"
-	"sammy.link/marketplace"
-	"sammy.link/util"
+	"sammy.link/main/app"
+	"sammy.link/main/marketplace/get/handler"
 )
 
-func isRecent(item marketplace.MarketplaceItem) bool {
-	return item.Date.After(time.Now())
-}
-
-func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, service marketplace.Service) (events.APIGatewayV2HTTPResponse, error) {
-
-	marketplaceEvents := service.GetItems(ctx)
-
-	resp, _ := json.Marshal(util.Filter(marketplaceEvents, isRecent))
-	return util.ApigatewayResponse(string(resp), 200)
-}
-
 func main() {
-	lambda.Start(func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-		return handleGet(ctx, request, marketplace.NewService(database.GetDatabaseService[marketplace.MarketplaceDynamoDbItem, marketplace.MarketplaceItem](ctx)))
-	})
+	lambda.Start(app.Logged(handler.New(app.Must(app.New(context.Background(), app.ConfigFromEnv())))))
 }
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/sport"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, now time.Time, service marketplace.Service, leagueService league.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	filter, paged, err := getFilter(request.QueryStringParameters, now)
+	if err != nil {
+		return validation.BadRequest(err)
+	}
+
+	if l, ok := request.QueryStringParameters["league"]; ok {
+		leagueItem, _ := leagueService.GetLeague(ctx, l)
+		if filter.Kind != "" && !leagueItem.Allows(filter.Kind) {
+			filter.Kinds = []string{}
+		} else if filter.Kind == "" {
+			filter.Kinds = leagueItem.EnabledSports()
+		}
+	}
+
+	marketplaceEvents := make([]marketplace.MarketplaceItem, 0)
+	cursor := ""
+	if filter.Kinds == nil || len(filter.Kinds) > 0 {
+		marketplaceEvents, cursor, err = service.GetPage(ctx, filter)
+		if err != nil {
+			resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+			return util.ApigatewayResponse(string(resp), 400)
+		}
+	}
+
+	if !paged {
+		resp, _ := json.Marshal(marketplaceEvents)
+		return util.ApigatewayResponse(string(resp), 200)
+	}
+
+	resp, _ := json.Marshal(util.Page[marketplace.MarketplaceItem]{
+		Items:  marketplaceEvents,
+		Cursor: cursor,
+	})
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// getFilter reads the listing query parameters. Only games that haven't started are
+// listed unless an earlier from is asked for, and a limit or cursor switches the
+// response to pages.
+func getFilter(params map[string]string, now time.Time) (marketplace.Filter, bool, error) {
+	filter := marketplace.Filter{
+		Kind: params["kind"],
+		Team: params["team"],
+		From: now,
+	}
+
+	v := validation.New()
+	limit, cursor, paged := v.Page(params)
+	if paged {
+		filter.Limit = limit
+		filter.Cursor = cursor
+	}
+
+	if kind, ok := params["kind"]; ok {
+		_, known := sport.Get(kind)
+		v.Check(known, "kind", "must be a supported sport")
+	}
+
+	if week, ok := params["week"]; ok {
+		filter.Week = int(v.Number("week", week).Min(1).Value())
+	}
+
+	var err error
+	if from, ok := params["from"]; ok {
+		if filter.From, err = parseDate(from, false); err != nil {
+			v.Add("from", "must be a date or RFC3339 time")
+		}
+	}
+
+	if to, ok := params["to"]; ok {
+		if filter.To, err = parseDate(to, true); err != nil {
+			v.Add("to", "must be a date or RFC3339 time")
+		} else {
+			v.Check(!filter.To.Before(filter.From), "to", "must be after from")
+		}
+	}
+
+	if open, ok := params["open"]; ok {
+		if filter.Open, err = strconv.ParseBool(open); err != nil {
+			v.Add("open", "must be true or false")
+		}
+	}
+
+	return filter, paged, v.Err()
+}
+
+// parseDate accepts a full timestamp or a plain date, which covers the whole day when
+// it ends a range.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err == nil && endOfDay {
+		date = date.Add(24*time.Hour - time.Second)
+	}
+	return date, err
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Now(), a.Marketplace, a.League)
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/main/app"
+	"sammy.link/validation"
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{}, a.Now(), a.Marketplace, a.League)
+	fmt.Printf("your boy %s", resp.Body)
+}
+
+func TestGetFilter(t *testing.T) {
+	now := time.Date(2023, time.September, 7, 12, 0, 0, 0, time.UTC)
+
+	filter, paged, err := getFilter(map[string]string{"kind": "NFL", "week": "1", "to": "2023-09-10", "open": "true", "team": "KC"}, now)
+	if err != nil || paged || filter.Kind != "NFL" || filter.Week != 1 || !filter.Open || filter.Team != "KC" || filter.Limit != 0 {
+		t.Fatalf("should read every filter but got %+v %v", filter, err)
+	}
+
+	if !filter.From.Equal(now) || !filter.To.Equal(time.Date(2023, time.September, 10, 23, 59, 59, 0, time.UTC)) {
+		t.Fatalf("should list games from now through the end of the 10th but got %s to %s", filter.From, filter.To)
+	}
+
+	if filter, paged, _ := getFilter(map[string]string{"cursor": "abc"}, now); !paged || filter.Limit != 25 || filter.Cursor != "abc" {
+		t.Fatalf("a cursor without a limit should use the default page size but got %d", filter.Limit)
+	}
+
+	for _, params := range []map[string]string{
+		{"week": "zero"},
+		{"limit": "500"},
+		{"from": "yesterday"},
+		{"to": "2023-09-01"},
+		{"open": "maybe"},
+		{"kind": "XFL"},
+	} {
+		if _, _, err := getFilter(params, now); err == nil {
+			t.Fatalf("%v should be rejected", params)
+		}
+	}
+
+	_, _, err = getFilter(map[string]string{"week": "0", "limit": "0", "open": "maybe"}, now)
+	var errs validation.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 || errs[0].Field != "limit" || errs[1].Field != "week" || errs[2].Field != "open" {
+		t.Fatalf("should report every bad parameter but got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/espn"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/sport"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handle(ctx context.Context, request events.APIGatewayV2HTTPRequest, espnService espn.Service, marketplaceService marketplace.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	eventId := request.QueryStringParameters["eventId"]
+	v := validation.New()
+	v.String("eventId", eventId).Required()
+	if kind, ok := request.QueryStringParameters["kind"]; ok {
+		_, known := sport.Get(kind)
+		v.Check(known, "kind", "must be a supported sport")
+	}
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	// games drop out of the marketplace a day after they're played, after that the
+	// client has to tell us what kind of game it was
+	kind := request.QueryStringParameters["kind"]
+	if item, ok := marketplaceService.GetByEventId(ctx, eventId); ok {
+		kind = item.Kind
+	}
+
+	eventSport, ok := sport.Get(kind)
+	if !ok {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: fmt.Sprintf("don't know what sport event %s is", eventId)})
+		return util.ApigatewayResponse(string(resp), 404)
+	}
+
+	resp, err := espnService.GetEspnEvent(ctx, eventSport.Sport, eventSport.League, eventId)
+	if errors.Is(err, espn.ErrCircuitOpen) {
+		message, _ := json.Marshal(util.DefaultResponse{Message: "scores are unavailable right now"})
+		return util.ApigatewayResponse(string(message), 503)
+	} else if err != nil || len(resp.Sports) == 0 {
+		message, _ := json.Marshal(util.DefaultResponse{Message: "event not found"})
+		return util.ApigatewayResponse(string(message), 404)
+	}
+
+	espnResponse, _ := json.Marshal(resp)
+
+	return util.ApigatewayResponse(string(espnResponse), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handle(ctx, request, a.Espn, a.Marketplace)
+	}
+}
@@ -1,22 +1,24 @@
-package main
+package handler
 
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	"sammy.link/espn"
+	"sammy.link/main/app"
 )
 
 func TestGetBetKinds(t *testing.T) {
 	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
 	resp, _ := handle(ctx, events.APIGatewayV2HTTPRequest{
 		QueryStringParameters: map[string]string{
 			"eventId": "401520176",
+			"kind":    "CFB",
 		},
-	}, espn.NewService(http.Client{}))
+	}, a.Espn,
+		a.Marketplace)
 
 	fmt.Println(resp)
 	// if len(result) != 2 || ((result[0] != "NFL" || result[1] != "CFB") && (result[1] != "NFL" || result[0] != "CFB")) {
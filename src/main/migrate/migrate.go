@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"sammy.link/bet"
+	"sammy.link/bid"
+	"sammy.link/database"
+	"sammy.link/espn"
+	"sammy.link/history"
+	"sammy.link/leaderboard"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/marketplace"
+	"sammy.link/outcome"
+	"sammy.link/season"
+	"sammy.link/user"
+	"sammy.link/util"
+)
+
+// Rewrites every item in the table at the current schema version.
+//
+//	TABLE_NAME=... go run ./migrate -dry-run
+//	TABLE_NAME=... go run ./migrate -checkpoint migrate.checkpoint
+//
+// The key to resume from is printed after every page, and kept in the checkpoint
+// file when one is given, so an interrupted run picks up where it stopped.
+func main() {
+	table := flag.String("table", os.Getenv("TABLE_NAME"), "table to migrate")
+	dryRun := flag.Bool("dry-run", false, "log the items that would be upgraded without writing them")
+	resume := flag.String("resume", "", "resume token printed by an earlier run")
+	checkpoint := flag.String("checkpoint", "", "file to keep the resume token in")
+	pageSize := flag.Int("page-size", 100, "items read per scan request")
+	flag.Parse()
+
+	if *table == "" {
+		fmt.Println("a table is required, set -table or TABLE_NAME")
+		os.Exit(2)
+	}
+
+	ctx := logging.WithLogger(context.Background(), logging.New(os.Stderr, logging.LevelFromEnv()))
+	config, err := util.GetAwsConfig(ctx)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	start, err := getResume(*resume, *checkpoint)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	migrator := &database.Migrator{
+		Client:     dynamodb.NewFromConfig(config),
+		Table:      *table,
+		Migrations: migrations,
+		DryRun:     *dryRun,
+		PageSize:   int32(*pageSize),
+		Checkpoint: func(next string) error {
+			if next != "" {
+				fmt.Printf("resume: %s\n", next)
+			}
+			return saveResume(*checkpoint, next, *dryRun)
+		},
+	}
+
+	stats, err := migrator.Run(ctx, start)
+	fmt.Printf("%+v\n", stats)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if stats.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// migrations is matched in order, so of two with the same prefix the narrower one comes first.
+var migrations = []database.Migration{
+	{Prefix: "BET|", Upgrade: database.Upgrade[bet.BetDynamoItem]},
+	{Prefix: "B|", Upgrade: database.Upgrade[bid.DyanmoBidItem]},
+	{Prefix: "MK", Upgrade: database.Upgrade[marketplace.MarketplaceDynamoDbItem]},
+	{Prefix: "O|", Upgrade: database.Upgrade[outcome.OutcomeDynamoItem]},
+	{Prefix: "U|", Upgrade: database.Upgrade[user.DynamoItem]},
+	{Prefix: "LEAGUE", Upgrade: database.Upgrade[league.LeagueDynamoItem]},
+	{Prefix: "LB|", Upgrade: database.Upgrade[leaderboard.DynamoItem]},
+	{Prefix: "L|", Upgrade: database.Upgrade[league.UserInLeagueDynamoItem]},
+	{Prefix: "SEASON|", Match: isStanding, Upgrade: database.Upgrade[season.StandingDynamoItem]},
+	{Prefix: "SEASON|", Upgrade: database.Upgrade[season.DynamoItem]},
+	{Prefix: "H2H|", Upgrade: database.Upgrade[history.RecordDynamoItem]},
+	{Prefix: "H|", Upgrade: database.Upgrade[history.DynamoItem]},
+	{Prefix: "ESPN|", Upgrade: database.Upgrade[espn.CacheDynamoItem]},
+}
+
+func isStanding(id string, sortKey string) bool {
+	return season.IsStanding(id)
+}
+
+func getResume(resume string, checkpoint string) (string, error) {
+	if resume != "" || checkpoint == "" {
+		return resume, nil
+	}
+
+	saved, err := os.ReadFile(checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(saved)), err
+}
+
+func saveResume(checkpoint string, next string, dryRun bool) error {
+	if checkpoint == "" || dryRun {
+		return nil
+	}
+	if next == "" {
+		err := os.Remove(checkpoint)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(checkpoint, []byte(next+"\n"), 0o644)
+}
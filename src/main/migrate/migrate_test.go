@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/bet"
+	"sammy.link/bid"
+	"sammy.link/database"
+	"sammy.link/history"
+	"sammy.link/leaderboard"
+	"sammy.link/league"
+	"sammy.link/marketplace"
+	"sammy.link/season"
+	"sammy.link/user"
+)
+
+func find(t *testing.T, raw map[string]types.AttributeValue) database.Migration {
+	id := raw["id"].(*types.AttributeValueMemberS).Value
+	sortKey := raw["sortKey"].(*types.AttributeValueMemberS).Value
+	for _, migration := range migrations {
+		if len(id) >= len(migration.Prefix) && id[:len(migration.Prefix)] == migration.Prefix &&
+			(migration.Match == nil || migration.Match(id, sortKey)) {
+			return migration
+		}
+	}
+	t.Fatalf("no migration for %s", id)
+	return database.Migration{}
+}
+
+func marshal(t *testing.T, item database.Item) map[string]types.AttributeValue {
+	raw, err := attributevalue.MarshalMap(item.GetDynamoItem())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestMigrationsKeepCurrentItems(t *testing.T) {
+	date := time.Date(2023, time.October, 1, 17, 0, 0, 0, time.UTC)
+	items := []database.Item{
+		bet.Bet{Kind: "nfl", Div: "league", Week: 4, Date: date, AwayTeam: "Jets", HomeTeam: "Chiefs", AwayUser: "a", HomeUser: "b", Amount: 10, Status: bet.Pending, HomeAbbreviation: "KC", AwayAbbreviation: "NYJ"},
+		bid.Bid{Kind: "nfl", Div: "league", Week: 4, Date: date, CreateDate: date.Add(-time.Hour), AwayTeam: "Jets", HomeTeam: "Chiefs", ChosenCompetitor: "Jets", User: "a", Amount: 10, HomeAbbreviation: "KC", AwayAbbreviation: "NYJ"},
+		marketplace.MarketplaceItem{Kind: "nfl", Date: date, AwayTeam: "Jets", HomeTeam: "Chiefs", Id: "401", Week: 4, HomeAbbreviation: "KC", AwayAbbreviation: "NYJ"},
+		user.Item{Email: "a@b.c", Name: "a", League: "league"},
+		league.LeagueItem{Name: "league", AdminUser: "a@b.c", Bankroll: 1000, Sports: []string{"nfl"}},
+		league.UserInLeagueItem{Email: "a@b.c", Name: "a", League: "league", Total: 900},
+		season.Item{League: "league", Id: "2023", Start: date, End: date.AddDate(1, 0, 0)},
+		season.StandingItem{League: "league", Season: "2023", Email: "a@b.c", Name: "a", Total: 900, Rank: 1},
+		leaderboard.Item{League: "league", Season: "2023", Week: 4, Entry: leaderboard.Entry{Email: "a@b.c", Wins: 1, Results: "W"}},
+		history.Item{Div: "league", Email: "a@b.c", OutcomeId: "o1", Date: date, Result: history.Win},
+		history.RecordItem{Div: "league", Email: "a@b.c", Opponent: "d@e.f", Wins: 1},
+	}
+
+	for _, item := range items {
+		raw := marshal(t, item)
+		upgraded, err := find(t, raw).Upgrade(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(raw, upgraded) {
+			t.Errorf("%T changed on upgrade\n%v\n%v", item, raw, upgraded)
+		}
+	}
+}
+
+func TestMigrationsUpgradeLegacyItems(t *testing.T) {
+	date := time.Date(2023, time.October, 1, 17, 0, 0, 0, time.UTC)
+
+	listing := marketplace.MarketplaceItem{Kind: "nfl", Date: date, AwayTeam: "Jets", HomeTeam: "Chiefs", Id: "401", Week: 4, HomeAbbreviation: "KC", AwayAbbreviation: "NYJ"}
+	want := marshal(t, listing)
+	legacy := map[string]types.AttributeValue{
+		"id":         &types.AttributeValueMemberS{Value: "MK"},
+		"sortKey":    &types.AttributeValueMemberS{Value: "nfl|2023-10-01T17:00:00Z|Jets|Chiefs"},
+		"awayAb":     &types.AttributeValueMemberS{Value: "NYJ"},
+		"homeAb":     &types.AttributeValueMemberS{Value: "KC"},
+		"eId":        &types.AttributeValueMemberS{Value: "401"},
+		"week":       &types.AttributeValueMemberN{Value: "4"},
+		"homeAmount": &types.AttributeValueMemberN{Value: "0"},
+		"awayAmount": &types.AttributeValueMemberN{Value: "0"},
+	}
+	upgraded, err := find(t, legacy).Upgrade(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, upgraded) {
+		t.Errorf("legacy listing upgraded to\n%v\nwant\n%v", upgraded, want)
+	}
+
+	member := league.UserInLeagueItem{Email: "a@b.c", Name: "a", League: "league", Total: 900}
+	want = marshal(t, member)
+	legacy = map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: "L|league"},
+		"sortKey": &types.AttributeValueMemberS{Value: "a@b.c"},
+		"na":      &types.AttributeValueMemberS{Value: "a"},
+		"amount":  &types.AttributeValueMemberN{Value: "900"},
+	}
+	upgraded, err = find(t, legacy).Upgrade(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, upgraded) {
+		t.Errorf("legacy member upgraded to\n%v\nwant\n%v", upgraded, want)
+	}
+}
+
+func TestMigrationsEscapeLegacyKeys(t *testing.T) {
+	legacy := map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: "LB|league|2023"},
+		"sortKey": &types.AttributeValueMemberS{Value: `S|a\b.c`},
+		"v":       &types.AttributeValueMemberN{Value: "1"},
+	}
+	upgraded, err := find(t, legacy).Upgrade(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sortKey := upgraded["sortKey"].(*types.AttributeValueMemberS).Value; sortKey != `S|a\\b.c` {
+		t.Errorf("should escape the email but moved to %s", sortKey)
+	}
+
+	if !isStanding("SEASON|league|2023", "a@b.c") || isStanding("SEASON|league", "2023") {
+		t.Error("should tell standings from seasons")
+	}
+}
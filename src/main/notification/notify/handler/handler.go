@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/bid"
+	"sammy.link/event"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/notification"
+	"sammy.link/outcome"
+)
+
+// notifications are what the domain event e tells members about, if anything.
+func notifications(e events.CloudWatchEvent) ([]notification.Notification, error) {
+	switch event.Type(e.DetailType) {
+	case event.BidMatched:
+		var b bid.Bid
+		if err := json.Unmarshal(e.Detail, &b); err != nil {
+			return nil, err
+		}
+		return []notification.Notification{notification.Matched(b)}, nil
+	case event.BetSettled:
+		var o outcome.OutcomeItem
+		if err := json.Unmarshal(e.Detail, &o); err != nil {
+			return nil, err
+		}
+		return notification.Settled(o), nil
+	}
+	return nil, nil
+}
+
+// handleNotify sends everything the queued events tell members about in one go, so a
+// member hears about a whole settlement run at once.
+func handleNotify(ctx context.Context, sqsEvent events.SQSEvent, notificationService notification.Service) error {
+	batch := make([]notification.Notification, 0, len(sqsEvent.Records))
+	for _, record := range sqsEvent.Records {
+		var e events.CloudWatchEvent
+		err := json.Unmarshal([]byte(record.Body), &e)
+		if err == nil {
+			var some []notification.Notification
+			some, err = notifications(e)
+			batch = append(batch, some...)
+		}
+		if err != nil {
+			// retrying won't make the detail any more readable
+			logging.FromContext(ctx).Error("unreadable event", "type", e.DetailType, "err", err)
+		}
+	}
+	notificationService.Notify(ctx, batch)
+	return nil
+}
+
+// New handles the domain events the notification rule queues, so sending never
+// holds up the request that made the change.
+func New(a *app.App) func(ctx context.Context, sqsEvent events.SQSEvent) error {
+	return func(ctx context.Context, sqsEvent events.SQSEvent) error {
+		return handleNotify(app.WithInvocation(ctx), sqsEvent, a.Notification)
+	}
+}
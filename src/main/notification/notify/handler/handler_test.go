@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/notification"
+)
+
+func TestNotifications(t *testing.T) {
+	matched := events.CloudWatchEvent{DetailType: "BidMatched", Detail: json.RawMessage(`{"user":"home@sam.com","div":"default","chosenCompetitor":"Chiefs","awayTeam":"Jets","homeTeam":"Chiefs","spread":"KC -9.5","amount":5}`)}
+	batch, err := notifications(matched)
+	if err != nil || len(batch) != 1 || batch[0].Kind != notification.BetMatched || batch[0].User != "home@sam.com" || batch[0].Body != "5 on Chiefs in Jets @ Chiefs (KC -9.5)" {
+		t.Fatalf("notifications() = %+v, %v", batch, err)
+	}
+
+	settled := events.CloudWatchEvent{DetailType: "BetSettled", Detail: json.RawMessage(`{"winner":"away@sam.com","loser":"home@sam.com","amount":10,"div":"default"}`)}
+	if batch, err := notifications(settled); err != nil || len(batch) != 2 || batch[0].User != "away@sam.com" || batch[1].User != "home@sam.com" {
+		t.Errorf("notifications() = %+v, %v", batch, err)
+	}
+
+	if batch, err := notifications(events.CloudWatchEvent{DetailType: "BidPlaced", Detail: json.RawMessage(`{}`)}); len(batch) != 0 || err != nil {
+		t.Error("only matches and settlements are notified")
+	}
+	if _, err := notifications(events.CloudWatchEvent{DetailType: "BetSettled", Detail: json.RawMessage(`[]`)}); err == nil {
+		t.Error("a detail that isn't an outcome should be reported")
+	}
+}
+
+type sent struct {
+	notification.Service
+	calls [][]notification.Notification
+}
+
+func (s *sent) Notify(ctx context.Context, notifications []notification.Notification) {
+	s.calls = append(s.calls, notifications)
+}
+
+func TestHandleNotifySendsTheWholeBatch(t *testing.T) {
+	settled := `{"detail-type":"BetSettled","detail":{"winner":"away@sam.com","loser":"home@sam.com","amount":10,"div":"default"}}`
+	service := &sent{}
+	handleNotify(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{{Body: settled}, {Body: "not json"}, {Body: settled}}}, service)
+
+	if len(service.calls) != 1 || len(service.calls[0]) != 4 {
+		t.Fatalf("every queued event should go to Notify in one call but got %+v", service.calls)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/main/app"
+	"sammy.link/outcome"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, outcomeService outcome.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	user := request.RequestContext.Authorizer.JWT.Claims["https://sammy.link/email"]
+
+	v := validation.New()
+	limit, cursor, paged := v.Page(request.QueryStringParameters)
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	if paged {
+		outcomes, next, err := outcomeService.GetByUserPage(ctx, user, limit, cursor)
+		if err != nil {
+			resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+			return util.ApigatewayResponse(string(resp), 400)
+		}
+
+		jsonOutcomes, _ := json.Marshal(util.Page[outcome.OutcomeItem]{Items: outcomes, Cursor: next})
+		return util.ApigatewayResponse(string(jsonOutcomes), 200)
+	}
+
+	outcomes := outcomeService.GetByUser(ctx, user)
+
+	jsonOutcomes, _ := json.Marshal(outcomes)
+
+	return util.ApigatewayResponse(string(jsonOutcomes), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Outcome)
+	}
+}
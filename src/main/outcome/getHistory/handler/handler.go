@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/history"
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+type historyResponse struct {
+	Items      []history.Item       `json:"items"`
+	Cursor     string               `json:"cursor"`
+	HeadToHead []history.RecordItem `json:"headToHead"`
+}
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, historyService history.Service, leagueService league.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	l := request.PathParameters["league"]
+
+	email, ok := authService.Authorize(ctx, request, l)
+	if !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	v := validation.New()
+	limit, cursor, _ := v.Page(request.QueryStringParameters)
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	items, cursor, err := historyService.GetHistory(ctx, l, email, limit, cursor)
+	if err != nil {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+		return util.ApigatewayResponse(string(resp), 400)
+	}
+
+	names := make(map[string]string)
+	for _, u := range leagueService.GetUsers(ctx, l) {
+		names[u.Email] = u.Name
+	}
+
+	for i := range items {
+		items[i].OpponentName = names[items[i].Opponent]
+	}
+
+	records := make(map[string]history.RecordItem)
+	for _, record := range historyService.GetRecords(ctx, l, email) {
+		records[record.Opponent] = record
+	}
+
+	resp, _ := json.Marshal(historyResponse{
+		Items:      items,
+		Cursor:     cursor,
+		HeadToHead: headToHead(l, email, names, records),
+	})
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// headToHead is email's record against everyone else in the league, including the
+// ones they haven't played yet, ordered by name.
+func headToHead(l string, email string, names map[string]string, records map[string]history.RecordItem) []history.RecordItem {
+	headToHead := make([]history.RecordItem, 0, len(names))
+	for opponent, name := range names {
+		if opponent == email {
+			continue
+		}
+		record, ok := records[opponent]
+		if !ok {
+			record = history.RecordItem{Email: email, Div: l, Opponent: opponent}
+		}
+		record.OpponentName = name
+		headToHead = append(headToHead, record)
+	}
+
+	slices.SortFunc(headToHead, func(a history.RecordItem, b history.RecordItem) int {
+		if byName := strings.Compare(a.OpponentName, b.OpponentName); byName != 0 {
+			return byName
+		}
+		return strings.Compare(a.Opponent, b.Opponent)
+	})
+	return headToHead
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.History, a.League, a.Auth)
+	}
+}
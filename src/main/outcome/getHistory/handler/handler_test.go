@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/history"
+	"sammy.link/main/app"
+)
+
+func TestGet(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{
+		PathParameters: map[string]string{
+			"league": "default",
+		},
+		QueryStringParameters: map[string]string{
+			"limit": "10",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"https://sammy.link/email": "pgreene864@gmail.com"},
+				},
+			},
+		},
+	}, a.History,
+		a.League,
+		a.Auth)
+	fmt.Printf("your boy %s", resp.Body)
+}
+
+func TestHeadToHeadOrder(t *testing.T) {
+	names := map[string]string{"sam@sam.com": "Sam", "greg@greg.com": "Greg", "pat@pat.com": "Alex", "alex@alex.com": "Alex"}
+	records := map[string]history.RecordItem{"greg@greg.com": {Opponent: "greg@greg.com", Wins: 2}}
+
+	for i := 0; i < 10; i++ {
+		records := headToHead("default", "sam@sam.com", names, records)
+		if len(records) != 3 || records[0].Opponent != "alex@alex.com" || records[1].Opponent != "pat@pat.com" || records[2].Opponent != "greg@greg.com" || records[2].Wins != 2 {
+			t.Fatalf("should be ordered by name then email but got %+v", records)
+		}
+	}
+}
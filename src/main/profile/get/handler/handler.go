@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/database"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/profile"
+	"sammy.link/user"
+	"sammy.link/util"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, profileService profile.Service, userService user.Service) (events.APIGatewayV2HTTPResponse, error) {
+	email := auth.GetEmail(request)
+
+	item, err := profileService.Get(ctx, email)
+	if errors.Is(err, database.ErrNotFound) {
+		item = profile.Default(email, userService.GetUser(ctx, email))
+	} else if err != nil {
+		logging.FromContext(ctx).Error("couldn't get profile", "err", err)
+		resp, _ := json.Marshal(util.DefaultResponse{Message: "couldn't get your profile"})
+		return util.ApigatewayResponse(string(resp), 500)
+	}
+
+	jsonProfile, _ := json.Marshal(item)
+
+	return util.ApigatewayResponse(string(jsonProfile), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.Profile, a.User)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/profile"
+	"sammy.link/user"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+func update(ctx context.Context, request events.APIGatewayV2HTTPRequest, profileService profile.Service, userService user.Service, leagueService league.Service) (events.APIGatewayV2HTTPResponse, error) {
+	var input = profile.Item{}
+	v := validation.New()
+	if v.Decode(request.Body, &input) {
+		profile.Check(v, input)
+	}
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	// the profile is always the caller's, whatever the body says
+	input.Email = auth.GetEmail(request)
+	if input.FavoriteTeams == nil {
+		input.FavoriteTeams = []string{}
+	}
+
+	memberships := userService.GetUser(ctx, input.Email)
+
+	// names from before reservations aren't reserved, see updateUsername
+	for _, membership := range memberships {
+		if membership.Name == input.DisplayName {
+			continue
+		}
+		for _, existingUser := range leagueService.GetUsers(ctx, membership.League) {
+			if existingUser.Email != input.Email && user.FoldName(existingUser.Name) == user.FoldName(input.DisplayName) {
+				return conflictResponse(fmt.Sprintf("%s is already taken in %s", input.DisplayName, membership.League))
+			}
+		}
+	}
+
+	err := profileService.Update(ctx, input, memberships)
+
+	var taken *profile.NameTakenError
+	if errors.As(err, &taken) {
+		return conflictResponse(fmt.Sprintf("%s is already taken in %s", input.DisplayName, taken.League))
+	} else if errors.Is(err, user.ErrNameChanged) {
+		return conflictResponse("one of your league names was changed by another request, try again")
+	} else if errors.Is(err, profile.ErrTooManyLeagues) {
+		return validation.BadRequest(err)
+	} else if err != nil {
+		logging.FromContext(ctx).Error("couldn't save profile", "err", err)
+		resp, _ := json.Marshal(util.DefaultResponse{Message: "couldn't save your profile"})
+		return util.ApigatewayResponse(string(resp), 500)
+	}
+
+	jsonProfile, _ := json.Marshal(input)
+
+	return util.ApigatewayResponse(string(jsonProfile), 200)
+}
+
+func conflictResponse(message string) (events.APIGatewayV2HTTPResponse, error) {
+	resp, _ := json.Marshal(util.DefaultResponse{Message: message})
+	return util.ApigatewayResponse(string(resp), 409)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return update(ctx, request, a.Profile, a.User, a.League)
+	}
+}
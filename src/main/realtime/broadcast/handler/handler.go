@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/bet"
+	"sammy.link/event"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/marketplace"
+	"sammy.link/realtime"
+)
+
+// listingUpdate is the part of a listing that changes as bids come and go.
+type listingUpdate struct {
+	EventId    string `json:"eventId"`
+	HomeAmount int64  `json:"homeAmount"`
+	AwayAmount int64  `json:"awayAmount"`
+}
+
+// message is what the domain event e is sent to subscribers as, if anything.
+func message(e events.CloudWatchEvent) (realtime.Message, bool, error) {
+	switch event.Type(e.DetailType) {
+	case event.ListingChanged:
+		var item marketplace.MarketplaceItem
+		if err := json.Unmarshal(e.Detail, &item); err != nil {
+			return realtime.Message{}, false, err
+		}
+		return realtime.Message{
+			Type:    realtime.ListingMessage,
+			Channel: realtime.Channel(realtime.EventChannel, item.Id),
+			Data:    listingUpdate{EventId: item.Id, HomeAmount: item.HomeAmount, AwayAmount: item.AwayAmount},
+		}, true, nil
+	case event.BetCreated:
+		var b bet.Bet
+		if err := json.Unmarshal(e.Detail, &b); err != nil {
+			return realtime.Message{}, false, err
+		}
+		return realtime.Message{
+			Type:    realtime.BetMatchedMessage,
+			Channel: realtime.Channel(realtime.LeagueChannel, b.Div),
+			Data:    b,
+		}, true, nil
+	}
+	return realtime.Message{}, false, nil
+}
+
+func handleBroadcast(ctx context.Context, e events.CloudWatchEvent, broadcaster *realtime.Broadcaster) error {
+	m, ok, err := message(e)
+	if err != nil {
+		// retrying won't make the detail any more readable
+		logging.FromContext(ctx).Error("unreadable event", "type", e.DetailType, "err", err)
+		return nil
+	}
+	if ok {
+		broadcaster.Broadcast(ctx, m)
+	}
+	return nil
+}
+
+// New handles the domain events the broadcast rule sends.
+func New(a *app.App) func(ctx context.Context, e events.CloudWatchEvent) error {
+	return func(ctx context.Context, e events.CloudWatchEvent) error {
+		return handleBroadcast(app.WithInvocation(ctx), e, a.Broadcaster)
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/realtime"
+)
+
+func TestMessage(t *testing.T) {
+	listing := events.CloudWatchEvent{DetailType: "ListingChanged", Detail: json.RawMessage(`{"id":"401547417","homeAmount":25,"awayAmount":10,"homeTeam":"Chiefs"}`)}
+	m, ok, err := message(listing)
+	if err != nil || !ok || m.Type != realtime.ListingMessage || m.Channel != "event:401547417" {
+		t.Fatalf("message() = %+v, %t, %v", m, ok, err)
+	}
+	if update := m.Data.(listingUpdate); update.HomeAmount != 25 || update.AwayAmount != 10 {
+		t.Errorf("data = %+v", update)
+	}
+
+	matched := events.CloudWatchEvent{DetailType: "BetCreated", Detail: json.RawMessage(`{"div":"default","amount":10}`)}
+	if m, ok, _ := message(matched); !ok || m.Type != realtime.BetMatchedMessage || m.Channel != "league:default" {
+		t.Errorf("message() = %+v", m)
+	}
+
+	if _, ok, err := message(events.CloudWatchEvent{DetailType: "BalanceChanged", Detail: json.RawMessage(`{}`)}); ok || err != nil {
+		t.Error("only listing and bet changes go out to subscribers")
+	}
+	if _, _, err := message(events.CloudWatchEvent{DetailType: "BetCreated", Detail: json.RawMessage(`[]`)}); err == nil {
+		t.Error("a detail that isn't a bet should be reported")
+	}
+}
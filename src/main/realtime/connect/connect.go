@@ -0,0 +1,13 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"sammy.link/main/app"
+	"sammy.link/main/realtime/connect/handler"
+)
+
+func main() {
+	lambda.Start(app.LoggedWebsocket(handler.New(app.Must(app.New(context.Background(), app.ConfigFromEnv())))))
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/main/app"
+	"sammy.link/realtime"
+	"sammy.link/util"
+)
+
+// handleConnect registers the connection under the member its ticket was issued to.
+// Anything but a 200 refuses the connection.
+func handleConnect(ctx context.Context, request events.APIGatewayWebsocketProxyRequest, realtimeService realtime.Service, secret []byte, now time.Time) (events.APIGatewayProxyResponse, error) {
+	email, err := realtime.ReadTicket(secret, request.QueryStringParameters["ticket"], now)
+	if err != nil {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: err.Error()})
+		return util.WebsocketResponse(string(resp), 401)
+	}
+
+	realtimeService.Connect(ctx, realtime.Connection{
+		Id:          request.RequestContext.ConnectionID,
+		Email:       email,
+		ConnectedAt: now,
+	})
+
+	return util.WebsocketResponse("", 200)
+}
+
+// New serves the route with the services in a.
+func New(a *app.App) app.WebsocketHandler {
+	return func(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return handleConnect(ctx, request, a.Realtime, []byte(a.Config.TicketSecret), a.Now())
+	}
+}
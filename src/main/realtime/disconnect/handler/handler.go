@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/main/app"
+	"sammy.link/realtime"
+	"sammy.link/util"
+)
+
+func handleDisconnect(ctx context.Context, request events.APIGatewayWebsocketProxyRequest, realtimeService realtime.Service) (events.APIGatewayProxyResponse, error) {
+	realtimeService.Disconnect(ctx, request.RequestContext.ConnectionID)
+
+	return util.WebsocketResponse("", 200)
+}
+
+// New serves the route with the services in a.
+func New(a *app.App) app.WebsocketHandler {
+	return func(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return handleDisconnect(ctx, request, a.Realtime)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/database"
+	"sammy.link/main/app"
+	"sammy.link/realtime"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+type subscribeRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+}
+
+type subscribeResponse struct {
+	Channels []string `json:"channels"`
+}
+
+func handleSubscribe(ctx context.Context, request events.APIGatewayWebsocketProxyRequest, realtimeService realtime.Service, authService auth.Service) (events.APIGatewayProxyResponse, error) {
+	var input subscribeRequest
+	v := validation.New()
+	if v.Decode(request.Body, &input) {
+		realtime.CheckChannels(v, input.Channels)
+	}
+	if !v.Valid() {
+		resp, _ := validation.BadRequest(v.Err())
+		return util.WebsocketResponse(resp.Body, resp.StatusCode)
+	}
+
+	connection, err := realtimeService.Get(ctx, request.RequestContext.ConnectionID)
+	if errors.Is(err, database.ErrNotFound) {
+		return messageResponse("connect again, this connection has expired", 410)
+	} else if err != nil {
+		return messageResponse("couldn't subscribe", 500)
+	}
+
+	// listings are public, bets matched in a league are only for its members
+	for _, channel := range input.Channels {
+		kind, league, _ := realtime.ParseChannel(channel)
+		if kind == realtime.LeagueChannel && !authService.IsMember(ctx, connection.Email, league) {
+			return messageResponse(fmt.Sprintf("you aren't a member of %s", league), 403)
+		}
+	}
+
+	connection, err = realtimeService.Subscribe(ctx, connection, input.Channels)
+	if errors.Is(err, realtime.ErrTooManyChannels) {
+		return messageResponse(err.Error(), 400)
+	}
+
+	resp, _ := json.Marshal(subscribeResponse{Channels: connection.Channels})
+	return util.WebsocketResponse(string(resp), 200)
+}
+
+func messageResponse(message string, statusCode int) (events.APIGatewayProxyResponse, error) {
+	resp, _ := json.Marshal(util.DefaultResponse{Message: message})
+	return util.WebsocketResponse(string(resp), statusCode)
+}
+
+// New serves the route with the services in a.
+func New(a *app.App) app.WebsocketHandler {
+	return func(ctx context.Context, request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return handleSubscribe(ctx, request, a.Realtime, a.Auth)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/auth"
+	"sammy.link/database"
+	"sammy.link/realtime"
+)
+
+type registry struct {
+	realtime.Service
+	connections map[string]realtime.Connection
+	subscribed  []string
+}
+
+func (r *registry) Get(ctx context.Context, connectionId string) (realtime.Connection, error) {
+	connection, ok := r.connections[connectionId]
+	if !ok {
+		return connection, database.ErrNotFound
+	}
+	return connection, nil
+}
+
+func (r *registry) Subscribe(ctx context.Context, connection realtime.Connection, channels []string) (realtime.Connection, error) {
+	r.subscribed = append(r.subscribed, channels...)
+	connection.Channels = append(connection.Channels, channels...)
+	return connection, nil
+}
+
+type members struct {
+	auth.Service
+}
+
+func (members) IsMember(ctx context.Context, email string, league string) bool {
+	return league == "default"
+}
+
+func subscribe(connectionId string, body string) events.APIGatewayWebsocketProxyRequest {
+	return events.APIGatewayWebsocketProxyRequest{
+		Body:           body,
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{ConnectionID: connectionId},
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	r := &registry{connections: map[string]realtime.Connection{
+		"abc": {Id: "abc", Email: "sam@sam.com", Channels: []string{"event:1"}},
+	}}
+
+	tests := []struct {
+		request events.APIGatewayWebsocketProxyRequest
+		status  int
+	}{
+		{subscribe("abc", `{"action":"subscribe","channels":["bets:default"]}`), 400},
+		{subscribe("gone", `{"action":"subscribe","channels":["league:default"]}`), 410},
+		{subscribe("abc", `{"action":"subscribe","channels":["event:2","league:other"]}`), 403},
+		{subscribe("abc", `{"action":"subscribe","channels":["event:2","league:default"]}`), 200},
+	}
+	for _, test := range tests {
+		resp, _ := handleSubscribe(context.TODO(), test.request, r, members{})
+		if resp.StatusCode != test.status {
+			t.Errorf("%s answered %d: %s", test.request.Body, resp.StatusCode, resp.Body)
+		}
+	}
+
+	if !slices.Equal(r.subscribed, []string{"event:2", "league:default"}) {
+		t.Errorf("subscribed to %v", r.subscribed)
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/main/app"
+	"sammy.link/realtime"
+	"sammy.link/util"
+)
+
+type ticketResponse struct {
+	Ticket  string    `json:"ticket"`
+	Expires time.Time `json:"expires"`
+}
+
+func handleTicket(request events.APIGatewayV2HTTPRequest, secret []byte, now time.Time) (events.APIGatewayV2HTTPResponse, error) {
+	resp, _ := json.Marshal(ticketResponse{
+		Ticket:  realtime.IssueTicket(secret, auth.GetEmail(request), now),
+		Expires: now.Add(realtime.TicketTtl),
+	})
+
+	return util.ApigatewayResponse(string(resp), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleTicket(request, []byte(a.Config.TicketSecret), a.Now())
+	}
+}
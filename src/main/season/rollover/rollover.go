@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/season"
+)
+
+func main() {
+	a := app.Must(app.New(context.Background(), app.ConfigFromEnv()))
+	lambda.Start(
+		func(ctx context.Context) {
+			handler(app.WithInvocation(ctx), a.Now(), a.League, a.Season)
+		})
+}
+
+func handler(ctx context.Context, now time.Time, leagueService league.Service, seasonService season.Service) {
+	var waitGroup sync.WaitGroup
+
+	for _, l := range leagueService.GetLeagues(ctx) {
+		waitGroup.Add(1)
+		go func(myLeague league.LeagueItem) {
+			defer waitGroup.Done()
+			rollover(ctx, now, myLeague, leagueService, seasonService)
+		}(l)
+	}
+
+	waitGroup.Wait()
+}
+
+func rollover(ctx context.Context, now time.Time, l league.LeagueItem, leagueService league.Service, seasonService season.Service) {
+	seasons := seasonService.GetSeasons(ctx, l.Name)
+
+	if len(seasons) == 0 {
+		seasonService.Create(ctx, season.New(l.Name, now))
+		return
+	}
+
+	for _, s := range seasons {
+		if !s.Archived && !now.Before(s.End) {
+			closeSeason(ctx, l, s, leagueService, seasonService)
+		}
+	}
+
+	latest := seasons[len(seasons)-1]
+	for !now.Before(latest.End) {
+		latest = season.Next(latest)
+		logging.FromContext(ctx).Info("starting season", "season", latest.Id, logging.LeagueKey, l.Name)
+		seasonService.Create(ctx, latest)
+	}
+}
+
+// closeSeason stops the season's bets being paid into the balances, stores every
+// member's final standing, resets their balances to the bankroll and only then
+// archives the season. Each step can be run again, so a close that fails partway is
+// finished by the next rollover.
+func closeSeason(ctx context.Context, l league.LeagueItem, s season.Item, leagueService league.Service, seasonService season.Service) {
+	if err := seasonService.Close(ctx, s); err != nil {
+		logging.FromContext(ctx).Error("couldn't close the season", "season", s.Id, logging.LeagueKey, l.Name, "err", err)
+		return
+	}
+
+	// read after closing, so every bet paid into a balance is counted
+	users := leagueService.GetUsers(ctx, l.Name)
+
+	standings := make([]season.StandingItem, len(users))
+	for i, u := range users {
+		standings[i] = season.StandingItem{
+			League: l.Name,
+			Season: s.Id,
+			Email:  u.Email,
+			Name:   u.Name,
+			Total:  u.Total,
+		}
+	}
+	standings = season.Rank(standings)
+
+	logging.FromContext(ctx).Info("closing season", "season", s.Id, logging.LeagueKey, l.Name, "users", len(users))
+	if !each(standings, func(standing season.StandingItem) error {
+		return seasonService.Snapshot(ctx, standing)
+	}) {
+		logging.FromContext(ctx).Error("couldn't store the standings", "season", s.Id, logging.LeagueKey, l.Name)
+		return
+	}
+
+	if !each(users, func(u league.UserInLeagueItem) error {
+		return leagueService.SetUserAmount(ctx, l.Name, u.Email, l.Bankroll)
+	}) {
+		logging.FromContext(ctx).Error("couldn't reset the balances", "season", s.Id, logging.LeagueKey, l.Name)
+		return
+	}
+
+	seasonService.Archive(ctx, s)
+}
+
+// each runs write on every item at once and reports whether they all succeeded.
+func each[T any](items []T, write func(T) error) bool {
+	var failed atomic.Bool
+	var waitGroup sync.WaitGroup
+	for _, item := range items {
+		waitGroup.Add(1)
+		go func(myItem T) {
+			defer waitGroup.Done()
+			if err := write(myItem); err != nil {
+				failed.Store(true)
+			}
+		}(item)
+	}
+	waitGroup.Wait()
+	return !failed.Load()
+}
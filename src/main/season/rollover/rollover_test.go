@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/season"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
+	handler(ctx, a.Now(), a.League,
+		a.Season)
+}
+
+// members keeps balances by email, failing to reset the ones in broken.
+type members struct {
+	league.Service
+	balances map[string]int64
+	broken   map[string]bool
+}
+
+func (m *members) GetUsers(ctx context.Context, l string) []league.UserInLeagueItem {
+	users := make([]league.UserInLeagueItem, 0, len(m.balances))
+	for email, total := range m.balances {
+		users = append(users, league.UserInLeagueItem{League: l, Email: email, Total: total})
+	}
+	return users
+}
+
+func (m *members) SetUserAmount(ctx context.Context, l string, email string, amount int64) error {
+	if m.broken[email] {
+		return errors.New("throttled")
+	}
+	m.balances[email] = amount
+	return nil
+}
+
+type seasons struct {
+	season.Service
+	standings map[string]season.StandingItem
+	archived  []season.Item
+}
+
+func (s *seasons) Close(ctx context.Context, item season.Item) error {
+	return nil
+}
+
+func (s *seasons) Snapshot(ctx context.Context, standing season.StandingItem) error {
+	if _, ok := s.standings[standing.Email]; !ok {
+		s.standings[standing.Email] = standing
+	}
+	return nil
+}
+
+func (s *seasons) Archive(ctx context.Context, item season.Item) {
+	s.archived = append(s.archived, item)
+}
+
+func TestCloseSeasonFinishesAPartialClose(t *testing.T) {
+	ctx := context.TODO()
+	l := league.LeagueItem{Name: "default", Bankroll: 1000}
+	s := season.New(l.Name, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC))
+	leagueService := &members{balances: map[string]int64{"sam@sam.com": 1040, "greg@greg.com": 980}, broken: map[string]bool{"greg@greg.com": true}}
+	seasonService := &seasons{standings: map[string]season.StandingItem{}}
+
+	closeSeason(ctx, l, s, leagueService, seasonService)
+	if len(seasonService.archived) != 0 {
+		t.Fatal("a season shouldn't be archived until every balance is reset")
+	}
+
+	leagueService.broken = nil
+	closeSeason(ctx, l, s, leagueService, seasonService)
+	if len(seasonService.archived) != 1 || leagueService.balances["greg@greg.com"] != 1000 {
+		t.Fatalf("the next rollover should finish the close but got %v", leagueService.balances)
+	}
+	if sam := seasonService.standings["sam@sam.com"]; sam.Total != 1040 || sam.Rank != 1 {
+		t.Fatalf("the standings should keep the balances from before the reset but got %+v", sam)
+	}
+}
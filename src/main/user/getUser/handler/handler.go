@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/league"
+	"sammy.link/main/app"
+	"sammy.link/user"
+	"sammy.link/util"
+)
+
+func handleGet(ctx context.Context, request events.APIGatewayV2HTTPRequest, userService user.Service, leagueService league.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	email := request.RequestContext.Authorizer.JWT.Claims["https://sammy.link/email"]
+	resp := userService.GetUser(ctx, email)
+
+	if len(resp) < 1 {
+		newUser := user.Item{
+			Email:  email,
+			League: "default",
+			Name:   "",
+		}
+		userService.Create(ctx, newUser)
+		leagueService.AddUser(ctx, league.UserInLeagueItem{
+			Email:  email,
+			Name:   "",
+			League: "default",
+			Total:  0,
+		},
+		)
+		resp = []user.Item{newUser}
+	}
+
+	jsonUser, _ := json.Marshal(resp)
+
+	return util.ApigatewayResponse(string(jsonUser), 200)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return handleGet(ctx, request, a.User, a.League)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package handler
 
 import (
 	"context"
@@ -6,21 +6,19 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	"sammy.link/database"
-	"sammy.link/league"
-	"sammy.link/user"
+	"sammy.link/main/app"
 )
 
 func TestCreate(t *testing.T) {
 	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
 	resp, _ := handleGet(ctx, events.APIGatewayV2HTTPRequest{RequestContext: events.APIGatewayV2HTTPRequestContext{
 		Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
 			Claims: map[string]string{
 				"https://sammy.link/email": "pgreene864@gmail.com",
 			},
 		}}},
-	}, user.NewService(database.GetDatabaseService[user.DynamoItem, user.Item](ctx)),
-		league.NewService(database.GetDatabaseService[league.LeagueDynamoItem, league.LeagueItem](ctx),
-			database.GetDatabaseService[league.UserInLeagueDynamoItem, league.UserInLeagueItem](ctx)))
+	}, a.User,
+		a.League)
 	fmt.Printf("your boy %s", resp.Body)
 }
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"sammy.link/auth"
+	"sammy.link/league"
+	"sammy.link/logging"
+	"sammy.link/main/app"
+	"sammy.link/user"
+	"sammy.link/util"
+	"sammy.link/validation"
+)
+
+type Input struct {
+	Name string `json:"name"`
+	Div  string `json:"div"`
+}
+
+func update(ctx context.Context, request events.APIGatewayV2HTTPRequest, userService user.Service, leagueService league.Service, authService auth.Service) (events.APIGatewayV2HTTPResponse, error) {
+
+	var input = Input{}
+	v := validation.New()
+	if v.Decode(request.Body, &input) {
+		v.String("div", input.Div).Required()
+		user.CheckName(v, "name", input.Name)
+	}
+	if !v.Valid() {
+		return validation.BadRequest(v.Err())
+	}
+
+	ctx = logging.With(ctx, logging.LeagueKey, input.Div)
+	email, ok := authService.Authorize(ctx, request, input.Div)
+	if !ok {
+		return auth.ForbiddenResponse()
+	}
+
+	// names from before reservations aren't reserved, but they only change through a
+	// rename, which reserves the new one
+	for _, existingUser := range leagueService.GetUsers(ctx, input.Div) {
+		if existingUser.Email != email && user.FoldName(existingUser.Name) == user.FoldName(input.Name) {
+			return conflictResponse(fmt.Sprintf("%s is already taken in this league", input.Name))
+		}
+	}
+
+	err := userService.Rename(ctx, user.Item{
+		Email:  email,
+		Name:   input.Name,
+		League: input.Div,
+	}, currentName(userService.GetUser(ctx, email), input.Div))
+
+	if errors.Is(err, user.ErrNameTaken) {
+		return conflictResponse(fmt.Sprintf("%s is already taken in this league", input.Name))
+	} else if errors.Is(err, user.ErrNotMember) {
+		resp, _ := json.Marshal(util.DefaultResponse{Message: fmt.Sprintf("you aren't a member of %s", input.Div)})
+		return util.ApigatewayResponse(string(resp), 404)
+	} else if errors.Is(err, user.ErrNameChanged) {
+		return conflictResponse("your name was changed by another request, try again")
+	} else if err != nil {
+		logging.FromContext(ctx).Error("rename failed", "err", err)
+		resp, _ := json.Marshal(util.DefaultResponse{Message: "couldn't change your name"})
+		return util.ApigatewayResponse(string(resp), 500)
+	}
+
+	jsonUser, _ := json.Marshal(map[string]string{
+		"message": "success",
+	})
+
+	return util.ApigatewayResponse(string(jsonUser), 200)
+}
+
+func currentName(items []user.Item, league string) string {
+	for _, item := range items {
+		if item.League == league {
+			return item.Name
+		}
+	}
+	return ""
+}
+
+func conflictResponse(message string) (events.APIGatewayV2HTTPResponse, error) {
+	resp, _ := json.Marshal(util.DefaultResponse{Message: message})
+	return util.ApigatewayResponse(string(resp), 409)
+}
+
+// New serves the endpoint with the services in a.
+func New(a *app.App) app.Handler {
+	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		return update(ctx, request, a.User, a.League, a.Auth)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package handler
 
 import (
 	"context"
@@ -6,13 +6,12 @@ import (
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
-	"sammy.link/database"
-	"sammy.link/league"
-	"sammy.link/user"
+	"sammy.link/main/app"
 )
 
 func TestUpdate(t *testing.T) {
 	ctx := context.TODO()
+	a := app.Must(app.New(ctx, app.ConfigFromEnv()))
 	resp, _ := update(ctx, events.APIGatewayV2HTTPRequest{
 		Body: "{\"name\": \"samg\", \"div\": \"default\"}",
 		RequestContext: events.APIGatewayV2HTTPRequestContext{
@@ -21,8 +20,8 @@ func TestUpdate(t *testing.T) {
 					"https://sammy.link/email": "pgreene864@gmail.com.com",
 				},
 			}}},
-	}, user.NewService(database.GetDatabaseService[user.DynamoItem, user.Item](ctx)),
-		league.NewService(database.GetDatabaseService[league.LeagueDynamoItem, league.LeagueItem](ctx),
-			database.GetDatabaseService[league.UserInLeagueDynamoItem, league.UserInLeagueItem](ctx)))
+	}, a.User,
+		a.League,
+		a.Auth)
 	fmt.Printf("your boy %s", resp.Body)
 }
@@ -3,9 +3,10 @@ package marketplace
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,6 +14,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"sammy.link/bid"
 	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/key"
+	"sammy.link/sport"
+	"sammy.link/util"
+	"sammy.link/validation"
 )
 
 type MarketplaceItem struct {
@@ -34,30 +40,59 @@ type MarketplaceItem struct {
 type MarketplaceDynamoDbItem struct {
 	Id               string `dynamodbav:"id"`
 	SortKey          string `dynamodbav:"sortKey"`
+	Gsi1_id          string `dynamodbav:"gsi1_id"`
+	Gsi1_sortKey     string `dynamodbav:"gsi1_sortKey"`
+	Gsi3_id          string `dynamodbav:"gsi3_id,omitempty"`
+	Gsi3_sortKey     string `dynamodbav:"gsi3_sortKey,omitempty"`
+	Kind             string `dynamodbav:"kind"`
+	Teams            string `dynamodbav:"teams"`
 	Spread           string `dynamodbav:"spread"`
 	Ttl              int64  `dynamodbav:"ttl"`
-	AwayAbbreviation string `dynamodbav:"awayAb"`
-	HomeAbbreviation string `dynamodbav:"homeAb"`
+	AwayAbbreviation string `dynamodbav:"awayAbbreviation"`
+	HomeAbbreviation string `dynamodbav:"homeAbbreviation"`
 	AwayRecord       string `dynamodbav:"aR"`
 	HomeRecord       string `dynamodbav:"hR"`
 	EventId          string `dynamodbav:"eId"`
 	HomeAmount       int64  `dynamodbav:"homeAmount"`
 	AwayAmount       int64  `dynamodbav:"awayAmount"`
 	Week             int    `dynamodbav:"week"`
+	Date             string `dynamodbav:"date"`
+	Version          int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyAwayAbbreviation string `dynamodbav:"awayAb,omitempty"`
+	LegacyHomeAbbreviation string `dynamodbav:"homeAb,omitempty"`
+}
+
+// Filter narrows a marketplace listing. Zero values mean no filter, and a Limit of
+// zero reads every match.
+type Filter struct {
+	Kind   string
+	Kinds  []string
+	Week   int
+	From   time.Time
+	To     time.Time
+	Team   string
+	Open   bool
+	Limit  int32
+	Cursor string
 }
 
 type Service interface {
 	GetItems(ctx context.Context) []MarketplaceItem
+	GetPage(ctx context.Context, filter Filter) ([]MarketplaceItem, string, error)
+	GetByEventId(ctx context.Context, eventId string) (MarketplaceItem, bool)
 	ModifyAmount(ctx context.Context, bid bid.Bid)
 	Write(ctx context.Context, items []MarketplaceItem)
 }
 type MarketplaceService struct {
 	databaseService database.Service[MarketplaceDynamoDbItem, MarketplaceItem]
+	publisher       event.Publisher
 }
 
-func NewService(databaseService database.Service[MarketplaceDynamoDbItem, MarketplaceItem]) Service {
+func NewService(databaseService database.Service[MarketplaceDynamoDbItem, MarketplaceItem], publisher event.Publisher) Service {
 	return &MarketplaceService{
 		databaseService: databaseService,
+		publisher:       publisher,
 	}
 }
 
@@ -67,8 +102,14 @@ func (item MarketplaceDynamoDbItem) String() string {
 
 func (item MarketplaceItem) GetDynamoItem() database.DynamoItem {
 	return MarketplaceDynamoDbItem{
-		Id:               "MK",
-		SortKey:          BuildMarketplaceDynamoId(item.Kind, item.Date, item.AwayTeam, item.HomeTeam),
+		Id:               BuildId(item.Kind),
+		SortKey:          BuildSortKey(item.Date, item.AwayTeam, item.HomeTeam),
+		Gsi1_id:          "MK",
+		Gsi1_sortKey:     gsi1SortKeyCodec.Encode(key.Time(item.Date), item.Kind, item.AwayTeam, item.HomeTeam),
+		Gsi3_id:          buildEventId(item.Id),
+		Gsi3_sortKey:     buildEventSortKey(item.Id),
+		Kind:             item.Kind,
+		Teams:            strings.ToLower(key.Join(item.AwayTeam, item.HomeTeam, item.AwayAbbreviation, item.HomeAbbreviation)),
 		Spread:           item.Spread,
 		Ttl:              item.Date.AddDate(0, 0, 1).Unix(),
 		HomeAmount:       item.HomeAmount,
@@ -79,28 +120,53 @@ func (item MarketplaceItem) GetDynamoItem() database.DynamoItem {
 		AwayRecord:       item.AwayRecord,
 		HomeRecord:       item.HomeRecord,
 		EventId:          item.Id,
+		Date:             key.Time(item.Date),
+		Version:          database.Version,
 	}
 }
 
+func (item MarketplaceDynamoDbItem) upgrade() MarketplaceDynamoDbItem {
+	if item.Version < 1 {
+		item.AwayAbbreviation = util.Coalesce(item.AwayAbbreviation, item.LegacyAwayAbbreviation)
+		item.HomeAbbreviation = util.Coalesce(item.HomeAbbreviation, item.LegacyHomeAbbreviation)
+
+		// listings used to share a single "MK" partition keyed by kind|date|away|home
+		if item.Id == "MK" {
+			if parts := key.Split(item.SortKey); len(parts) == 4 {
+				item.Id = BuildId(parts[0])
+				item.SortKey = sortKeyCodec.Encode(parts[1], parts[2], parts[3])
+			}
+		}
+	}
+	return item
+}
+
 func (item MarketplaceDynamoDbItem) GetItem() database.Item {
-	expression := regexp.MustCompile(`(?P<Kind>[^|]+)\|(?P<Date>[^|]+)\|(?P<AwayTeam>[^|]+)\|(?P<HomeTeam>[^|]+)`)
+	item = item.upgrade()
 
-	match := expression.FindStringSubmatch(item.SortKey)
+	ids, err := idCodec.Decode(item.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
 
-	paramsMap := make(map[string]string)
-	for i, name := range expression.SubexpNames() {
-		if i > 0 && i <= len(match) {
-			paramsMap[name] = match[i]
-		}
+	sortKeys, err := sortKeyCodec.Decode(item.SortKey)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		sortKeys = make([]string, sortKeyCodec.Parts)
 	}
 
-	date, _ := time.Parse(time.RFC3339, paramsMap["Date"])
+	// listings written before the date attribute only have it in the sort key
+	date, err := key.ParseTime(item.Date)
+	if err != nil {
+		date, _ = key.ParseTime(sortKeys[0])
+	}
 
 	return MarketplaceItem{
-		AwayTeam:         paramsMap["AwayTeam"],
-		HomeTeam:         paramsMap["HomeTeam"],
+		AwayTeam:         sortKeys[1],
+		HomeTeam:         sortKeys[2],
 		Date:             date,
-		Kind:             paramsMap["Kind"],
+		Kind:             ids[0],
 		Spread:           item.Spread,
 		HomeAmount:       item.HomeAmount,
 		AwayAmount:       item.AwayAmount,
@@ -113,20 +179,173 @@ func (item MarketplaceDynamoDbItem) GetItem() database.Item {
 	}
 }
 
+var (
+	idCodec          = key.Codec{Prefix: "MK", Parts: 1}
+	sortKeyCodec     = key.Codec{Parts: 3}
+	gsi1SortKeyCodec = key.Codec{Parts: 4}
+	eventCodec       = key.Codec{Prefix: "EVENT", Parts: 1}
+)
+
+// buildEventId puts a listing on gsi3 under its ESPN event id, which is how bids
+// find it. Listings without one stay off the index.
+func buildEventId(eventId string) string {
+	if eventId == "" {
+		return ""
+	}
+	return eventCodec.Encode(eventId)
+}
+
+func buildEventSortKey(eventId string) string {
+	if eventId == "" {
+		return ""
+	}
+	return "MK"
+}
+
+// BuildId partitions the marketplace by kind so a kind and date range can be read
+// without touching the other sports. Every listing is also on gsi1 under "MK",
+// ordered by kickoff.
+func BuildId(kind string) string {
+	return idCodec.Encode(kind)
+}
+
+func BuildSortKey(date time.Time, awayTeam string, homeTeam string) string {
+	return sortKeyCodec.Encode(key.Time(date), awayTeam, homeTeam)
+}
+
 func BuildMarketplaceDynamoId(kind string, date time.Time, awayTeam string, homeTeam string) string {
-	return fmt.Sprintf("%s|%s|%s|%s", kind, date.Format(time.RFC3339), awayTeam, homeTeam)
+	return bid.GetEventKey(kind, date, awayTeam, homeTeam)
 }
 
 func (s *MarketplaceService) GetItems(ctx context.Context) []MarketplaceItem {
 	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(os.Getenv("TABLE_NAME")),
-		KeyConditionExpression: aws.String("id = :id"),
+		KeyConditionExpression: aws.String("gsi1_id = :id"),
+		IndexName:              aws.String("gsi1"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":id": &types.AttributeValueMemberS{Value: "MK"},
 		},
 	})
 }
 
+// GetPage returns listings matching filter in kickoff order, along with the cursor
+// for the next page.
+func (s *MarketplaceService) GetPage(ctx context.Context, filter Filter) ([]MarketplaceItem, string, error) {
+	return s.databaseService.QueryPaged(ctx, buildQuery(filter), filter.Limit, filter.Cursor)
+}
+
+func buildQuery(filter Filter) *dynamodb.QueryInput {
+	from := key.Time(filter.From)
+	// "~" sorts after the "|" that follows the date, so games at exactly To are kept
+	to := "~"
+	if !filter.To.IsZero() {
+		to = key.Time(filter.To) + "~"
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("gsi1_id = :id and gsi1_sortKey between :from and :to"),
+		IndexName:              aws.String("gsi1"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":   &types.AttributeValueMemberS{Value: "MK"},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+	}
+
+	if filter.Kind != "" {
+		input.KeyConditionExpression = aws.String("id = :id and sortKey between :from and :to")
+		input.IndexName = nil
+		input.ExpressionAttributeValues[":id"] = &types.AttributeValueMemberS{Value: BuildId(filter.Kind)}
+	}
+
+	filters := make([]string, 0)
+	if filter.Week > 0 {
+		filters = append(filters, "week = :week")
+		input.ExpressionAttributeValues[":week"] = &types.AttributeValueMemberN{Value: strconv.Itoa(filter.Week)}
+	}
+
+	if len(filter.Kinds) > 0 {
+		names := make([]string, len(filter.Kinds))
+		for i, kind := range filter.Kinds {
+			names[i] = fmt.Sprintf(":kind%d", i)
+			input.ExpressionAttributeValues[names[i]] = &types.AttributeValueMemberS{Value: kind}
+		}
+		filters = append(filters, fmt.Sprintf("#kind IN (%s)", strings.Join(names, ", ")))
+		input.ExpressionAttributeNames = map[string]string{"#kind": "kind"}
+	}
+
+	if filter.Team != "" {
+		filters = append(filters, "contains(teams, :team)")
+		input.ExpressionAttributeValues[":team"] = &types.AttributeValueMemberS{Value: strings.ToLower(filter.Team)}
+	}
+
+	if filter.Open {
+		filters = append(filters, "(awayAmount > :zero or homeAmount > :zero)")
+		input.ExpressionAttributeValues[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+	}
+
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " and "))
+	}
+
+	return input
+}
+
+func (s *MarketplaceService) GetByEventId(ctx context.Context, eventId string) (MarketplaceItem, bool) {
+	if eventId == "" {
+		return MarketplaceItem{}, false
+	}
+	items := s.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("gsi3_id = :id and gsi3_sortKey = :sortKey"),
+		IndexName:              aws.String("gsi3"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":      &types.AttributeValueMemberS{Value: buildEventId(eventId)},
+			":sortKey": &types.AttributeValueMemberS{Value: buildEventSortKey(eventId)},
+		},
+	})
+
+	if len(items) == 0 {
+		return MarketplaceItem{}, false
+	}
+	return items[0], true
+}
+
+// CheckListing makes sure the game an order is on is listed, which found says, and
+// still taking bids. Bids close at kickoff.
+func CheckListing(v *validation.Validator, item MarketplaceItem, found bool, now time.Time) bool {
+	if !v.Check(found, "eventId", "is not in the marketplace") {
+		return false
+	}
+	v.Check(item.Spread != "", "eventId", "has no line yet")
+	v.Check(item.Date.After(now), "eventId", "has kicked off, bidding is closed")
+	return v.Valid()
+}
+
+// NewBid is the bid order places on item, with everything but the side and amount
+// taken from the listing.
+func NewBid(item MarketplaceItem, order bid.Order) bid.Bid {
+	chosen := item.HomeTeam
+	if order.Side == sport.Away {
+		chosen = item.AwayTeam
+	}
+
+	return bid.Bid{
+		Kind:             item.Kind,
+		AwayTeam:         item.AwayTeam,
+		HomeTeam:         item.HomeTeam,
+		ChosenCompetitor: chosen,
+		Spread:           item.Spread,
+		Amount:           order.Amount,
+		Date:             item.Date,
+		Week:             item.Week,
+		HomeAbbreviation: item.HomeAbbreviation,
+		AwayAbbreviation: item.AwayAbbreviation,
+		Div:              order.Div,
+	}
+}
+
 func (s *MarketplaceService) ModifyAmount(ctx context.Context, bid bid.Bid) {
 
 	var updateExpression *string
@@ -143,14 +362,33 @@ func (s *MarketplaceService) ModifyAmount(ctx context.Context, bid bid.Bid) {
 		},
 		TableName: aws.String(os.Getenv("TABLE_NAME")),
 		Key: map[string]types.AttributeValue{
-			"id":      &types.AttributeValueMemberS{Value: "MK"},
-			"sortKey": &types.AttributeValueMemberS{Value: BuildMarketplaceDynamoId(bid.Kind, bid.Date, bid.AwayTeam, bid.HomeTeam)},
+			"id":      &types.AttributeValueMemberS{Value: BuildId(bid.Kind)},
+			"sortKey": &types.AttributeValueMemberS{Value: BuildSortKey(bid.Date, bid.AwayTeam, bid.HomeTeam)},
 		},
-		UpdateExpression: updateExpression,
+		// an update would otherwise create a listing with nothing but amounts
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		UpdateExpression:    updateExpression,
 	}
 
-	s.databaseService.UpdateItem(ctx, input)
+	updated, err := s.databaseService.UpdateReturning(ctx, input)
+	if database.ConditionFailed(err) {
+		// listings from before version 1 stay in the shared "MK" partition until the
+		// migrate command moves them
+		input.Key = legacyKey(bid)
+		updated, err = s.databaseService.UpdateReturning(ctx, input)
+	}
+	if err != nil {
+		return
+	}
+
+	event.Emit(ctx, s.publisher, event.Event{Type: event.ListingChanged, Detail: updated})
+}
 
+func legacyKey(bid bid.Bid) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: "MK"},
+		"sortKey": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s|%s|%s|%s", bid.Kind, key.Time(bid.Date), bid.AwayTeam, bid.HomeTeam)},
+	}
 }
 
 func (s *MarketplaceService) Write(ctx context.Context, items []MarketplaceItem) {
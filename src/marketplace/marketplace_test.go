@@ -0,0 +1,217 @@
+package marketplace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/bid"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/sport"
+	"sammy.link/validation"
+)
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	item := MarketplaceItem{
+		AwayTeam:         "Detroit Lions",
+		HomeTeam:         "Kansas City Chiefs",
+		AwayAbbreviation: "DET",
+		HomeAbbreviation: "KC",
+		Id:               "401547353",
+		Date:             time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC),
+		Kind:             "NFL",
+		Spread:           "KC -6.5",
+		Week:             1,
+	}
+
+	dynamoItem := item.GetDynamoItem().(MarketplaceDynamoDbItem)
+	if dynamoItem.Id != "MK|NFL" || dynamoItem.SortKey != "2023-09-08T00:20:00Z|Detroit Lions|Kansas City Chiefs" || dynamoItem.Gsi1_sortKey != "2023-09-08T00:20:00Z|NFL|Detroit Lions|Kansas City Chiefs" {
+		t.Fatalf("should be keyed by kind then kickoff but got %+v", dynamoItem)
+	}
+	if dynamoItem.Gsi3_id != "EVENT|401547353" || dynamoItem.Gsi3_sortKey != "MK" {
+		t.Fatalf("should be on gsi3 by event id but got %+v", dynamoItem)
+	}
+	if unlisted := (MarketplaceItem{Kind: "NFL"}).GetDynamoItem().(MarketplaceDynamoDbItem); unlisted.Gsi3_id != "" || unlisted.Gsi3_sortKey != "" {
+		t.Fatalf("should leave listings without an event id off gsi3 but got %+v", unlisted)
+	}
+
+	if back := dynamoItem.GetItem().(MarketplaceItem); back != item {
+		t.Fatalf("should read back what was written but got %+v", back)
+	}
+}
+
+func TestDynamoItemRoundTripsAnyNames(t *testing.T) {
+	roundTrip := func(kind string, awayTeam string, homeTeam string, seconds uint32) bool {
+		item := MarketplaceItem{
+			Kind:     kind,
+			AwayTeam: awayTeam,
+			HomeTeam: homeTeam,
+			Date:     time.Unix(int64(seconds), 0).UTC(),
+		}
+		return item.GetDynamoItem().(MarketplaceDynamoDbItem).GetItem() == item
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	from := time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC)
+
+	all := buildQuery(Filter{From: from})
+	if *all.IndexName != "gsi1" || all.FilterExpression != nil {
+		t.Fatalf("without a kind every sport should be read from gsi1 but got %+v", all)
+	}
+
+	byKind := buildQuery(Filter{Kind: "NFL", From: from, To: from.AddDate(0, 0, 7), Week: 1, Team: "Chiefs", Open: true})
+	if byKind.IndexName != nil || byKind.ExpressionAttributeValues[":id"].(*types.AttributeValueMemberS).Value != "MK|NFL" {
+		t.Fatalf("a kind should only read its own partition but got %+v", byKind)
+	}
+
+	if to := byKind.ExpressionAttributeValues[":to"].(*types.AttributeValueMemberS).Value; to != "2023-09-14T00:00:00Z~" {
+		t.Fatalf("should include games at the end of the range but got %s", to)
+	}
+
+	if *byKind.FilterExpression != "week = :week and contains(teams, :team) and (awayAmount > :zero or homeAmount > :zero)" {
+		t.Fatalf("unexpected filter %s", *byKind.FilterExpression)
+	}
+
+	if team := byKind.ExpressionAttributeValues[":team"].(*types.AttributeValueMemberS).Value; team != "chiefs" {
+		t.Fatalf("team search should ignore case but got %s", team)
+	}
+}
+
+func TestNewBid(t *testing.T) {
+	item := MarketplaceItem{
+		AwayTeam:         "Detroit Lions",
+		HomeTeam:         "Kansas City Chiefs",
+		AwayAbbreviation: "DET",
+		HomeAbbreviation: "KC",
+		Id:               "401547353",
+		Date:             time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC),
+		Kind:             "NFL",
+		Spread:           "KC -6.5",
+		Week:             1,
+		AwayAmount:       40,
+	}
+
+	want := bid.Bid{
+		Kind:             "NFL",
+		AwayTeam:         "Detroit Lions",
+		HomeTeam:         "Kansas City Chiefs",
+		AwayAbbreviation: "DET",
+		HomeAbbreviation: "KC",
+		ChosenCompetitor: "Detroit Lions",
+		Spread:           "KC -6.5",
+		Amount:           10,
+		Date:             item.Date,
+		Week:             1,
+		Div:              "default",
+	}
+	if got := NewBid(item, bid.Order{Div: "default", EventId: "401547353", Side: sport.Away, Amount: 10}); got != want {
+		t.Fatalf("should take everything but the side and amount from the listing but got %+v", got)
+	}
+
+	if got := NewBid(item, bid.Order{Div: "default", EventId: "401547353", Side: sport.Home, Amount: 10}); got.ChosenCompetitor != "Kansas City Chiefs" {
+		t.Fatalf("should pick the home team but got %s", got.ChosenCompetitor)
+	}
+}
+
+func TestCheckListing(t *testing.T) {
+	item := MarketplaceItem{Kind: "NFL", Spread: "KC -6.5", Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)}
+	before := item.Date.Add(-time.Hour)
+
+	if v := validation.New(); !CheckListing(v, item, true, before) {
+		t.Fatalf("a listed game before kickoff should take bids but got %v", v.Err())
+	}
+
+	noLine := item
+	noLine.Spread = ""
+	tests := []struct {
+		item  MarketplaceItem
+		found bool
+		now   time.Time
+	}{
+		{item, false, before},
+		{noLine, true, before},
+		{item, true, item.Date},
+	}
+
+	for _, test := range tests {
+		v := validation.New()
+		CheckListing(v.At("[0]"), test.item, test.found, test.now)
+
+		var errs validation.Errors
+		if !errors.As(v.Err(), &errs) || len(errs) != 1 || errs[0].Field != "[0].eventId" {
+			t.Errorf("%+v found=%t at %s should be rejected but got %v", test.item, test.found, test.now, v.Err())
+		}
+	}
+
+	order := bid.Order{Div: "", EventId: "401547353", Side: "DRAW", Amount: 101}
+	v := validation.New()
+	if bid.CheckOrder(v, order) || len(v.Err().(validation.Errors)) != 3 {
+		t.Errorf("should reject the div, side and amount but got %v", v.Err())
+	}
+}
+
+type updates struct {
+	database.Service[MarketplaceDynamoDbItem, MarketplaceItem]
+	inputs []string
+	// missing is the id no listing is stored under
+	missing string
+	err     error
+}
+
+func (u *updates) UpdateReturning(ctx context.Context, params *dynamodb.UpdateItemInput) (MarketplaceItem, error) {
+	id := params.Key["id"].(*types.AttributeValueMemberS).Value
+	u.inputs = append(u.inputs, id+" "+params.Key["sortKey"].(*types.AttributeValueMemberS).Value+" "+*params.UpdateExpression)
+	if id == u.missing {
+		return MarketplaceItem{}, &types.ConditionalCheckFailedException{}
+	}
+	return MarketplaceItem{Id: "401547353", AwayAmount: 50}, u.err
+}
+
+func TestModifyAmountPublishes(t *testing.T) {
+	db := &updates{}
+	memory := event.NewMemory(clock.System)
+	service := NewService(db, memory)
+	taken := bid.Bid{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", ChosenCompetitor: "Detroit Lions", Amount: 10}
+
+	service.ModifyAmount(context.TODO(), taken)
+
+	if db.inputs[0] != "MK|NFL 0001-01-01T00:00:00Z|Detroit Lions|Kansas City Chiefs add awayAmount :amount" {
+		t.Errorf("update = %s", db.inputs[0])
+	}
+	changed := memory.Of(event.ListingChanged)
+	if len(changed) != 1 || changed[0].Detail.(MarketplaceItem).AwayAmount != 50 {
+		t.Errorf("should publish the listing as updated but published %+v", changed)
+	}
+
+	db.err = errors.New("conditional check failed")
+	service.ModifyAmount(context.TODO(), taken)
+	if len(memory.Of(event.ListingChanged)) != 1 {
+		t.Error("a failed update shouldn't publish anything")
+	}
+}
+
+func TestModifyAmountFindsLegacyListings(t *testing.T) {
+	db := &updates{missing: "MK|NFL"}
+	memory := event.NewMemory(clock.System)
+	taken := bid.Bid{Kind: "NFL", AwayTeam: "Detroit Lions", HomeTeam: "Kansas City Chiefs", ChosenCompetitor: "Kansas City Chiefs", Amount: 10, Date: time.Date(2023, time.September, 8, 0, 20, 0, 0, time.UTC)}
+
+	NewService(db, memory).ModifyAmount(context.TODO(), taken)
+
+	if len(db.inputs) != 2 || db.inputs[1] != "MK NFL|2023-09-08T00:20:00Z|Detroit Lions|Kansas City Chiefs add homeAmount :amount" {
+		t.Errorf("should fall back to the shared partition but updated %v", db.inputs)
+	}
+	if len(memory.Of(event.ListingChanged)) != 1 {
+		t.Error("should publish the legacy listing's change")
+	}
+}
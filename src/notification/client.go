@@ -0,0 +1,45 @@
+package notification
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"sammy.link/profile"
+)
+
+var ErrPrivateAddress = errors.New("refusing to post to a private address")
+
+// publicClient only connects to public addresses. The URLs it posts to are whatever
+// members saved, and a name can resolve somewhere else by the time it's posted to,
+// so the check is on each address actually dialled, redirects included.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !profile.IsPublic(addr) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			// a proxy would dial the address for us, unchecked
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+}
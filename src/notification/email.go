@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SmtpConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type Email struct {
+	config   SmtpConfig
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewEmail(config SmtpConfig) *Email {
+	return &Email{config: config, sendMail: smtp.SendMail}
+}
+
+func (e *Email) Send(ctx context.Context, to Recipient, batch []Notification) error {
+	if !to.Preferences.Email {
+		return nil
+	}
+
+	auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	err := e.sendMail(net.JoinHostPort(e.config.Host, e.config.Port), auth, e.config.From, []string{to.Email}, message(e.config.From, to.Email, batch))
+	if err != nil {
+		return fmt.Errorf("emailing %d notifications: %w", len(batch), err)
+	}
+	return nil
+}
+
+func message(from string, to string, batch []Notification) []byte {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Subject(batch)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(Text(batch), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return []byte(msg.String())
+}
@@ -0,0 +1,3 @@
+module sammy.link/notification
+
+go 1.21.0
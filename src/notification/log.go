@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"context"
+	"sync"
+
+	"sammy.link/logging"
+)
+
+// Log keeps what it was asked to send instead of sending it, for tests and local runs.
+type Log struct {
+	mutex sync.Mutex
+	Sent  []Sent
+}
+
+type Sent struct {
+	To    Recipient
+	Batch []Notification
+}
+
+func (l *Log) Send(ctx context.Context, to Recipient, batch []Notification) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	logging.FromContext(ctx).Info("notifying", "user", logging.HashUser(to.Email), "updates", len(batch), "subject", Subject(batch))
+	l.Sent = append(l.Sent, Sent{To: to, Batch: batch})
+	return nil
+}
@@ -0,0 +1,207 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"sammy.link/bid"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/logging"
+	"sammy.link/outcome"
+	"sammy.link/profile"
+)
+
+type Kind string
+
+const (
+	BetMatched Kind = "BET_MATCHED"
+	BetSettled Kind = "BET_SETTLED"
+)
+
+type Notification struct {
+	Kind   Kind   `json:"kind"`
+	User   string `json:"-"`
+	League string `json:"league"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// Recipient is who a batch goes to and how they want to hear about it.
+type Recipient struct {
+	Email       string
+	Preferences profile.Notifications
+}
+
+// Sender delivers over one channel. It skips recipients who haven't turned that
+// channel on.
+type Sender interface {
+	Send(ctx context.Context, to Recipient, batch []Notification) error
+}
+
+type Service interface {
+	Notify(ctx context.Context, notifications []Notification)
+}
+
+type NotificationService struct {
+	profileService profile.Service
+	senders        []Sender
+}
+
+func NewService(profileService profile.Service, senders []Sender) Service {
+	return &NotificationService{
+		profileService: profileService,
+		senders:        senders,
+	}
+}
+
+// Notify sends each user one batch with everything they want to hear about. Failed
+// deliveries are logged, nothing a notification is about should be undone by one.
+func (s *NotificationService) Notify(ctx context.Context, notifications []Notification) {
+	if len(s.senders) == 0 {
+		return
+	}
+
+	for _, batch := range batches(notifications) {
+		to := s.recipient(ctx, batch[0].User)
+		batch = wanted(batch, to.Preferences)
+		if len(batch) == 0 {
+			continue
+		}
+
+		for _, sender := range s.senders {
+			if err := sender.Send(ctx, to, batch); err != nil {
+				logging.FromContext(ctx).Error("notification failed", "user", logging.HashUser(to.Email), "err", err)
+			}
+		}
+	}
+}
+
+func (s *NotificationService) recipient(ctx context.Context, email string) Recipient {
+	item, err := s.profileService.Get(ctx, email)
+	if errors.Is(err, database.ErrNotFound) {
+		item = profile.Default(email, nil)
+	} else if err != nil {
+		// better to stay quiet than to send what someone may have turned off
+		logging.FromContext(ctx).Error("couldn't read preferences", "user", logging.HashUser(email), "err", err)
+		return Recipient{Email: email}
+	}
+	return Recipient{Email: email, Preferences: item.Notifications}
+}
+
+// batches groups notifications by user, keeping the order they came in.
+func batches(notifications []Notification) [][]Notification {
+	indexes := make(map[string]int)
+	grouped := make([][]Notification, 0)
+	for _, n := range notifications {
+		if n.User == "" {
+			continue
+		}
+		i, ok := indexes[n.User]
+		if !ok {
+			i = len(grouped)
+			indexes[n.User] = i
+			grouped = append(grouped, nil)
+		}
+		grouped[i] = append(grouped[i], n)
+	}
+	return grouped
+}
+
+func wanted(batch []Notification, preferences profile.Notifications) []Notification {
+	kept := make([]Notification, 0, len(batch))
+	for _, n := range batch {
+		if (n.Kind == BetMatched && preferences.BetMatched) || (n.Kind == BetSettled && preferences.BetSettled) {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// Subject sums a batch up in a line.
+func Subject(batch []Notification) string {
+	if len(batch) == 1 {
+		return batch[0].Title
+	}
+	return fmt.Sprintf("%d updates on your bets", len(batch))
+}
+
+// Text lists every notification in a batch.
+func Text(batch []Notification) string {
+	lines := make([]string, len(batch))
+	for i, n := range batch {
+		lines[i] = fmt.Sprintf("%s: %s", n.Title, n.Body)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Matched tells whoever placed b that it was taken, b carrying the amount matched.
+func Matched(b bid.Bid) Notification {
+	return Notification{
+		Kind:   BetMatched,
+		User:   b.User,
+		League: b.Div,
+		Title:  "Your bid was matched",
+		Body:   fmt.Sprintf("%d on %s in %s @ %s (%s)", b.Amount, b.ChosenCompetitor, b.AwayTeam, b.HomeTeam, b.Spread),
+	}
+}
+
+// Settled tells both sides of o how it went.
+func Settled(o outcome.OutcomeItem) []Notification {
+	game := fmt.Sprintf("%s %s @ %s %s (%s)", o.AwayTeam, o.AwayScore, o.HomeTeam, o.HomeScore, o.Spread)
+	if o.Push {
+		return []Notification{
+			{Kind: BetSettled, User: o.Winner, League: o.Div, Title: "Your bet pushed", Body: game},
+			{Kind: BetSettled, User: o.Loser, League: o.Div, Title: "Your bet pushed", Body: game},
+		}
+	}
+	return []Notification{
+		{Kind: BetSettled, User: o.Winner, League: o.Div, Title: fmt.Sprintf("You won %d", o.Amount), Body: game},
+		{Kind: BetSettled, User: o.Loser, League: o.Div, Title: fmt.Sprintf("You lost %d", o.Amount), Body: game},
+	}
+}
+
+type Config struct {
+	Smtp          SmtpConfig
+	Push          PushConfig
+	WebhookSecret string
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		Smtp: SmtpConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+		Push: PushConfig{
+			PrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+			Subject:    os.Getenv("VAPID_SUBJECT"),
+		},
+		WebhookSecret: os.Getenv("WEBHOOK_SECRET"),
+	}
+}
+
+// NewSenders builds a sender for every channel config has settings for.
+func NewSenders(config Config, clk clock.Clock) ([]Sender, error) {
+	senders := make([]Sender, 0, 3)
+	if config.Smtp.Host != "" {
+		senders = append(senders, NewEmail(config.Smtp))
+	}
+	if config.Push.PrivateKey != "" {
+		push, err := NewPush(config.Push, clk)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, push)
+	}
+	if config.WebhookSecret != "" {
+		senders = append(senders, NewWebhook(config.WebhookSecret))
+	}
+	return senders, nil
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"sammy.link/bid"
+	"sammy.link/database"
+	"sammy.link/outcome"
+	"sammy.link/profile"
+	"sammy.link/user"
+)
+
+type profiles map[string]profile.Notifications
+
+func (p profiles) Get(ctx context.Context, email string) (profile.Item, error) {
+	if email == "broken@sam.com" {
+		return profile.Item{}, errors.New("throttled")
+	}
+	notifications, ok := p[email]
+	if !ok {
+		return profile.Item{}, database.ErrNotFound
+	}
+	return profile.Item{Email: email, Notifications: notifications}, nil
+}
+
+func (p profiles) Update(ctx context.Context, item profile.Item, memberships []user.Item) error {
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	log := &Log{}
+	service := NewService(profiles{
+		"quiet@sam.com":   {},
+		"matches@sam.com": {BetMatched: true},
+	}, []Sender{log})
+
+	service.Notify(context.TODO(), []Notification{
+		{Kind: BetMatched, User: "new@sam.com", Title: "one"},
+		{Kind: BetMatched, User: "quiet@sam.com", Title: "muted"},
+		{Kind: BetSettled, User: "matches@sam.com", Title: "muted"},
+		{Kind: BetMatched, User: "matches@sam.com", Title: "two"},
+		{Kind: BetSettled, User: "new@sam.com", Title: "three"},
+		{Kind: BetSettled, User: "broken@sam.com", Title: "unsure"},
+		{Kind: BetSettled, User: "", Title: "nobody"},
+	})
+
+	if len(log.Sent) != 2 {
+		t.Fatalf("should send one batch to each user who wants one but sent %+v", log.Sent)
+	}
+	if to := log.Sent[0].To; to.Email != "new@sam.com" || !to.Preferences.Email {
+		t.Errorf("users without a profile should get the defaults but got %+v", to)
+	}
+	if batch := log.Sent[0].Batch; len(batch) != 2 || batch[0].Title != "one" || batch[1].Title != "three" {
+		t.Errorf("batch = %+v", batch)
+	}
+	if batch := log.Sent[1].Batch; len(batch) != 1 || batch[0].Title != "two" {
+		t.Errorf("batch = %+v", batch)
+	}
+}
+
+func TestMatchedAndSettled(t *testing.T) {
+	b := bid.Bid{User: "home@sam.com", ChosenCompetitor: "Chiefs", AwayTeam: "Jets", HomeTeam: "Chiefs", Spread: "KC -9.5", Amount: 10, Div: "default"}
+
+	if n := Matched(b); n.User != "home@sam.com" || n.League != "default" || n.Body != "10 on Chiefs in Jets @ Chiefs (KC -9.5)" {
+		t.Errorf("Matched() = %+v", n)
+	}
+
+	settled := Settled(outcome.OutcomeItem{Winner: "away@sam.com", Loser: "home@sam.com", Amount: 10, AwayTeam: "Jets", HomeTeam: "Chiefs", AwayScore: "17", HomeScore: "20", Spread: "KC -9.5"})
+	if len(settled) != 2 || settled[0].Title != "You won 10" || settled[1].Title != "You lost 10" || settled[0].Body != "Jets 17 @ Chiefs 20 (KC -9.5)" {
+		t.Errorf("Settled() = %+v", settled)
+	}
+	if pushed := Settled(outcome.OutcomeItem{Winner: "a", Loser: "b", Push: true}); pushed[0].Title != "Your bet pushed" || pushed[1].User != "b" {
+		t.Errorf("Settled() = %+v", pushed)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	email := NewEmail(SmtpConfig{Host: "smtp.sammy.link", Port: "587", From: "bets@sammy.link"})
+	var sent []byte
+	var addr string
+	email.sendMail = func(a string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		addr, sent = a, msg
+		return nil
+	}
+	batch := []Notification{{Title: "You won 10", Body: "Jets"}, {Title: "You lost 5", Body: "Bills"}}
+
+	if email.Send(context.TODO(), Recipient{Email: "sam@sam.com"}, batch); sent != nil {
+		t.Fatal("should only email users who turned email on")
+	}
+
+	email.Send(context.TODO(), Recipient{Email: "sam@sam.com", Preferences: profile.Notifications{Email: true}}, batch)
+	if addr != "smtp.sammy.link:587" || !strings.Contains(string(sent), "Subject: 2 updates on your bets\r\n") || !strings.HasSuffix(string(sent), "You won 10: Jets\r\nYou lost 5: Bills\r\n") {
+		t.Errorf("sent %q to %s", sent, addr)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var signature, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, _ := io.ReadAll(r.Body)
+		signature, body = r.Header.Get(SignatureHeader), string(read)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook("secret")
+	webhook.client = server.Client()
+	batch := []Notification{{Kind: BetSettled, User: "sam@sam.com", Title: "You won 10", Body: "Jets"}}
+	if err := webhook.Send(context.TODO(), Recipient{Preferences: profile.Notifications{WebhookUrl: server.URL}}, batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if signature != "sha256="+Sign([]byte("secret"), []byte(body)) {
+		t.Errorf("signature %s doesn't match %s", signature, body)
+	}
+	if strings.Contains(body, "sam@sam.com") || !strings.Contains(body, `"subject":"You won 10"`) {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	batch := []Notification{{Kind: BetSettled, User: "sam@sam.com", Title: "You won 10"}}
+	err := NewWebhook("secret").Send(context.TODO(), Recipient{Preferences: profile.Notifications{WebhookUrl: server.URL}}, batch)
+	if !errors.Is(err, ErrPrivateAddress) || called {
+		t.Errorf("should refuse to post to %s but got %v", server.URL, err)
+	}
+}
@@ -0,0 +1,239 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sammy.link/clock"
+	"sammy.link/profile"
+)
+
+// PushConfig holds the VAPID key push services identify us by, its 32 byte P-256
+// scalar base64url encoded. Browsers subscribe with the public half.
+type PushConfig struct {
+	PrivateKey string
+	// Subject is a mailto: or https: URL push services can reach us at.
+	Subject string
+}
+
+const (
+	pushTtl = 24 * time.Hour
+	// how long the VAPID token is good for, push services cap it at a day
+	vapidTtl = 12 * time.Hour
+	// push services take 4096 byte bodies, which is one record after the header
+	recordSize = 4096
+	maxPayload = recordSize - 16 - 4 - 1 - 65 - 16 - 1
+)
+
+var ErrPushKey = errors.New("malformed push key")
+
+// Push sends web push messages (RFC 8030), encrypted for each subscription
+// (RFC 8291) and signed with our VAPID key (RFC 8292).
+type Push struct {
+	publicKey string
+	key       *ecdsa.PrivateKey
+	subject   string
+	clock     clock.Clock
+	client    *http.Client
+	random    io.Reader
+}
+
+func NewPush(config PushConfig, clk clock.Clock) (*Push, error) {
+	scalar, err := decodeKey(config.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	private, err := ecdh.P256().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, ErrPushKey
+	}
+
+	public := private.PublicKey().Bytes()
+	x, y := elliptic.Unmarshal(elliptic.P256(), public)
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+		D:         new(big.Int).SetBytes(scalar),
+	}
+
+	return &Push{
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		key:       key,
+		subject:   config.Subject,
+		clock:     clk,
+		client:    publicClient(),
+		random:    rand.Reader,
+	}, nil
+}
+
+// pushMessage is what the service worker gets in the push event.
+type pushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func (p *Push) Send(ctx context.Context, to Recipient, batch []Notification) error {
+	payload, _ := json.Marshal(pushMessage{Title: Subject(batch), Body: Text(batch)})
+	if len(payload) > maxPayload {
+		payload, _ = json.Marshal(pushMessage{Title: Subject(batch), Body: "Open the app to see them"})
+	}
+
+	var errs []error
+	for _, subscription := range to.Preferences.PushSubscriptions {
+		if err := p.send(ctx, subscription, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Push) send(ctx context.Context, subscription profile.PushSubscription, payload []byte) error {
+	body, err := encrypt(subscription, payload, p.random)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+	token, err := p.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", fmt.Sprintf("%d", int(pushTtl.Seconds())))
+	request.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, p.publicKey))
+
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("pushing to %s: %w", endpoint.Host, err)
+	}
+	defer resp.Body.Close()
+
+	// 404 and 410 mean the browser unsubscribed, which it also tells the app about
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %d", endpoint.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+// vapidToken is an ES256 JWT for the push service at audience.
+func (p *Push) vapidToken(audience string) (string, error) {
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, _ := json.Marshal(map[string]any{
+		"aud": audience,
+		"exp": p.clock.Now().Add(vapidTtl).Unix(),
+		"sub": p.subject,
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(p.random, p.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encrypt builds the aes128gcm body of RFC 8291 as a single record.
+func encrypt(subscription profile.PushSubscription, payload []byte, random io.Reader) ([]byte, error) {
+	userAgentBytes, err := decodeKey(subscription.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(userAgentBytes)
+	if err != nil {
+		return nil, ErrPushKey
+	}
+	authSecret, err := decodeKey(subscription.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(random)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(random, salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), userAgentBytes...), serverPublic...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	contentKey := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last record
+	ciphertext := gcm.Seal(nil, nonce, append(payload, 0x02), nil)
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return append(header, ciphertext...), nil
+}
+
+// hkdf is HKDF-SHA256 (RFC 5869) for outputs of up to one hash.
+func hkdf(salt []byte, secret []byte, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// decodeKey accepts base64url with or without padding, browsers hand out both.
+func decodeKey(encoded string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, ErrPushKey
+	}
+	return decoded, nil
+}
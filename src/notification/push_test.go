@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sammy.link/clock"
+	"sammy.link/profile"
+)
+
+// decrypt is what the browser does with a push body.
+func decrypt(t *testing.T, userAgentKey *ecdh.PrivateKey, authSecret []byte, body []byte) []byte {
+	t.Helper()
+	salt := body[:16]
+	if size := binary.BigEndian.Uint32(body[16:20]); size != recordSize {
+		t.Fatalf("record size = %d", size)
+	}
+	keyLength := int(body[20])
+	serverPublic := body[21 : 21+keyLength]
+
+	serverKey, err := ecdh.P256().NewPublicKey(serverPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedSecret, err := userAgentKey.ECDH(serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), userAgentKey.PublicKey().Bytes()...), serverPublic...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	block, _ := aes.NewCipher(hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16))
+	gcm, _ := cipher.NewGCM(block)
+
+	plaintext, err := gcm.Open(nil, hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12), body[21+keyLength:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatal("the only record should be marked as the last")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestPush(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	vapidKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	userAgentKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	io.ReadFull(rand.Reader, authSecret)
+
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	push, err := NewPush(PushConfig{
+		PrivateKey: base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()),
+		Subject:    "mailto:admin@sammy.link",
+	}, clock.NewFake(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	push.client = server.Client()
+
+	to := Recipient{Email: "sam@sam.com", Preferences: profile.Notifications{PushSubscriptions: []profile.PushSubscription{{
+		Endpoint: server.URL + "/push/abc",
+		P256dh:   base64.RawURLEncoding.EncodeToString(userAgentKey.PublicKey().Bytes()),
+		// browsers pad some keys
+		Auth: base64.URLEncoding.EncodeToString(authSecret),
+	}}}}
+	batch := []Notification{{Kind: BetMatched, User: to.Email, Title: "Your bid was matched", Body: "10 on Jets"}}
+
+	if err := push.Send(context.TODO(), to, batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Header.Get("Content-Encoding") != "aes128gcm" || request.Header.Get("TTL") != "86400" {
+		t.Errorf("headers = %v", request.Header)
+	}
+
+	var message pushMessage
+	if err := json.Unmarshal(decrypt(t, userAgentKey, authSecret, body), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Title != "Your bid was matched" || message.Body != "Your bid was matched: 10 on Jets" {
+		t.Errorf("message = %+v", message)
+	}
+
+	// the Authorization header is "vapid t=<jwt>, k=<public key>"
+	authorization := strings.TrimPrefix(request.Header.Get("Authorization"), "vapid t=")
+	token, publicKey, _ := strings.Cut(authorization, ", k=")
+	if publicKey != base64.RawURLEncoding.EncodeToString(vapidKey.PublicKey().Bytes()) {
+		t.Errorf("k = %s", publicKey)
+	}
+
+	parts := strings.Split(token, ".")
+	claimsJson, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	json.Unmarshal(claimsJson, &claims)
+	if claims.Aud != server.URL || claims.Exp != now.Add(vapidTtl).Unix() || claims.Sub != "mailto:admin@sammy.link" {
+		t.Errorf("claims = %s", claimsJson)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	x, y := elliptic.Unmarshal(elliptic.P256(), vapidKey.PublicKey().Bytes())
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, digest[:], r, s) {
+		t.Error("the VAPID token should be signed with the configured key")
+	}
+}
+
+func TestNewPushRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewPush(PushConfig{PrivateKey: key}, clock.System); err != ErrPushKey {
+			t.Errorf("NewPush(%q) = %v, want %v", key, err, ErrPushKey)
+		}
+	}
+}
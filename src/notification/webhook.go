@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body under the webhook secret,
+// so receivers can tell the request came from us.
+const SignatureHeader = "X-Sammy-Signature"
+
+type Webhook struct {
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhook(secret string) *Webhook {
+	return &Webhook{secret: []byte(secret), client: publicClient()}
+}
+
+type webhookBody struct {
+	Subject       string         `json:"subject"`
+	Notifications []Notification `json:"notifications"`
+}
+
+func (w *Webhook) Send(ctx context.Context, to Recipient, batch []Notification) error {
+	if to.Preferences.WebhookUrl == "" {
+		return nil
+	}
+
+	body, _ := json.Marshal(webhookBody{Subject: Subject(batch), Notifications: batch})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, to.Preferences.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, body))
+
+	resp, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
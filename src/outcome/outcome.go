@@ -3,14 +3,17 @@ package outcome
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"sammy.link/database"
+	"sammy.link/event"
+	"sammy.link/key"
 )
 
 type OutcomeDynamoItem struct {
@@ -20,46 +23,98 @@ type OutcomeDynamoItem struct {
 	Gsi1_sortKey string `dynamodbav:"gsi1_sortKey"`
 	Gsi2_id      string `dynamodbav:"gsi2_id"`
 	Gsi2_sortKey string `dynamodbav:"gsi2_sortKey"`
+	Date         string `dynamodbav:"date"`
+	Push         bool   `dynamodbav:"push"`
+	Season       string `dynamodbav:"season"`
+	Kind         string `dynamodbav:"kind"`
+	AwayTeam     string `dynamodbav:"away"`
+	HomeTeam     string `dynamodbav:"home"`
+	AwayUser     string `dynamodbav:"awayUser"`
+	HomeUser     string `dynamodbav:"homeUser"`
+	Spread       string `dynamodbav:"spread"`
+	AwayScore    string `dynamodbav:"awayScore"`
+	HomeScore    string `dynamodbav:"homeScore"`
+	Version      int    `dynamodbav:"v"`
 }
 
 type OutcomeItem struct {
-	Winner  string `json:"winner"`
-	Loser   string `json:"loser"`
-	EventId string `json:"eventId"`
-	Week    int    `json:"week"`
-	Amount  int64  `json:"amount"`
-	Id      string `json:"id"`
-	Div     string `json:"div"`
+	Winner    string    `json:"winner"`
+	Loser     string    `json:"loser"`
+	EventId   string    `json:"eventId"`
+	Week      int       `json:"week"`
+	Amount    int64     `json:"amount"`
+	Id        string    `json:"id"`
+	Div       string    `json:"div"`
+	Date      time.Time `json:"date"`
+	Push      bool      `json:"push"`
+	Season    string    `json:"season"`
+	Kind      string    `json:"kind"`
+	AwayTeam  string    `json:"awayTeam"`
+	HomeTeam  string    `json:"homeTeam"`
+	AwayUser  string    `json:"awayUser"`
+	HomeUser  string    `json:"homeUser"`
+	Spread    string    `json:"spread"`
+	AwayScore string    `json:"awayScore"`
+	HomeScore string    `json:"homeScore"`
 }
 
 type Service interface {
 	GetByUser(ctx context.Context, user string) []OutcomeItem
+	GetByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]OutcomeItem, string, error)
+	GetByWeek(ctx context.Context, div string, week int) []OutcomeItem
 	Write(ctx context.Context, outcomes []OutcomeItem)
 }
 type OutcomeService struct {
 	databaseService database.Service[OutcomeDynamoItem, OutcomeItem]
+	publisher       event.Publisher
 }
 
-func NewService(databaseService database.Service[OutcomeDynamoItem, OutcomeItem]) Service {
+func NewService(databaseService database.Service[OutcomeDynamoItem, OutcomeItem], publisher event.Publisher) Service {
 	return &OutcomeService{
 		databaseService: databaseService,
+		publisher:       publisher,
 	}
 }
 
 func (item OutcomeItem) getDynamoId() string {
-	return fmt.Sprintf("O|%s|%d", item.Div, item.Week)
+	return getDynamoId(item.Div, item.Week)
+}
+
+var idCodec = key.Codec{Prefix: "O", Parts: 2}
+
+func getDynamoId(div string, week int) string {
+	return idCodec.Encode(div, strconv.Itoa(week))
 }
 
 func (dynamoItem OutcomeDynamoItem) GetItem() database.Item {
+	ids, err := idCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
+
 	amount, _ := strconv.ParseInt(dynamoItem.Gsi1_sortKey, 10, 64)
-	week, _ := strconv.Atoi(strings.Split(dynamoItem.Id, "|")[2])
+	week, _ := strconv.Atoi(ids[1])
+	date, _ := key.ParseTime(dynamoItem.Date)
 	return OutcomeItem{
-		Winner:  dynamoItem.Gsi1_id,
-		Loser:   dynamoItem.Gsi2_id,
-		EventId: dynamoItem.Gsi2_sortKey,
-		Week:    week,
-		Amount:  amount,
-		Div:     strings.Split(dynamoItem.Id, "|")[1],
+		Winner:    dynamoItem.Gsi1_id,
+		Loser:     dynamoItem.Gsi2_id,
+		EventId:   dynamoItem.Gsi2_sortKey,
+		Week:      week,
+		Amount:    amount,
+		Id:        dynamoItem.SortKey,
+		Div:       ids[0],
+		Date:      date,
+		Push:      dynamoItem.Push,
+		Season:    dynamoItem.Season,
+		Kind:      dynamoItem.Kind,
+		AwayTeam:  dynamoItem.AwayTeam,
+		HomeTeam:  dynamoItem.HomeTeam,
+		AwayUser:  dynamoItem.AwayUser,
+		HomeUser:  dynamoItem.HomeUser,
+		Spread:    dynamoItem.Spread,
+		AwayScore: dynamoItem.AwayScore,
+		HomeScore: dynamoItem.HomeScore,
 	}
 }
 
@@ -71,6 +126,29 @@ func (item OutcomeItem) GetDynamoItem() database.DynamoItem {
 		Gsi1_sortKey: fmt.Sprintf("%d", item.Amount),
 		Gsi2_id:      item.Loser,
 		Gsi2_sortKey: item.EventId,
+		Date:         key.Time(item.Date),
+		Push:         item.Push,
+		Season:       item.Season,
+		Kind:         item.Kind,
+		AwayTeam:     item.AwayTeam,
+		HomeTeam:     item.HomeTeam,
+		AwayUser:     item.AwayUser,
+		HomeUser:     item.HomeUser,
+		Spread:       item.Spread,
+		AwayScore:    item.AwayScore,
+		HomeScore:    item.HomeScore,
+		Version:      database.Version,
+	}
+}
+
+func userQuery(user string, num int) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String(fmt.Sprintf("gsi%d_id = :id", num)),
+		IndexName:              aws.String(fmt.Sprintf("gsi%d", num)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: user},
+		},
 	}
 }
 
@@ -79,14 +157,7 @@ func (s *OutcomeService) GetByUser(ctx context.Context, user string) []OutcomeIt
 	sliceChan := make(chan []OutcomeItem, 2)
 	for i := 1; i < 3; i++ {
 		go func(sliceChan chan []OutcomeItem, num int) {
-			sliceChan <- s.databaseService.Query(ctx, &dynamodb.QueryInput{
-				TableName:              aws.String(os.Getenv("TABLE_NAME")),
-				KeyConditionExpression: aws.String(fmt.Sprintf("gsi%d_id = :id", num)),
-				IndexName:              aws.String(fmt.Sprintf("gsi%d", num)),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":id": &types.AttributeValueMemberS{Value: user},
-				},
-			})
+			sliceChan <- s.databaseService.Query(ctx, userQuery(user, num))
 
 		}(sliceChan, i)
 	}
@@ -97,6 +168,30 @@ func (s *OutcomeService) GetByUser(ctx context.Context, user string) []OutcomeIt
 	return append(sliceOne, sliceTwo...)
 }
 
+// GetByUserPage pages through the outcomes a user won and then the ones they lost.
+func (s *OutcomeService) GetByUserPage(ctx context.Context, user string, limit int32, cursor string) ([]OutcomeItem, string, error) {
+	return s.databaseService.QueryMerged(ctx, []*dynamodb.QueryInput{userQuery(user, 1), userQuery(user, 2)}, limit, cursor, nil)
+}
+
+// GetByWeek reads consistently, as leaderboards are computed from outcomes just written.
+func (s *OutcomeService) GetByWeek(ctx context.Context, div string, week int) []OutcomeItem {
+	return s.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: getDynamoId(div, week)},
+		},
+	})
+}
+
+// Write publishes BetSettled for each outcome, since every outcome settles a bet.
 func (s *OutcomeService) Write(ctx context.Context, outcomes []OutcomeItem) {
 	s.databaseService.Write(ctx, outcomes)
+
+	events := make([]event.Event, len(outcomes))
+	for i, o := range outcomes {
+		events[i] = event.Event{Type: event.BetSettled, Detail: o}
+	}
+	event.Emit(ctx, s.publisher, events...)
 }
@@ -0,0 +1,26 @@
+package outcome
+
+import (
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	roundTrip := func(div string, id string, winner string, loser string, week uint8, amount uint16, seconds uint32) bool {
+		item := OutcomeItem{
+			Div:    div,
+			Id:     id,
+			Winner: winner,
+			Loser:  loser,
+			Week:   int(week),
+			Amount: int64(amount),
+			Date:   time.Unix(int64(seconds), 0).UTC(),
+		}
+		return item.GetDynamoItem().(OutcomeDynamoItem).GetItem() == item
+	}
+
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+}
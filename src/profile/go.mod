@@ -0,0 +1,3 @@
+module sammy.link/profile
+
+go 1.21.0
@@ -0,0 +1,309 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/clock"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/user"
+	"sammy.link/validation"
+)
+
+// Notifications are what a member wants to hear about and how.
+type Notifications struct {
+	BetMatched        bool               `json:"betMatched" dynamodbav:"betMatched"`
+	BetSettled        bool               `json:"betSettled" dynamodbav:"betSettled"`
+	Email             bool               `json:"email" dynamodbav:"email"`
+	WebhookUrl        string             `json:"webhookUrl" dynamodbav:"webhookUrl,omitempty"`
+	PushSubscriptions []PushSubscription `json:"pushSubscriptions" dynamodbav:"pushSubscriptions,omitempty"`
+}
+
+// PushSubscription is what a browser's PushManager.subscribe hands back, with the
+// keys base64url encoded.
+type PushSubscription struct {
+	Endpoint string `json:"endpoint" dynamodbav:"endpoint"`
+	P256dh   string `json:"p256dh" dynamodbav:"p256dh"`
+	Auth     string `json:"auth" dynamodbav:"auth"`
+}
+
+// Item is a member's profile, shared by every league they're in. League records keep
+// a copy of the display name, which Update rewrites.
+type Item struct {
+	Email         string        `json:"email"`
+	DisplayName   string        `json:"displayName"`
+	AvatarUrl     string        `json:"avatarUrl"`
+	Timezone      string        `json:"timezone"`
+	FavoriteTeams []string      `json:"favoriteTeams"`
+	Notifications Notifications `json:"notifications"`
+}
+
+type DynamoItem struct {
+	Id            string        `dynamodbav:"id"`
+	SortKey       string        `dynamodbav:"sortKey"`
+	DisplayName   string        `dynamodbav:"displayName"`
+	AvatarUrl     string        `dynamodbav:"avatarUrl,omitempty"`
+	Timezone      string        `dynamodbav:"timezone"`
+	FavoriteTeams []string      `dynamodbav:"favoriteTeams"`
+	Notifications Notifications `dynamodbav:"notifications"`
+	Version       int           `dynamodbav:"v"`
+}
+
+const sortKey = "PROFILE"
+
+var idCodec = key.Codec{Prefix: "PROFILE", Parts: 1}
+
+const (
+	MaxFavoriteTeams = 10
+	maxTeamLength    = 40
+	maxAvatarLength  = 2048
+	maxUrlLength     = 2048
+	maxKeyLength     = 128
+	// one per browser the member turned notifications on in
+	MaxPushSubscriptions = 5
+	// a transaction holds 100 writes, one for the profile and up to four per league
+	MaxLeagues = 24
+)
+
+var ErrTooManyLeagues = fmt.Errorf("display names can only be changed in up to %d leagues at once", MaxLeagues)
+
+// NameTakenError is user.ErrNameTaken in one of the leagues Update renamed in.
+type NameTakenError struct {
+	League string
+}
+
+func (e *NameTakenError) Error() string {
+	return fmt.Sprintf("%s in %s", user.ErrNameTaken, e.League)
+}
+
+func (e *NameTakenError) Unwrap() error {
+	return user.ErrNameTaken
+}
+
+type Service interface {
+	Get(ctx context.Context, email string) (Item, error)
+	Update(ctx context.Context, item Item, memberships []user.Item) error
+}
+
+type ProfileService struct {
+	databaseService database.Service[DynamoItem, Item]
+}
+
+func NewService(databaseService database.Service[DynamoItem, Item]) Service {
+	return &ProfileService{
+		databaseService: databaseService,
+	}
+}
+
+func (dynamoItem DynamoItem) GetItem() database.Item {
+	parts, _ := idCodec.Decode(dynamoItem.Id)
+	email := ""
+	if len(parts) > 0 {
+		email = parts[0]
+	}
+
+	return Item{
+		Email:         email,
+		DisplayName:   dynamoItem.DisplayName,
+		AvatarUrl:     dynamoItem.AvatarUrl,
+		Timezone:      dynamoItem.Timezone,
+		FavoriteTeams: dynamoItem.FavoriteTeams,
+		Notifications: dynamoItem.Notifications,
+	}
+}
+
+func (item Item) GetDynamoItem() database.DynamoItem {
+	return DynamoItem{
+		Id:            idCodec.Encode(item.Email),
+		SortKey:       sortKey,
+		DisplayName:   item.DisplayName,
+		AvatarUrl:     item.AvatarUrl,
+		Timezone:      item.Timezone,
+		FavoriteTeams: item.FavoriteTeams,
+		Notifications: item.Notifications,
+		Version:       database.Version,
+	}
+}
+
+// Default is the profile of a member who never saved one, named after the first
+// league that has a name for them.
+func Default(email string, memberships []user.Item) Item {
+	item := Item{
+		Email:         email,
+		Timezone:      clock.Eastern.String(),
+		FavoriteTeams: []string{},
+		Notifications: Notifications{BetMatched: true, BetSettled: true, Email: true},
+	}
+	for _, membership := range memberships {
+		if membership.Name != "" {
+			item.DisplayName = membership.Name
+			break
+		}
+	}
+	return item
+}
+
+func Check(v *validation.Validator, item Item) bool {
+	user.CheckName(v, "displayName", item.DisplayName)
+
+	if item.AvatarUrl != "" {
+		checkUrl(v, "avatarUrl", item.AvatarUrl, maxAvatarLength)
+	}
+
+	if v.String("timezone", item.Timezone).Required().Valid() {
+		_, err := time.LoadLocation(item.Timezone)
+		v.Check(err == nil, "timezone", "must be an IANA time zone, e.g. America/New_York")
+	}
+
+	if v.Int("favoriteTeams", int64(len(item.FavoriteTeams))).Between(0, MaxFavoriteTeams).Valid() {
+		for i, team := range item.FavoriteTeams {
+			field := fmt.Sprintf("favoriteTeams[%d]", i)
+			if v.String(field, team).Required().MaxLength(maxTeamLength).Valid() {
+				v.Check(!slices.Contains(item.FavoriteTeams[:i], team), field, "is already a favorite")
+			}
+		}
+	}
+
+	checkNotifications(v.At("notifications"), item.Notifications)
+
+	return v.Valid()
+}
+
+func checkNotifications(v *validation.Validator, notifications Notifications) {
+	if notifications.WebhookUrl != "" {
+		checkUrl(v, "webhookUrl", notifications.WebhookUrl, maxUrlLength)
+	}
+
+	if v.Int("pushSubscriptions", int64(len(notifications.PushSubscriptions))).Between(0, MaxPushSubscriptions).Valid() {
+		for i, subscription := range notifications.PushSubscriptions {
+			pushV := v.At(fmt.Sprintf("pushSubscriptions[%d]", i))
+			checkUrl(pushV, "endpoint", subscription.Endpoint, maxUrlLength)
+			pushV.String("p256dh", subscription.P256dh).Required().MaxLength(maxKeyLength)
+			pushV.String("auth", subscription.Auth).Required().MaxLength(maxKeyLength)
+		}
+	}
+}
+
+func checkUrl(v *validation.Validator, field string, value string, maxLength int) {
+	if v.String(field, value).Required().MaxLength(maxLength).Valid() {
+		parsed, err := url.Parse(value)
+		if v.Check(err == nil && parsed.Scheme == "https" && parsed.Host != "", field, "must be an https URL") {
+			v.Check(isPublicHost(parsed.Hostname()), field, "must be a public address")
+		}
+	}
+}
+
+// reserved are ranges that aren't on the internet but that netip doesn't flag.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// IsPublic is whether addr is on the internet. Webhooks and push endpoints are posted
+// to from inside AWS, where anything else could be the metadata service or our own
+// network.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPublicHost turns away hosts that plainly aren't on the internet. Names are only
+// resolved when posting, where every address dialled is checked again.
+func isPublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return IsPublic(addr)
+	}
+	return true
+}
+
+func (s *ProfileService) Get(ctx context.Context, email string) (Item, error) {
+	return s.databaseService.Get(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(os.Getenv("TABLE_NAME")),
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: idCodec.Encode(email)},
+			"sortKey": &types.AttributeValueMemberS{Value: sortKey},
+		},
+	})
+}
+
+// Update saves item and renames its member in every league in memberships that has
+// them under another name, all in one transaction. It fails with a NameTakenError if
+// someone in one of them already has the display name, and with
+// user.ErrNameChanged if a league name changed since memberships was read.
+func (s *ProfileService) Update(ctx context.Context, item Item, memberships []user.Item) error {
+	writes, leagues, err := updateWrites(item, memberships)
+	if err != nil {
+		return err
+	}
+
+	err = s.databaseService.TransactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: writes})
+	if err == nil {
+		return nil
+	}
+
+	failed := database.CancelledBy(err)
+	for _, index := range failed {
+		if league, ok := leagues[index]; ok {
+			return &NameTakenError{League: league}
+		}
+	}
+	if len(failed) > 0 {
+		return user.ErrNameChanged
+	}
+	return err
+}
+
+// updateWrites also returns the league each name reservation is for, by its index.
+func updateWrites(item Item, memberships []user.Item) ([]types.TransactWriteItem, map[int]string, error) {
+	profile, err := attributevalue.MarshalMap(item.GetDynamoItem())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	writes := []types.TransactWriteItem{{Put: &types.Put{
+		TableName: aws.String(os.Getenv("TABLE_NAME")),
+		Item:      profile,
+	}}}
+	leagues := map[int]string{}
+
+	for _, membership := range memberships {
+		if membership.Name == item.DisplayName {
+			continue
+		}
+		if len(leagues) == MaxLeagues {
+			return nil, nil, ErrTooManyLeagues
+		}
+
+		leagues[len(writes)] = membership.League
+		writes = append(writes, user.RenameWrites(user.Item{
+			Email:  item.Email,
+			Name:   item.DisplayName,
+			League: membership.League,
+		}, membership.Name)...)
+	}
+
+	return writes, leagues, nil
+}
@@ -0,0 +1,170 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/user"
+	"sammy.link/validation"
+)
+
+func valid() Item {
+	return Item{
+		DisplayName:   "Sammy",
+		AvatarUrl:     "https://cdn.sammy.link/avatars/sammy.png",
+		Timezone:      "America/Chicago",
+		FavoriteTeams: []string{"Jets", "Chiefs"},
+		Notifications: Notifications{
+			BetMatched: true,
+			WebhookUrl: "https://hooks.sammy.link/bets",
+			PushSubscriptions: []PushSubscription{
+				{Endpoint: "https://fcm.googleapis.com/fcm/send/abc", P256dh: "BOr8", Auth: "c2Ft"},
+			},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if v := validation.New(); !Check(v, valid()) {
+		t.Fatalf("a valid profile was rejected: %v", v.Err())
+	}
+
+	tests := map[string]func(item *Item){
+		"displayName":              func(item *Item) { item.DisplayName = "" },
+		"avatarUrl":                func(item *Item) { item.AvatarUrl = "http://cdn.sammy.link/sammy.png" },
+		"timezone":                 func(item *Item) { item.Timezone = "Mars/Olympus_Mons" },
+		"favoriteTeams":            func(item *Item) { item.FavoriteTeams = make([]string, MaxFavoriteTeams+1) },
+		"favoriteTeams[1]":         func(item *Item) { item.FavoriteTeams = []string{"Jets", "Jets"} },
+		"notifications.webhookUrl": func(item *Item) { item.Notifications.WebhookUrl = "ftp://hooks.sammy.link" },
+		"notifications.pushSubscriptions[0].auth": func(item *Item) {
+			item.Notifications.PushSubscriptions[0].Auth = ""
+		},
+	}
+	for field, change := range tests {
+		item := valid()
+		change(&item)
+
+		v := validation.New()
+		var got validation.Errors
+		if Check(v, item) || !errors.As(v.Err(), &got) || len(got) != 1 || got[0].Field != field {
+			t.Errorf("expected only %s to be rejected but got %v", field, v.Err())
+		}
+	}
+}
+
+func TestCheckPrivateTargets(t *testing.T) {
+	for _, target := range []string{
+		"https://localhost/hook",
+		"https://api.localhost./hook",
+		"https://127.0.0.1/hook",
+		"https://10.0.0.8/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]:8443/hook",
+		"https://[::ffff:192.168.0.1]/hook",
+		"https://100.64.0.1/hook",
+	} {
+		item := valid()
+		item.Notifications.WebhookUrl = target
+		item.Notifications.PushSubscriptions[0].Endpoint = target
+
+		v := validation.New()
+		var got validation.Errors
+		if Check(v, item) || !errors.As(v.Err(), &got) || len(got) != 2 {
+			t.Errorf("%s should be rejected twice but got %v", target, v.Err())
+		}
+	}
+
+	if !IsPublic(netip.MustParseAddr("8.8.8.8")) || !IsPublic(netip.MustParseAddr("2606:4700::1111")) {
+		t.Error("should allow public addresses")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	item := Default("sam@sam.com", []user.Item{{League: "new"}, {Name: "sam", League: "default"}})
+	if item.DisplayName != "sam" || item.Timezone != "America/New_York" || !item.Notifications.BetMatched {
+		t.Errorf("Default() = %+v", item)
+	}
+	if !Check(validation.New(), item) {
+		t.Error("the default profile should be valid once named")
+	}
+}
+
+type transactions struct {
+	database.Service[DynamoItem, Item]
+	inputs []*dynamodb.TransactWriteItemsInput
+	err    error
+}
+
+func (t *transactions) TransactWrite(ctx context.Context, params *dynamodb.TransactWriteItemsInput) error {
+	t.inputs = append(t.inputs, params)
+	return t.err
+}
+
+func cancelled(codes ...string) error {
+	reasons := make([]types.CancellationReason, len(codes))
+	for i, code := range codes {
+		reasons[i] = types.CancellationReason{Code: aws.String(code)}
+	}
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestUpdate(t *testing.T) {
+	item := valid()
+	item.Email = "sam@sam.com"
+	memberships := []user.Item{
+		{Email: item.Email, Name: "sam", League: "default"},
+		{Email: item.Email, Name: "Sammy", League: "work"},
+		{Email: item.Email, Name: "", League: "family"},
+	}
+
+	db := &transactions{}
+	if err := NewService(db).Update(context.TODO(), item, memberships); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction := db.inputs[0].TransactItems
+	// the profile, then a rename in default that releases "sam" and one in family
+	if len(transaction) != 8 || transaction[0].Put == nil {
+		t.Fatalf("should save the profile and rename in two leagues but got %+v", transaction)
+	}
+	if id := transaction[0].Put.Item["id"].(*types.AttributeValueMemberS).Value; id != "PROFILE|sam@sam.com" {
+		t.Errorf("saved the profile under %s", id)
+	}
+	if reserved := transaction[5].Put.Item["id"].(*types.AttributeValueMemberS).Value; reserved != "NAME|family" {
+		t.Errorf("expected the second rename to reserve the name in family but got %s", reserved)
+	}
+
+	none := func(n int) []string { return strings.Split(strings.Repeat("None ", n), " ")[:n] }
+	tests := []struct {
+		codes []string
+		want  error
+	}{
+		{append(append(none(5), "ConditionalCheckFailed"), none(2)...), &NameTakenError{League: "family"}},
+		{append(append(none(2), "ConditionalCheckFailed"), none(5)...), user.ErrNameChanged},
+	}
+	for _, test := range tests {
+		err := NewService(&transactions{err: cancelled(test.codes...)}).Update(context.TODO(), item, memberships)
+		if fmt.Sprint(err) != fmt.Sprint(test.want) {
+			t.Errorf("Update() = %v, want %v", err, test.want)
+		}
+	}
+	if err := NewService(&transactions{err: cancelled(append(none(5), "ConditionalCheckFailed")...)}).Update(context.TODO(), item, memberships); !errors.Is(err, user.ErrNameTaken) {
+		t.Errorf("a NameTakenError should be user.ErrNameTaken but got %v", err)
+	}
+
+	many := make([]user.Item, MaxLeagues+1)
+	for i := range many {
+		many[i] = user.Item{Email: item.Email, League: fmt.Sprintf("league %d", i)}
+	}
+	if err := NewService(&transactions{}).Update(context.TODO(), item, many); err != ErrTooManyLeagues {
+		t.Errorf("Update() = %v, want %v", err, ErrTooManyLeagues)
+	}
+}
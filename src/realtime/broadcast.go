@@ -0,0 +1,87 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"sammy.link/logging"
+)
+
+// Message is what subscribers receive, Data being the listing or bet that changed.
+type Message struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Data    any    `json:"data"`
+}
+
+const (
+	ListingMessage    = "listing"
+	BetMatchedMessage = "betMatched"
+)
+
+// ErrGone is a connection that closed without its disconnect reaching us.
+var ErrGone = errors.New("connection is gone")
+
+// Poster sends data down a connection.
+type Poster interface {
+	Post(ctx context.Context, connectionId string, data []byte) error
+}
+
+type discard struct{}
+
+func (discard) Post(ctx context.Context, connectionId string, data []byte) error {
+	return nil
+}
+
+// Discard is for when there's no WebSocket API to post to.
+var Discard Poster = discard{}
+
+type Broadcaster struct {
+	service Service
+	poster  Poster
+}
+
+func NewBroadcaster(service Service, poster Poster) *Broadcaster {
+	return &Broadcaster{
+		service: service,
+		poster:  poster,
+	}
+}
+
+// Broadcast sends message to everyone subscribed to its channel and reports how many
+// it reached. Connections that are gone are disconnected on the way.
+func (b *Broadcaster) Broadcast(ctx context.Context, message Message) int {
+	data, err := json.Marshal(message)
+	if err != nil {
+		logging.FromContext(ctx).Error("unsendable message", "type", message.Type, "err", err)
+		return 0
+	}
+
+	subscriptions := b.service.Subscribers(ctx, message.Channel)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	sent := 0
+	for _, subscription := range subscriptions {
+		wg.Add(1)
+		go func(connectionId string) {
+			defer wg.Done()
+			err := b.poster.Post(ctx, connectionId, data)
+			if errors.Is(err, ErrGone) {
+				b.service.Disconnect(ctx, connectionId)
+				return
+			} else if err != nil {
+				logging.FromContext(ctx).Warn("broadcast failed", "connectionId", connectionId, "err", err)
+				return
+			}
+			mutex.Lock()
+			sent++
+			mutex.Unlock()
+		}(subscription.ConnectionId)
+	}
+	wg.Wait()
+
+	return sent
+}
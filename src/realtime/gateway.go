@@ -0,0 +1,68 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"sammy.link/clock"
+)
+
+// ApiGatewayPoster posts to connections through the WebSocket API's management
+// endpoint, https://{api id}.execute-api.{region}.amazonaws.com/{stage}.
+type ApiGatewayPoster struct {
+	endpoint    string
+	region      string
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	client      *http.Client
+	clock       clock.Clock
+}
+
+func NewApiGatewayPoster(config aws.Config, endpoint string, clk clock.Clock) *ApiGatewayPoster {
+	return &ApiGatewayPoster{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      config.Region,
+		credentials: config.Credentials,
+		signer:      v4.NewSigner(),
+		client:      &http.Client{},
+		clock:       clk,
+	}
+}
+
+func (p *ApiGatewayPoster) Post(ctx context.Context, connectionId string, data []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/@connections/"+url.PathEscape(connectionId), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	credentials, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	if err := p.signer.SignHTTP(ctx, credentials, request, hex.EncodeToString(hash[:]), "execute-api", p.region, p.clock.Now()); err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("posting to %s: %s", connectionId, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+module sammy.link/realtime
+
+go 1.21.0
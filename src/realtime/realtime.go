@@ -0,0 +1,256 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/validation"
+)
+
+// Channels are what clients subscribe to, "league:<league>" for bets matched in a
+// league and "event:<event id>" for a listing's amounts.
+const (
+	LeagueChannel = "league"
+	EventChannel  = "event"
+)
+
+const (
+	MaxChannels      = 20
+	maxChannelLength = 100
+	// API Gateway closes a connection after two hours, so anything older is one
+	// whose disconnect never reached us.
+	connectionTtl = 2 * time.Hour
+)
+
+var ErrTooManyChannels = fmt.Errorf("a connection can subscribe to at most %d channels", MaxChannels)
+
+var channelPattern = regexp.MustCompile(`^(league|event):.+$`)
+
+func Channel(kind string, id string) string {
+	return kind + ":" + id
+}
+
+// ParseChannel splits a channel into its kind and id.
+func ParseChannel(channel string) (kind string, id string, ok bool) {
+	if !channelPattern.MatchString(channel) {
+		return "", "", false
+	}
+	kind, id, _ = strings.Cut(channel, ":")
+	return kind, id, true
+}
+
+// Connection is an open WebSocket and the member who opened it.
+type Connection struct {
+	Id          string
+	Email       string
+	Channels    []string
+	ConnectedAt time.Time
+}
+
+type ConnectionDynamoItem struct {
+	Id          string   `dynamodbav:"id"`
+	SortKey     string   `dynamodbav:"sortKey"`
+	Email       string   `dynamodbav:"email"`
+	Channels    []string `dynamodbav:"channels"`
+	ConnectedAt string   `dynamodbav:"date"`
+	Ttl         int64    `dynamodbav:"ttl"`
+	Version     int      `dynamodbav:"v"`
+}
+
+// Subscription is a connection listening on a channel, kept under the channel so a
+// broadcast is one query.
+type Subscription struct {
+	Channel      string
+	ConnectionId string
+	ConnectedAt  time.Time
+}
+
+type SubscriptionDynamoItem struct {
+	Id          string `dynamodbav:"id"`
+	SortKey     string `dynamodbav:"sortKey"`
+	ConnectedAt string `dynamodbav:"date"`
+	Ttl         int64  `dynamodbav:"ttl"`
+	Version     int    `dynamodbav:"v"`
+}
+
+const connectionSortKey = "WS"
+
+var (
+	connectionCodec   = key.Codec{Prefix: "WS", Parts: 1}
+	subscriptionCodec = key.Codec{Prefix: "SUB", Parts: 1}
+)
+
+func (dynamoItem ConnectionDynamoItem) GetItem() database.Item {
+	id := ""
+	if parts, err := connectionCodec.Decode(dynamoItem.Id); err == nil {
+		id = parts[0]
+	}
+	connectedAt, _ := key.ParseTime(dynamoItem.ConnectedAt)
+	channels := dynamoItem.Channels
+	if channels == nil {
+		channels = []string{}
+	}
+
+	return Connection{
+		Id:          id,
+		Email:       dynamoItem.Email,
+		Channels:    channels,
+		ConnectedAt: connectedAt,
+	}
+}
+
+func (item Connection) GetDynamoItem() database.DynamoItem {
+	return ConnectionDynamoItem{
+		Id:          connectionCodec.Encode(item.Id),
+		SortKey:     connectionSortKey,
+		Email:       item.Email,
+		Channels:    item.Channels,
+		ConnectedAt: key.Time(item.ConnectedAt),
+		Ttl:         item.ConnectedAt.Add(connectionTtl).Unix(),
+		Version:     database.Version,
+	}
+}
+
+func (dynamoItem SubscriptionDynamoItem) GetItem() database.Item {
+	channel := ""
+	if parts, err := subscriptionCodec.Decode(dynamoItem.Id); err == nil {
+		channel = parts[0]
+	}
+	connectedAt, _ := key.ParseTime(dynamoItem.ConnectedAt)
+
+	return Subscription{
+		Channel:      channel,
+		ConnectionId: dynamoItem.SortKey,
+		ConnectedAt:  connectedAt,
+	}
+}
+
+func (item Subscription) GetDynamoItem() database.DynamoItem {
+	return SubscriptionDynamoItem{
+		Id:          subscriptionCodec.Encode(item.Channel),
+		SortKey:     item.ConnectionId,
+		ConnectedAt: key.Time(item.ConnectedAt),
+		Ttl:         item.ConnectedAt.Add(connectionTtl).Unix(),
+		Version:     database.Version,
+	}
+}
+
+type Service interface {
+	Connect(ctx context.Context, connection Connection)
+	Get(ctx context.Context, connectionId string) (Connection, error)
+	Subscribe(ctx context.Context, connection Connection, channels []string) (Connection, error)
+	Disconnect(ctx context.Context, connectionId string)
+	Subscribers(ctx context.Context, channel string) []Subscription
+}
+
+type RealtimeService struct {
+	connections   database.Service[ConnectionDynamoItem, Connection]
+	subscriptions database.Service[SubscriptionDynamoItem, Subscription]
+}
+
+func NewService(connections database.Service[ConnectionDynamoItem, Connection], subscriptions database.Service[SubscriptionDynamoItem, Subscription]) Service {
+	return &RealtimeService{
+		connections:   connections,
+		subscriptions: subscriptions,
+	}
+}
+
+func (s *RealtimeService) Connect(ctx context.Context, connection Connection) {
+	if connection.Channels == nil {
+		connection.Channels = []string{}
+	}
+	s.connections.Write(ctx, []Connection{connection})
+}
+
+func (s *RealtimeService) Get(ctx context.Context, connectionId string) (Connection, error) {
+	return s.connections.Get(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(os.Getenv("TABLE_NAME")),
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: connectionCodec.Encode(connectionId)},
+			"sortKey": &types.AttributeValueMemberS{Value: connectionSortKey},
+		},
+	})
+}
+
+// Subscribe adds channels to the ones connection listens on, and hands back the
+// connection as saved.
+func (s *RealtimeService) Subscribe(ctx context.Context, connection Connection, channels []string) (Connection, error) {
+	added := make([]Subscription, 0, len(channels))
+	for _, channel := range channels {
+		if slices.Contains(connection.Channels, channel) {
+			continue
+		}
+		connection.Channels = append(connection.Channels, channel)
+		added = append(added, Subscription{Channel: channel, ConnectionId: connection.Id, ConnectedAt: connection.ConnectedAt})
+	}
+	if len(connection.Channels) > MaxChannels {
+		return connection, ErrTooManyChannels
+	}
+	if len(added) == 0 {
+		return connection, nil
+	}
+
+	s.subscriptions.Write(ctx, added)
+	s.connections.Write(ctx, []Connection{connection})
+	return connection, nil
+}
+
+// Disconnect forgets a connection and its subscriptions. Anything it misses expires
+// with the connection's ttl.
+func (s *RealtimeService) Disconnect(ctx context.Context, connectionId string) {
+	connection, err := s.Get(ctx, connectionId)
+	if err != nil {
+		return
+	}
+
+	deletes := make([]types.WriteRequest, 0, len(connection.Channels)+1)
+	for _, channel := range connection.Channels {
+		deletes = append(deletes, deleteRequest(subscriptionCodec.Encode(channel), connection.Id))
+	}
+	deletes = append(deletes, deleteRequest(connectionCodec.Encode(connection.Id), connectionSortKey))
+
+	s.connections.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{os.Getenv("TABLE_NAME"): deletes},
+	})
+}
+
+func deleteRequest(id string, sortKey string) types.WriteRequest {
+	return types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: id},
+		"sortKey": &types.AttributeValueMemberS{Value: sortKey},
+	}}}
+}
+
+func (s *RealtimeService) Subscribers(ctx context.Context, channel string) []Subscription {
+	return s.subscriptions.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: subscriptionCodec.Encode(channel)},
+		},
+	})
+}
+
+// CheckChannels reports channels that aren't a league or event channel, or that are
+// listed twice.
+func CheckChannels(v *validation.Validator, channels []string) bool {
+	if v.Int("channels", int64(len(channels))).Between(1, MaxChannels).Valid() {
+		for i, channel := range channels {
+			field := fmt.Sprintf("channels[%d]", i)
+			if v.String(field, channel).MaxLength(maxChannelLength).Matches(channelPattern, "league:<league> or event:<event id>").Valid() {
+				v.Check(!slices.Contains(channels[:i], channel), field, "is listed twice")
+			}
+		}
+	}
+	return v.Valid()
+}
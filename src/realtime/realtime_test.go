@@ -0,0 +1,147 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"sammy.link/clock"
+	"sammy.link/validation"
+)
+
+func TestDynamoItemRoundTrip(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	connection := Connection{Id: "L0SM9cOFvHcCIhw=", Email: "sam@sam.com", Channels: []string{"league:a|b"}, ConnectedAt: now}
+
+	dynamoItem := connection.GetDynamoItem().(ConnectionDynamoItem)
+	if dynamoItem.Ttl != now.Add(connectionTtl).Unix() {
+		t.Errorf("ttl = %d", dynamoItem.Ttl)
+	}
+	if got := dynamoItem.GetItem().(Connection); got.Id != connection.Id || !got.ConnectedAt.Equal(now) || !slices.Equal(got.Channels, connection.Channels) {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	subscription := Subscription{Channel: "league:a|b", ConnectionId: connection.Id, ConnectedAt: now}
+	if got := subscription.GetDynamoItem().(SubscriptionDynamoItem).GetItem().(Subscription); got != subscription {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestCheckChannels(t *testing.T) {
+	if v := validation.New(); !CheckChannels(v, []string{"league:default", "event:401547417"}) {
+		t.Fatalf("valid channels were rejected: %v", v.Err())
+	}
+
+	tests := map[string][]string{
+		"channels":    {},
+		"channels[0]": {"bets:default"},
+		"channels[1]": {"event:1", "event:1"},
+	}
+	for field, channels := range tests {
+		v := validation.New()
+		var got validation.Errors
+		if CheckChannels(v, channels) || !errors.As(v.Err(), &got) || len(got) != 1 || got[0].Field != field {
+			t.Errorf("expected only %s to be rejected but got %v", field, v.Err())
+		}
+	}
+
+	if kind, id, ok := ParseChannel(Channel(LeagueChannel, "a:b")); !ok || kind != LeagueChannel || id != "a:b" {
+		t.Errorf("ParseChannel() = %s, %s, %t", kind, id, ok)
+	}
+}
+
+func TestTicket(t *testing.T) {
+	now := time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)
+	secret := []byte("secret")
+	ticket := IssueTicket(secret, "sam@sam.com", now)
+
+	if email, err := ReadTicket(secret, ticket, now.Add(TicketTtl)); err != nil || email != "sam@sam.com" {
+		t.Errorf("ReadTicket() = %s, %v", email, err)
+	}
+	if _, err := ReadTicket(secret, ticket, now.Add(TicketTtl+time.Second)); err != ErrTicket {
+		t.Error("an expired ticket should be rejected")
+	}
+	if _, err := ReadTicket([]byte("other"), ticket, now); err != ErrTicket {
+		t.Error("a ticket signed with another secret should be rejected")
+	}
+
+	forged := IssueTicket([]byte("other"), "admin@sam.com", now)
+	payload, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(ticket, ".")
+	if _, err := ReadTicket(secret, payload+"."+signature, now); err != ErrTicket {
+		t.Error("a signature from another ticket should be rejected")
+	}
+}
+
+type registry struct {
+	Service
+	subscribers  []Subscription
+	disconnected []string
+}
+
+func (r *registry) Subscribers(ctx context.Context, channel string) []Subscription {
+	return r.subscribers
+}
+
+func (r *registry) Disconnect(ctx context.Context, connectionId string) {
+	r.disconnected = append(r.disconnected, connectionId)
+}
+
+type poster map[string]error
+
+func (p poster) Post(ctx context.Context, connectionId string, data []byte) error {
+	return p[connectionId]
+}
+
+func TestBroadcast(t *testing.T) {
+	r := &registry{subscribers: []Subscription{{ConnectionId: "open"}, {ConnectionId: "gone"}, {ConnectionId: "throttled"}}}
+	broadcaster := NewBroadcaster(r, poster{"gone": ErrGone, "throttled": errors.New("throttled")})
+
+	sent := broadcaster.Broadcast(context.TODO(), Message{Type: ListingMessage, Channel: "event:1"})
+
+	if sent != 1 {
+		t.Errorf("sent to %d connections", sent)
+	}
+	if !slices.Equal(r.disconnected, []string{"gone"}) {
+		t.Errorf("only connections that are gone should be disconnected but got %v", r.disconnected)
+	}
+}
+
+func TestApiGatewayPoster(t *testing.T) {
+	var request *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, _ := io.ReadAll(r.Body)
+		request, body = r, string(read)
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	credentials := aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+	})
+	p := NewApiGatewayPoster(aws.Config{Region: "us-east-1", Credentials: credentials}, server.URL+"/production/", clock.NewFake(time.Date(2023, 10, 1, 17, 0, 0, 0, time.UTC)))
+
+	if err := p.Post(context.TODO(), "L0SM9cOFvHcCIhw=", []byte(`{"type":"listing"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if request.URL.Path != "/production/@connections/L0SM9cOFvHcCIhw=" || body != `{"type":"listing"}` {
+		t.Errorf("posted %s to %s", body, request.URL.Path)
+	}
+	if authorization := request.Header.Get("Authorization"); !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/20231001/us-east-1/execute-api/aws4_request") {
+		t.Errorf("Authorization = %s", authorization)
+	}
+
+	if err := p.Post(context.TODO(), "gone", nil); err != ErrGone {
+		t.Errorf("a 410 should be %v but got %v", ErrGone, err)
+	}
+}
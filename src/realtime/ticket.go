@@ -0,0 +1,57 @@
+package realtime
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Browsers can't set headers on a WebSocket, so the JWT the HTTP API checks can't come
+// along. Instead the HTTP API hands out a short lived ticket, which the connect route
+// reads from the ticket query parameter.
+const TicketTtl = time.Minute
+
+var ErrTicket = errors.New("ticket is invalid or expired")
+
+type ticket struct {
+	Email   string `json:"email"`
+	Expires int64  `json:"exp"`
+}
+
+func IssueTicket(secret []byte, email string, now time.Time) string {
+	payload, _ := json.Marshal(ticket{Email: email, Expires: now.Add(TicketTtl).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(secret, encoded))
+}
+
+// ReadTicket is the email a ticket was issued to.
+func ReadTicket(secret []byte, value string, now time.Time) (string, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", ErrTicket
+	}
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, sign(secret, encoded)) {
+		return "", ErrTicket
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrTicket
+	}
+	var t ticket
+	if err := json.Unmarshal(payload, &t); err != nil || t.Email == "" || now.Unix() > t.Expires {
+		return "", ErrTicket
+	}
+	return t.Email, nil
+}
+
+func sign(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
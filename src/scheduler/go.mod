@@ -0,0 +1,3 @@
+module sammy.link/scheduler
+
+go 1.21.0
@@ -0,0 +1,157 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	schedules "github.com/aws/aws-sdk-go-v2/service/scheduler"
+	scheduletypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+	"sammy.link/clock"
+	"sammy.link/logging"
+	"sammy.link/util"
+)
+
+// ResolveAfter is how long after kickoff a game is expected to be final.
+const ResolveAfter = 4 * time.Hour
+
+type Event struct {
+	Id       string    `json:"id"`
+	Kind     string    `json:"kind"`
+	Date     time.Time `json:"date"`
+	AwayTeam string    `json:"awayTeam"`
+	HomeTeam string    `json:"homeTeam"`
+}
+
+// Target is the payload a schedule invokes the resolve lambda with. Attempt counts
+// how many times the same games have been rescheduled. RuleName is only set by the
+// EventBridge rules used before schedules, which have to be deleted once they fire.
+type Target struct {
+	RuleName string  `json:"ruleName,omitempty"`
+	Events   []Event `json:"events"`
+	Attempt  int     `json:"attempt"`
+}
+
+type Scheduler interface {
+	Schedule(ctx context.Context, at time.Time, target Target) error
+	Delete(ctx context.Context, ruleName string) error
+}
+
+// EventBridgeScheduler makes one-time EventBridge Scheduler schedules, which delete
+// themselves once they fire and don't count against the event bus's rule quota.
+type EventBridgeScheduler struct {
+	client    *schedules.Client
+	rules     *eventbridge.Client
+	targetArn string
+	roleArn   string
+	clock     clock.Clock
+}
+
+func NewService(ctx context.Context, targetArn string, roleArn string) Scheduler {
+	defaultConfig, err := util.GetAwsConfig(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("no aws config", "err", err)
+	}
+
+	return NewFromConfig(defaultConfig, targetArn, roleArn, clock.System)
+}
+
+// NewFromConfig schedules invocations of targetArn, made as roleArn.
+func NewFromConfig(config aws.Config, targetArn string, roleArn string, clk clock.Clock) Scheduler {
+	return &EventBridgeScheduler{
+		client:    schedules.NewFromConfig(config),
+		rules:     eventbridge.NewFromConfig(config),
+		targetArn: targetArn,
+		roleArn:   roleArn,
+		clock:     clk,
+	}
+}
+
+// Name names a one-time schedule. The time it was created is part of the name so
+// games added later for the same kickoff don't overwrite an existing schedule.
+func Name(at time.Time, now time.Time) string {
+	return fmt.Sprintf("resolve-%d-%d", at.Unix(), now.UnixNano())
+}
+
+func AtExpression(at time.Time) string {
+	return fmt.Sprintf("at(%s)", at.UTC().Format("2006-01-02T15:04:05"))
+}
+
+func (s *EventBridgeScheduler) Schedule(ctx context.Context, at time.Time, target Target) error {
+	jsonInput, _ := json.Marshal(target)
+
+	_, err := s.client.CreateSchedule(ctx, &schedules.CreateScheduleInput{
+		Name:                       aws.String(Name(at, s.clock.Now())),
+		ScheduleExpression:         aws.String(AtExpression(at)),
+		ScheduleExpressionTimezone: aws.String("UTC"),
+		FlexibleTimeWindow:         &scheduletypes.FlexibleTimeWindow{Mode: scheduletypes.FlexibleTimeWindowModeOff},
+		ActionAfterCompletion:      scheduletypes.ActionAfterCompletionDelete,
+		Target: &scheduletypes.Target{
+			Arn:     aws.String(s.targetArn),
+			RoleArn: aws.String(s.roleArn),
+			Input:   aws.String(string(jsonInput)),
+		},
+	})
+	return err
+}
+
+// Delete removes one of the rules made before schedules once it has fired, its
+// targets have to go first.
+func (s *EventBridgeScheduler) Delete(ctx context.Context, ruleName string) error {
+	_, err := s.rules.RemoveTargets(ctx, &eventbridge.RemoveTargetsInput{
+		Rule: aws.String(ruleName),
+		Ids:  []string{"resolve"},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.rules.DeleteRule(ctx, &eventbridge.DeleteRuleInput{
+		Name: aws.String(ruleName),
+	})
+	return err
+}
+
+// GroupByKickoff batches events that start at the same time so they share a schedule.
+func GroupByKickoff(events []Event) map[time.Time][]Event {
+	groups := make(map[time.Time][]Event)
+	for _, event := range events {
+		kickoff := event.Date.UTC()
+		groups[kickoff] = append(groups[kickoff], event)
+	}
+	return groups
+}
+
+type Scheduled struct {
+	At     time.Time
+	Target Target
+}
+
+// FakeScheduler remembers what it was asked to do instead of calling EventBridge.
+type FakeScheduler struct {
+	mutex     sync.Mutex
+	Scheduled []Scheduled
+	Deleted   []string
+}
+
+func (f *FakeScheduler) Schedule(ctx context.Context, at time.Time, target Target) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	target.Events = slices.Clone(target.Events)
+	f.Scheduled = append(f.Scheduled, Scheduled{At: at, Target: target})
+	return nil
+}
+
+func (f *FakeScheduler) Delete(ctx context.Context, ruleName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.Deleted = append(f.Deleted, ruleName)
+	return nil
+}
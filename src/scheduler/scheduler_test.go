@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAtExpression(t *testing.T) {
+	at := time.Date(2023, time.September, 8, 4, 20, 0, 0, time.UTC)
+
+	if expression := AtExpression(at); expression != "at(2023-09-08T04:20:00)" {
+		t.Fatalf("should fire once at 4:20 UTC but got %s", expression)
+	}
+
+	eastern := time.FixedZone("EDT", -4*60*60)
+	if expression := AtExpression(at.In(eastern)); expression != "at(2023-09-08T04:20:00)" {
+		t.Fatalf("should always schedule in UTC but got %s", expression)
+	}
+}
+
+func TestGroupByKickoff(t *testing.T) {
+	kickoff := time.Date(2023, time.September, 10, 17, 0, 0, 0, time.UTC)
+	groups := GroupByKickoff([]Event{
+		{Id: "1", Date: kickoff},
+		{Id: "2", Date: kickoff},
+		{Id: "3", Date: kickoff.Add(3 * time.Hour)},
+	})
+
+	if len(groups) != 2 || len(groups[kickoff]) != 2 {
+		t.Fatalf("the early games should share a schedule but got %+v", groups)
+	}
+}
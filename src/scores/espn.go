@@ -0,0 +1,85 @@
+package scores
+
+import (
+	"context"
+	"time"
+
+	"sammy.link/espn"
+	"sammy.link/sport"
+)
+
+type EspnProvider struct {
+	espnService espn.Service
+}
+
+func NewEspnProvider(espnService espn.Service) Provider {
+	return &EspnProvider{
+		espnService: espnService,
+	}
+}
+
+func (p *EspnProvider) Games(ctx context.Context, s sport.Sport, from time.Time, to time.Time) ([]Game, error) {
+	resp, err := p.espnService.GetEspnData(ctx, s.Sport, s.League, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return FromEspn(s, resp), nil
+}
+
+func (p *EspnProvider) Game(ctx context.Context, s sport.Sport, id string) (Game, error) {
+	resp, err := p.espnService.GetEspnEvent(ctx, s.Sport, s.League, id)
+	if err != nil {
+		return Game{}, err
+	}
+	return find(FromEspn(s, resp), id)
+}
+
+// FromEspn flattens a scoreboard response into games, skipping events that
+// don't have both teams yet.
+func FromEspn(s sport.Sport, resp espn.EspnResponse) []Game {
+	games := make([]Game, 0)
+	for _, espnSport := range resp.Sports {
+		for _, league := range espnSport.Leagues {
+			for _, event := range league.Events {
+				if len(event.Competitors) < 2 {
+					continue
+				}
+
+				games = append(games, Game{
+					Id:      event.Id,
+					Kind:    s.Kind,
+					Date:    event.Date,
+					Week:    event.Week,
+					Status:  event.Status,
+					Odds:    event.Odds.Details,
+					Link:    event.Link,
+					Clock:   event.Clock,
+					Period:  event.Period,
+					Summary: event.Summary,
+					Away:    fromCompetitor(event.Competitors[0]),
+					Home:    fromCompetitor(event.Competitors[1]),
+				})
+			}
+		}
+	}
+	return games
+}
+
+func fromCompetitor(competitor espn.EspnCompetitor) Team {
+	return Team{
+		Name:         competitor.Name,
+		Abbreviation: competitor.Abbreviation,
+		Record:       competitor.Record,
+		Score:        competitor.Score,
+		Winner:       competitor.Winner,
+	}
+}
+
+func find(games []Game, id string) (Game, error) {
+	for _, game := range games {
+		if game.Id == id {
+			return game, nil
+		}
+	}
+	return Game{}, ErrNotFound
+}
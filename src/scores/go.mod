@@ -0,0 +1,3 @@
+module sammy.link/scores
+
+go 1.21.0
@@ -0,0 +1,53 @@
+package scores
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"sammy.link/sport"
+)
+
+const (
+	Scheduled  = "pre"
+	InProgress = "in"
+	Final      = "post"
+)
+
+var ErrNotFound = errors.New("scores: game not found")
+
+type Team struct {
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	Record       string `json:"record"`
+	Score        string `json:"score"`
+	Winner       bool   `json:"winner"`
+}
+
+type Game struct {
+	Id     string    `json:"id"`
+	Kind   string    `json:"kind"`
+	Date   time.Time `json:"date"`
+	Week   int       `json:"week"`
+	Status string    `json:"status"`
+	Odds   string    `json:"odds"`
+	Link   string    `json:"link"`
+	// Clock, Period and Summary describe where a game in progress is at, e.g. "7:42", 3, "7:42 - 3rd".
+	Clock   string `json:"clock"`
+	Period  int    `json:"period"`
+	Summary string `json:"summary"`
+	Away    Team   `json:"away"`
+	Home    Team   `json:"home"`
+}
+
+func (g Game) Final() bool {
+	return g.Status == Final
+}
+
+// Provider is a source of games, odds and final scores.
+type Provider interface {
+	// Games lists every game of a sport played between from and to, inclusive of both days.
+	Games(ctx context.Context, s sport.Sport, from time.Time, to time.Time) ([]Game, error)
+	// Game looks up a single game, returning ErrNotFound when the provider doesn't know it.
+	Game(ctx context.Context, s sport.Sport, id string) (Game, error)
+}
@@ -0,0 +1,48 @@
+package scores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sammy.link/sport"
+)
+
+func TestFixtureGames(t *testing.T) {
+	nfl, _ := sport.Get("NFL")
+	provider := NewFixtureProvider("testdata")
+
+	games, err := provider.Games(context.TODO(), nfl, time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, time.September, 8, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(games) != 1 {
+		t.Fatalf("should only find thursday night's game but got %+v %v", games, err)
+	}
+
+	game := games[0]
+	if !game.Final() || game.Kind != "NFL" || game.Away.Abbreviation != "DET" || game.Home.Score != "20" || game.Odds != "KC -6.5" {
+		t.Fatalf("game should come back flattened but got %+v", game)
+	}
+}
+
+func TestFixtureGame(t *testing.T) {
+	nfl, _ := sport.Get("NFL")
+	provider := NewFixtureProvider("testdata")
+
+	game, err := provider.Game(context.TODO(), nfl, "401547397")
+	if err != nil || game.Final() || game.Home.Name != "New England Patriots" {
+		t.Fatalf("should find the eagles game but got %+v %v", game, err)
+	}
+
+	if _, err := provider.Game(context.TODO(), nfl, "1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown games should not be found but got %v", err)
+	}
+}
+
+func TestFixtureWithoutRecording(t *testing.T) {
+	nhl, _ := sport.Get("NHL")
+
+	games, err := NewFixtureProvider("testdata").Games(context.TODO(), nhl, time.Now(), time.Now())
+	if err != nil || len(games) != 0 {
+		t.Fatalf("a sport without a recording should have no games but got %+v %v", games, err)
+	}
+}
@@ -0,0 +1,3 @@
+module sammy.link/season
+
+go 1.21.0
@@ -0,0 +1,382 @@
+package season
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/util"
+)
+
+type Item struct {
+	League   string    `json:"league"`
+	Id       string    `json:"id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Archived bool      `json:"archived"`
+	// set once the season starts closing, after which the bets placed in it count
+	// towards its standings instead of the balances
+	Closing bool `json:"closing"`
+}
+
+type DynamoItem struct {
+	Id       string `dynamodbav:"id"`
+	SortKey  string `dynamodbav:"sortKey"`
+	Start    string `dynamodbav:"start"`
+	End      string `dynamodbav:"end"`
+	Archived bool   `dynamodbav:"archived"`
+	Closing  bool   `dynamodbav:"closing,omitempty"`
+	Version  int    `dynamodbav:"v"`
+}
+
+type StandingItem struct {
+	League string `json:"league"`
+	Season string `json:"season"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Total  int64  `json:"total"`
+	Rank   int    `json:"rank"`
+}
+
+type StandingDynamoItem struct {
+	Id      string `dynamodbav:"id"`
+	SortKey string `dynamodbav:"sortKey"`
+	Name    string `dynamodbav:"name"`
+	Total   int64  `dynamodbav:"amount"`
+	Rank    int    `dynamodbav:"rank"`
+	Version int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyName string `dynamodbav:"na,omitempty"`
+}
+
+type Service interface {
+	GetSeasons(ctx context.Context, league string) []Item
+	GetCurrent(ctx context.Context, league string, now time.Time) (Item, bool)
+	GetStandings(ctx context.Context, league string, season string) []StandingItem
+	Create(ctx context.Context, item Item)
+	Close(ctx context.Context, item Item) error
+	Snapshot(ctx context.Context, standing StandingItem) error
+	Archive(ctx context.Context, item Item)
+	Pay(ctx context.Context, league string, season string, changes map[string]int64, writes ...types.TransactWriteItem) error
+}
+
+type SeasonService struct {
+	databaseService         database.Service[DynamoItem, Item]
+	standingDatabaseService database.Service[StandingDynamoItem, StandingItem]
+}
+
+func NewService(databaseService database.Service[DynamoItem, Item], standingDatabaseService database.Service[StandingDynamoItem, StandingItem]) Service {
+	return &SeasonService{
+		databaseService:         databaseService,
+		standingDatabaseService: standingDatabaseService,
+	}
+}
+
+// IdOf names the season a date falls in. Seasons turn over on March 1st so the
+// playoffs in January and February count towards the season that started in the fall.
+func IdOf(date time.Time) string {
+	if date.Month() < time.March {
+		return strconv.Itoa(date.Year() - 1)
+	}
+	return strconv.Itoa(date.Year())
+}
+
+// WeekOf numbers the weeks of the default season a date falls in, for sports where
+// ESPN doesn't number the weeks itself.
+func WeekOf(date time.Time) int {
+	start := New("", date).Start
+	return int(date.Sub(start).Hours()/24)/7 + 1
+}
+
+// New returns the default season for a league containing date.
+func New(league string, date time.Time) Item {
+	year, _ := strconv.Atoi(IdOf(date))
+	start := time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC)
+	return Item{
+		League: league,
+		Id:     IdOf(start),
+		Start:  start,
+		End:    start.AddDate(1, 0, 0),
+	}
+}
+
+// Next returns the season that follows item and lasts just as long.
+func Next(item Item) Item {
+	return Item{
+		League: item.League,
+		Id:     IdOf(item.End),
+		Start:  item.End,
+		End:    item.End.Add(item.End.Sub(item.Start)),
+	}
+}
+
+func (item Item) Contains(date time.Time) bool {
+	return !date.Before(item.Start) && date.Before(item.End)
+}
+
+var (
+	idCodec         = key.Codec{Prefix: "SEASON", Parts: 1}
+	standingIdCodec = key.Codec{Prefix: "SEASON", Parts: 2}
+)
+
+func getId(league string) string {
+	return idCodec.Encode(league)
+}
+
+func getStandingId(league string, season string) string {
+	return standingIdCodec.Encode(league, season)
+}
+
+// IsStanding tells a final standing's id, SEASON|league|season, from a season's.
+func IsStanding(id string) bool {
+	return len(key.Split(id)) == standingIdCodec.Parts+1
+}
+
+func (item Item) GetDynamoItem() database.DynamoItem {
+	return DynamoItem{
+		Id:       getId(item.League),
+		SortKey:  item.Id,
+		Start:    item.Start.Format(time.RFC3339),
+		End:      item.End.Format(time.RFC3339),
+		Archived: item.Archived,
+		Closing:  item.Closing,
+		Version:  database.Version,
+	}
+}
+
+func (dynamoItem DynamoItem) upgrade() DynamoItem {
+	if dynamoItem.Version < 3 {
+		// the league was joined on without escaping
+		if ids := strings.SplitN(dynamoItem.Id, "|", 2); len(ids) == 2 {
+			dynamoItem.Id = getId(ids[1])
+		}
+	}
+	return dynamoItem
+}
+
+func (dynamoItem DynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
+	ids, err := idCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, idCodec.Parts)
+	}
+
+	start, _ := time.Parse(time.RFC3339, dynamoItem.Start)
+	end, _ := time.Parse(time.RFC3339, dynamoItem.End)
+	return Item{
+		League:   ids[0],
+		Id:       dynamoItem.SortKey,
+		Start:    start,
+		End:      end,
+		Archived: dynamoItem.Archived,
+		Closing:  dynamoItem.Closing,
+	}
+}
+
+func (item StandingItem) GetDynamoItem() database.DynamoItem {
+	return StandingDynamoItem{
+		Id:      getStandingId(item.League, item.Season),
+		SortKey: item.Email,
+		Name:    item.Name,
+		Total:   item.Total,
+		Rank:    item.Rank,
+		Version: database.Version,
+	}
+}
+
+func (dynamoItem StandingDynamoItem) upgrade() StandingDynamoItem {
+	if dynamoItem.Version < 1 {
+		dynamoItem.Name = util.Coalesce(dynamoItem.Name, dynamoItem.LegacyName)
+	}
+	if dynamoItem.Version < 3 {
+		// keys were joined without escaping, so the season took whatever was left
+		if ids := strings.SplitN(dynamoItem.Id, "|", 3); len(ids) == 3 {
+			dynamoItem.Id = getStandingId(ids[1], ids[2])
+		}
+	}
+	return dynamoItem
+}
+
+func (dynamoItem StandingDynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
+	ids, err := standingIdCodec.Decode(dynamoItem.Id)
+	if err != nil {
+		slog.Warn("malformed key", "err", err)
+		ids = make([]string, standingIdCodec.Parts)
+	}
+	return StandingItem{
+		League: ids[0],
+		Season: ids[1],
+		Email:  dynamoItem.SortKey,
+		Name:   dynamoItem.Name,
+		Total:  dynamoItem.Total,
+		Rank:   dynamoItem.Rank,
+	}
+}
+
+func (s *SeasonService) GetSeasons(ctx context.Context, league string) []Item {
+	seasons := s.databaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: getId(league)},
+		},
+	})
+
+	slices.SortFunc(seasons, func(a Item, b Item) int {
+		return a.Start.Compare(b.Start)
+	})
+	return seasons
+}
+
+func (s *SeasonService) GetCurrent(ctx context.Context, league string, now time.Time) (Item, bool) {
+	for _, item := range s.GetSeasons(ctx, league) {
+		if item.Contains(now) {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+func (s *SeasonService) GetStandings(ctx context.Context, league string, season string) []StandingItem {
+	standings := s.standingDatabaseService.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(os.Getenv("TABLE_NAME")),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: getStandingId(league, season)},
+		},
+	})
+
+	// bets settled after the close move totals around, so the stored ranks go stale
+	return Rank(standings)
+}
+
+func (s *SeasonService) Create(ctx context.Context, item Item) {
+	s.databaseService.Write(ctx, []Item{item})
+}
+
+func seasonKey(league string, season string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: getId(league)},
+		"sortKey": &types.AttributeValueMemberS{Value: season},
+	}
+}
+
+func standingKey(league string, season string, email string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: getStandingId(league, season)},
+		"sortKey": &types.AttributeValueMemberS{Value: email},
+	}
+}
+
+// Close marks a season as closing. From then on its bets are paid into its standings
+// by Pay, since the balances are about to be reset for the next season.
+func (s *SeasonService) Close(ctx context.Context, item Item) error {
+	_, err := s.databaseService.UpdateReturning(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(os.Getenv("TABLE_NAME")),
+		Key:              seasonKey(item.League, item.Id),
+		UpdateExpression: aws.String("SET closing = :true"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true": &types.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+	return err
+}
+
+// OpenCheck holds a transaction to season not having started closing, for paying a
+// bet placed in it out of the balances.
+func OpenCheck(league string, season string) types.TransactWriteItem {
+	return types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
+		TableName:           aws.String(os.Getenv("TABLE_NAME")),
+		Key:                 seasonKey(league, season),
+		ConditionExpression: aws.String("attribute_not_exists(closing)"),
+	}}
+}
+
+// Snapshot adds a member's final balance to their standing unless it has been
+// already, so a close retried after some balances were reset keeps the totals from
+// before. Bets Pay settled in between are kept too.
+func (s *SeasonService) Snapshot(ctx context.Context, standing StandingItem) error {
+	_, err := s.standingDatabaseService.UpdateReturning(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(os.Getenv("TABLE_NAME")),
+		Key:                      standingKey(standing.League, standing.Season, standing.Email),
+		ConditionExpression:      aws.String("attribute_not_exists(snapshot)"),
+		ExpressionAttributeNames: map[string]string{"#name": "name", "#rank": "rank"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":name":   &types.AttributeValueMemberS{Value: standing.Name},
+			":amount": &types.AttributeValueMemberN{Value: strconv.FormatInt(standing.Total, 10)},
+			":rank":   &types.AttributeValueMemberN{Value: strconv.Itoa(standing.Rank)},
+			":v":      &types.AttributeValueMemberN{Value: strconv.Itoa(database.Version)},
+			":true":   &types.AttributeValueMemberBOOL{Value: true},
+		},
+		UpdateExpression: aws.String("SET #name = :name, #rank = :rank, v = :v, snapshot = :true ADD amount :amount"),
+	})
+	if database.ConditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// Archive marks a season as archived, once its standings are stored and balances
+// reset.
+func (s *SeasonService) Archive(ctx context.Context, item Item) {
+	item.Closing = true
+	item.Archived = true
+	s.databaseService.Write(ctx, []Item{item})
+}
+
+// Pay changes members' standings in a closing season by changes, keyed by email, in
+// one transaction with writes, which come first.
+func (s *SeasonService) Pay(ctx context.Context, league string, season string, changes map[string]int64, writes ...types.TransactWriteItem) error {
+	items := slices.Clone(writes)
+	for email, amount := range changes {
+		if amount != 0 {
+			items = append(items, types.TransactWriteItem{Update: &types.Update{
+				TableName: aws.String(os.Getenv("TABLE_NAME")),
+				Key:       standingKey(league, season, email),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":amount": &types.AttributeValueMemberN{Value: strconv.FormatInt(amount, 10)},
+				},
+				UpdateExpression: aws.String("ADD amount :amount"),
+			}})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return s.standingDatabaseService.TransactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+}
+
+// Rank orders final totals from highest to lowest, giving equal totals the same rank.
+func Rank(standings []StandingItem) []StandingItem {
+	ranked := slices.Clone(standings)
+	slices.SortStableFunc(ranked, func(a StandingItem, b StandingItem) int {
+		if a.Total > b.Total {
+			return -1
+		} else if a.Total < b.Total {
+			return 1
+		}
+		return strings.Compare(a.Email, b.Email)
+	})
+
+	for i := range ranked {
+		if i > 0 && ranked[i].Total == ranked[i-1].Total {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+	return ranked
+}
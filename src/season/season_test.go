@@ -0,0 +1,77 @@
+package season
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	item := New("default", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC))
+
+	if item.Id != "2023" || !item.Contains(time.Date(2024, time.February, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("wild card weekend should fall in the 2023 season but got %+v", item)
+	}
+
+	if item.Contains(item.End) {
+		t.Fatalf("a season should not contain its end %+v", item)
+	}
+}
+
+func TestWeekOf(t *testing.T) {
+	if week := WeekOf(time.Date(2024, time.March, 7, 23, 0, 0, 0, time.UTC)); week != 1 {
+		t.Fatalf("the first seven days should be week 1 but got %d", week)
+	}
+
+	if week := WeekOf(time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC)); week != 2 {
+		t.Fatalf("the eighth day should start week 2 but got %d", week)
+	}
+
+	if week := WeekOf(time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)); week != 53 {
+		t.Fatalf("the last day of the season should be week 53 but got %d", week)
+	}
+}
+
+func TestNext(t *testing.T) {
+	next := Next(New("default", time.Date(2023, time.September, 7, 0, 0, 0, 0, time.UTC)))
+
+	if next.Id != "2024" || next.League != "default" || !next.Start.Equal(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("the season after 2023 should be 2024 but got %+v", next)
+	}
+}
+
+func TestRank(t *testing.T) {
+	ranked := Rank([]StandingItem{
+		{Email: "greg", Total: -20},
+		{Email: "sam", Total: 40},
+		{Email: "pat", Total: 40},
+	})
+
+	if ranked[0].Email != "pat" || ranked[0].Rank != 1 || ranked[1].Rank != 1 || ranked[2].Rank != 3 {
+		t.Fatalf("pat and sam should tie for first but got %+v", ranked)
+	}
+}
+
+func TestKeysEscapeTheSeparator(t *testing.T) {
+	standing := StandingItem{League: "fam|ily", Season: "2023", Email: "sam@sam.com", Total: 10, Rank: 1}
+	dynamoItem := standing.GetDynamoItem().(StandingDynamoItem)
+	if roundTrip := dynamoItem.GetItem().(StandingItem); roundTrip != standing {
+		t.Errorf("expected %+v but got %+v", standing, roundTrip)
+	}
+	if !IsStanding(dynamoItem.Id) || IsStanding((Item{League: "fam|ily"}).GetDynamoItem().(DynamoItem).Id) {
+		t.Errorf("should tell standings from seasons by their id")
+	}
+
+	item := New("fam|ily", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC))
+	if roundTrip := item.GetDynamoItem().(DynamoItem).GetItem().(Item); roundTrip.League != item.League || roundTrip.Id != item.Id {
+		t.Errorf("expected %+v but got %+v", item, roundTrip)
+	}
+}
+
+func TestLegacyKeys(t *testing.T) {
+	if item := (DynamoItem{Id: "SEASON|default", SortKey: "2023"}).GetItem().(Item); item.League != "default" || item.Id != "2023" {
+		t.Errorf("should read a key from before escaping but got %+v", item)
+	}
+	if item := (StandingDynamoItem{Id: "SEASON|default|2023", SortKey: "sam@sam.com"}).GetItem().(StandingItem); item.League != "default" || item.Season != "2023" {
+		t.Errorf("should read a key from before escaping but got %+v", item)
+	}
+}
@@ -0,0 +1,3 @@
+module sammy.link/sport
+
+go 1.21.0
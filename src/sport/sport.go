@@ -0,0 +1,111 @@
+package sport
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+type Sport struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Sport  string `json:"sport"`
+	League string `json:"league"`
+	Line   string `json:"line"`
+	// FixedLine is the handicap the favorite gives in sports where ESPN quotes a
+	// moneyline instead of a point spread, e.g. the 1.5 run line in baseball.
+	FixedLine float64 `json:"fixedLine"`
+}
+
+var Sports = []Sport{
+	{Kind: "NFL", Name: "NFL", Sport: "football", League: "nfl", Line: "spread"},
+	{Kind: "CFB", Name: "College Football", Sport: "football", League: "college-football", Line: "spread"},
+	{Kind: "NBA", Name: "NBA", Sport: "basketball", League: "nba", Line: "spread"},
+	{Kind: "CBB", Name: "College Basketball", Sport: "basketball", League: "mens-college-basketball", Line: "spread"},
+	{Kind: "MLB", Name: "MLB", Sport: "baseball", League: "mlb", Line: "run line", FixedLine: 1.5},
+	{Kind: "NHL", Name: "NHL", Sport: "hockey", League: "nhl", Line: "puck line", FixedLine: 1.5},
+	{Kind: "EPL", Name: "Premier League", Sport: "soccer", League: "eng.1", Line: "goal line", FixedLine: 0.5},
+	{Kind: "MLS", Name: "MLS", Sport: "soccer", League: "usa.1", Line: "goal line", FixedLine: 0.5},
+}
+
+const (
+	Away = "AWAY"
+	Home = "HOME"
+	Push = "PUSH"
+)
+
+// DefaultKinds are the sports a league plays when it hasn't picked any.
+var DefaultKinds = []string{"NFL", "CFB"}
+
+var spreadExpression = regexp.MustCompile(`^(?P<Team>\S+)\s+(?P<Points>\S+)$`)
+
+func Get(kind string) (Sport, bool) {
+	i := slices.IndexFunc(Sports, func(s Sport) bool {
+		return s.Kind == kind
+	})
+	if i < 0 {
+		return Sport{}, false
+	}
+	return Sports[i], true
+}
+
+// Spread turns the odds ESPN lists for a game into the line bets are placed against.
+// An empty string means the game has no line yet.
+func (s Sport) Spread(details string, awayAbbreviation string) string {
+	if details == "" || details == "OFF" {
+		return ""
+	}
+
+	if details == "EVEN" {
+		return fmt.Sprintf("%s %d", awayAbbreviation, 0)
+	}
+
+	if s.FixedLine > 0 {
+		team, _, ok := ParseSpread(details)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("%s -%.1f", team, s.FixedLine)
+	}
+
+	return details
+}
+
+func ParseSpread(spread string) (string, float64, bool) {
+	match := spreadExpression.FindStringSubmatch(spread)
+	if match == nil {
+		return "", 0, false
+	}
+
+	points, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return match[1], points, true
+}
+
+// Adjust applies a spread to the final score so the team with the higher
+// adjusted score is the one that covered.
+func Adjust(spread string, awayAbbreviation string, awayScore float64, homeScore float64) (float64, float64) {
+	team, points, ok := ParseSpread(spread)
+	if !ok {
+		return awayScore, homeScore
+	}
+
+	if team == awayAbbreviation {
+		return awayScore + points, homeScore
+	}
+	return awayScore, homeScore + points
+}
+
+// Cover says which side is beating the spread with the given score, or Push when neither is.
+func Cover(spread string, awayAbbreviation string, awayScore float64, homeScore float64) string {
+	awayScore, homeScore = Adjust(spread, awayAbbreviation, awayScore, homeScore)
+	if awayScore > homeScore {
+		return Away
+	} else if homeScore > awayScore {
+		return Home
+	}
+	return Push
+}
@@ -0,0 +1,66 @@
+package sport
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	mlb, ok := Get("MLB")
+	if !ok || mlb.Sport != "baseball" || mlb.League != "mlb" {
+		t.Fatalf("should find baseball but got %+v", mlb)
+	}
+
+	if _, ok := Get("XFL"); ok {
+		t.Fatalf("XFL isn't a supported sport")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	nfl, _ := Get("NFL")
+	if spread := nfl.Spread("KC -7.5", "DET"); spread != "KC -7.5" {
+		t.Fatalf("football should keep the spread but got %s", spread)
+	}
+
+	if spread := nfl.Spread("EVEN", "DET"); spread != "DET 0" {
+		t.Fatalf("an even game should be a pick'em but got %s", spread)
+	}
+
+	if spread := nfl.Spread("OFF", "DET"); spread != "" {
+		t.Fatalf("a game without odds shouldn't have a spread but got %s", spread)
+	}
+
+	mlb, _ := Get("MLB")
+	if spread := mlb.Spread("NYY -155", "BOS"); spread != "NYY -1.5" {
+		t.Fatalf("the favorite should give the run line but got %s", spread)
+	}
+
+	epl, _ := Get("EPL")
+	if spread := epl.Spread("ARS -250", "CHE"); spread != "ARS -0.5" {
+		t.Fatalf("the favorite should give the goal line but got %s", spread)
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	away, home := Adjust("NYY -1.5", "NYY", 4, 3)
+	if away >= home {
+		t.Fatalf("winning by one shouldn't cover the run line but got %f-%f", away, home)
+	}
+
+	away, home = Adjust("BOS -3.0", "NYY", 17, 21)
+	if away != 17 || home != 18 {
+		t.Fatalf("the spread should come off the home score but got %f-%f", away, home)
+	}
+
+	away, home = Adjust("garbage", "NYY", 1, 2)
+	if away != 1 || home != 2 {
+		t.Fatalf("an unreadable spread should leave the score alone but got %f-%f", away, home)
+	}
+}
+
+func TestCover(t *testing.T) {
+	if cover := Cover("KC -6.5", "DET", 21, 20); cover != Away {
+		t.Fatalf("the lions should cover but got %s", cover)
+	}
+
+	if cover := Cover("KC -3.0", "DET", 17, 20); cover != Push {
+		t.Fatalf("a three point win should push but got %s", cover)
+	}
+}
@@ -2,20 +2,31 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"sammy.link/database"
+	"sammy.link/key"
+	"sammy.link/league"
+	"sammy.link/util"
+	"sammy.link/validation"
 )
 
 type DynamoItem struct {
 	Id      string `dynamodbav:"id"`
 	SortKey string `dynamodbav:"sortKey"`
-	Name    string `dynamodbav:"na"`
+	Name    string `dynamodbav:"name"`
+	Version int    `dynamodbav:"v"`
+	// read from items written before version 1, never written
+	LegacyName string `dynamodbav:"na,omitempty"`
 }
 
 type Item struct {
@@ -28,7 +39,18 @@ type Service interface {
 	GetUser(ctx context.Context, user string) []Item
 	Create(ctx context.Context, item Item)
 	Update(ctx context.Context, user string, amount int64)
-	UpdateName(ctx context.Context, item Item)
+	Rename(ctx context.Context, item Item, oldName string) error
+}
+
+const MaxNameLength = 24
+
+// names show up in leaderboards and bet slips, so they stick to printable characters
+// without leading or trailing spaces
+var namePattern = regexp.MustCompile(`^[\p{L}\p{N}_.'-]+( [\p{L}\p{N}_.'-]+)*$`)
+
+func CheckName(v *validation.Validator, field string, name string) bool {
+	return v.String(field, name).Required().MaxLength(MaxNameLength).
+		Matches(namePattern, "letters, numbers, spaces, dots, dashes, apostrophes or underscores").Valid()
 }
 
 type UserService struct {
@@ -41,7 +63,16 @@ func NewService(databaseService database.Service[DynamoItem, Item]) Service {
 	}
 }
 
+func (dynamoItem DynamoItem) upgrade() DynamoItem {
+	if dynamoItem.Version < 1 {
+		dynamoItem.Name = util.Coalesce(dynamoItem.Name, dynamoItem.LegacyName)
+	}
+	return dynamoItem
+}
+
 func (dynamoItem DynamoItem) GetItem() database.Item {
+	dynamoItem = dynamoItem.upgrade()
+
 	return Item{
 		Email:  strings.Split(dynamoItem.Id, "|")[1],
 		Name:   dynamoItem.Name,
@@ -54,6 +85,7 @@ func (item Item) GetDynamoItem() database.DynamoItem {
 		Id:      getId(item.Email),
 		SortKey: item.League,
 		Name:    item.Name,
+		Version: database.Version,
 	}
 }
 
@@ -61,19 +93,109 @@ func getId(email string) string {
 	return fmt.Sprintf("U|%s", email)
 }
 
-func (s *UserService) UpdateName(ctx context.Context, item Item) {
-	fmt.Printf("item = %+v", item)
-	s.databaseService.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		Key: map[string]types.AttributeValue{
-			"id":      &types.AttributeValueMemberS{Value: getId(item.Email)},
-			"sortKey": &types.AttributeValueMemberS{Value: item.League},
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":name": &types.AttributeValueMemberS{Value: item.Name},
-		},
-		TableName:        aws.String(os.Getenv("TABLE_NAME")),
-		UpdateExpression: aws.String("SET na = :name"),
+var (
+	ErrNameTaken   = errors.New("name is taken")
+	ErrNameChanged = errors.New("name changed during the rename")
+	ErrNotMember   = errors.New("not a member of the league")
+)
+
+// NameDynamoItem reserves a name in a league for the member in Email. Names are
+// unique ignoring case, so the sort key is the folded name.
+type NameDynamoItem struct {
+	Id      string `dynamodbav:"id"`
+	SortKey string `dynamodbav:"sortKey"`
+	Email   string `dynamodbav:"email"`
+	Version int    `dynamodbav:"v"`
+}
+
+var nameIdCodec = key.Codec{Prefix: "NAME", Parts: 1}
+
+func FoldName(name string) string {
+	return strings.ToLower(name)
+}
+
+func nameKey(league string, name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":      &types.AttributeValueMemberS{Value: nameIdCodec.Encode(league)},
+		"sortKey": &types.AttributeValueMemberS{Value: FoldName(name)},
+	}
+}
+
+// Rename moves item's member from oldName to item.Name in one transaction: the new
+// name is reserved, the old reservation released, and both the user and league
+// records renamed. It fails with ErrNameTaken if someone else holds the name,
+// ErrNotMember if item's member isn't in the league and ErrNameChanged if they were
+// renamed since oldName was read.
+func (s *UserService) Rename(ctx context.Context, item Item, oldName string) error {
+	err := s.databaseService.TransactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: RenameWrites(item, oldName)})
+	if err == nil {
+		return nil
+	}
+
+	failed := database.CancelledBy(err)
+	if slices.Contains(failed, 0) {
+		return ErrNameTaken
+	}
+	if slices.Contains(failed, 2) {
+		return ErrNotMember
+	}
+	if len(failed) > 0 {
+		return ErrNameChanged
+	}
+	return err
+}
+
+// RenameWrites are the writes Rename makes, for transactions that rename alongside
+// other changes. The reservation always comes first and the league record third.
+func RenameWrites(item Item, oldName string) []types.TransactWriteItem {
+	table := aws.String(os.Getenv("TABLE_NAME"))
+	reservation, _ := attributevalue.MarshalMap(NameDynamoItem{
+		Id:      nameIdCodec.Encode(item.League),
+		SortKey: FoldName(item.Name),
+		Email:   item.Email,
+		Version: database.Version,
 	})
+	held := map[string]types.AttributeValue{
+		":email": &types.AttributeValueMemberS{Value: item.Email},
+	}
+
+	// the reservation has to come first, Rename tells a taken name apart by it
+	items := []types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:                 table,
+			Item:                      reservation,
+			ConditionExpression:       aws.String("attribute_not_exists(id) OR email = :email"),
+			ExpressionAttributeValues: held,
+		}},
+		{Update: &types.Update{
+			TableName: table,
+			Key: map[string]types.AttributeValue{
+				"id":      &types.AttributeValueMemberS{Value: getId(item.Email)},
+				"sortKey": &types.AttributeValueMemberS{Value: item.League},
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":name": &types.AttributeValueMemberS{Value: item.Name},
+				":old":  &types.AttributeValueMemberS{Value: oldName},
+			},
+			ExpressionAttributeNames: map[string]string{"#name": "name"},
+			// items written before version 1 keep the name under "na"
+			ConditionExpression: aws.String("attribute_exists(id) AND (#name = :old OR na = :old OR (attribute_not_exists(#name) AND attribute_not_exists(na)))"),
+			UpdateExpression:    aws.String("SET #name = :name REMOVE na"),
+		}},
+		{Update: league.NameUpdate(item.League, item.Email, item.Name)},
+	}
+
+	// names from before reservations have nothing to release, which the condition allows
+	if oldName != "" && FoldName(oldName) != FoldName(item.Name) {
+		items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+			TableName:                 table,
+			Key:                       nameKey(item.League, oldName),
+			ConditionExpression:       aws.String("attribute_not_exists(id) OR email = :email"),
+			ExpressionAttributeValues: held,
+		}})
+	}
+
+	return items
 }
 
 func (s *UserService) GetUser(ctx context.Context, email string) []Item {
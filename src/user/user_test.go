@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sammy.link/database"
+	"sammy.link/validation"
+)
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"sam", "Sam S.", "o'neil", "José_99", "big-dog"} {
+		if !CheckName(validation.New(), "name", name) {
+			t.Errorf("%q should be allowed", name)
+		}
+	}
+
+	for _, name := range []string{"", "   ", " sam", "sam ", "two  spaces", "<script>", "sam|admin", strings.Repeat("a", MaxNameLength+1)} {
+		if CheckName(validation.New(), "name", name) {
+			t.Errorf("%q should be rejected", name)
+		}
+	}
+}
+
+type transactions struct {
+	database.Service[DynamoItem, Item]
+	inputs []*dynamodb.TransactWriteItemsInput
+	err    error
+}
+
+func (t *transactions) TransactWrite(ctx context.Context, params *dynamodb.TransactWriteItemsInput) error {
+	t.inputs = append(t.inputs, params)
+	return t.err
+}
+
+func cancelled(codes ...string) error {
+	reasons := make([]types.CancellationReason, len(codes))
+	for i, code := range codes {
+		reasons[i] = types.CancellationReason{Code: aws.String(code)}
+	}
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestRename(t *testing.T) {
+	item := Item{Email: "sam@sam.com", Name: "Sammy", League: "default"}
+
+	db := &transactions{}
+	if err := NewService(db).Rename(context.TODO(), item, "sam"); err != nil {
+		t.Fatal(err)
+	}
+
+	transaction := db.inputs[0].TransactItems
+	if len(transaction) != 4 || transaction[0].Put == nil || transaction[3].Delete == nil {
+		t.Fatalf("should reserve, rename both records and release the old name but got %+v", transaction)
+	}
+	reserved := transaction[0].Put.Item
+	if reserved["id"].(*types.AttributeValueMemberS).Value != "NAME|default" || reserved["sortKey"].(*types.AttributeValueMemberS).Value != "sammy" {
+		t.Fatalf("should reserve the folded name in the league but got %+v", reserved)
+	}
+	if transaction[3].Delete.Key["sortKey"].(*types.AttributeValueMemberS).Value != "sam" {
+		t.Fatalf("should release the old name but got %+v", transaction[3].Delete.Key)
+	}
+
+	for _, old := range []string{"", "SAMMY"} {
+		if transaction := RenameWrites(item, old); len(transaction) != 3 {
+			t.Fatalf("renaming from %q has nothing to release but got %+v", old, transaction)
+		}
+	}
+
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{cancelled("ConditionalCheckFailed", "None", "None", "None"), ErrNameTaken},
+		{cancelled("None", "ConditionalCheckFailed", "None", "None"), ErrNameChanged},
+		{cancelled("None", "None", "None", "ConditionalCheckFailed"), ErrNameChanged},
+		{cancelled("None", "ConditionalCheckFailed", "ConditionalCheckFailed", "None"), ErrNotMember},
+	}
+	for _, test := range tests {
+		if err := NewService(&transactions{err: test.err}).Rename(context.TODO(), item, "sam"); !errors.Is(err, test.want) {
+			t.Errorf("Rename() = %v, want %v", err, test.want)
+		}
+	}
+
+	throttled := errors.New("throttled")
+	if err := NewService(&transactions{err: throttled}).Rename(context.TODO(), item, "sam"); err != throttled {
+		t.Errorf("Rename() = %v, want the error as is", err)
+	}
+}
@@ -21,6 +21,12 @@ func ApigatewayResponse(body string, statusCode int) (events.APIGatewayV2HTTPRes
 		StatusCode: statusCode}, nil
 }
 
+// WebsocketResponse answers a WebSocket route, which only reaches the client on routes
+// set up to return one.
+func WebsocketResponse(body string, statusCode int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{Body: body, StatusCode: statusCode}, nil
+}
+
 func GetAwsConfig(ctx context.Context) (aws.Config, error) {
 	cfg, err := config.LoadDefaultConfig(ctx)
 	return cfg, err
@@ -42,3 +48,20 @@ func Min[T int64 | int](a, b T) T {
 	}
 	return b
 }
+
+// Coalesce returns the first of values that isn't the zero value.
+func Coalesce[T comparable](values ...T) T {
+	var zero T
+	for _, value := range values {
+		if value != zero {
+			return value
+		}
+	}
+	return zero
+}
+
+// Page is the response body of a paginated endpoint. Cursor is empty on the last page.
+type Page[T any] struct {
+	Items  []T    `json:"items"`
+	Cursor string `json:"cursor"`
+}
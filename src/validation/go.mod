@@ -0,0 +1,3 @@
+module sammy.link/validation
+
+go 1.21.0
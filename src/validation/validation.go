@@ -0,0 +1,259 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"sammy.link/util"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is every field that failed, in the order they were checked.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fmt.Sprintf("%s %s", fieldError.Field, fieldError.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+type Response struct {
+	util.DefaultResponse
+	Errors Errors `json:"errors"`
+}
+
+// BadRequest answers with the fields in err, or with its message when it isn't a
+// validation error.
+func BadRequest(err error) (events.APIGatewayV2HTTPResponse, error) {
+	resp := Response{DefaultResponse: util.DefaultResponse{Message: "invalid request"}, Errors: Errors{}}
+
+	var fieldErrors Errors
+	if errors.As(err, &fieldErrors) {
+		resp.Errors = fieldErrors
+	} else {
+		resp.Message = err.Error()
+	}
+
+	body, _ := json.Marshal(resp)
+	return util.ApigatewayResponse(string(body), 400)
+}
+
+// Validator collects the errors from a request's fields. Each field only reports the
+// first rule it breaks.
+type Validator struct {
+	prefix string
+	errors *Errors
+	parent *Validator
+	count  int
+}
+
+func New() *Validator {
+	return &Validator{errors: &Errors{}}
+}
+
+// At checks the fields of a nested value, e.g. v.At("bids[0]") reports bids[0].amount.
+func (v *Validator) At(prefix string) *Validator {
+	return &Validator{prefix: v.field(prefix), errors: v.errors, parent: v}
+}
+
+func (v *Validator) field(name string) string {
+	if v.prefix == "" {
+		return name
+	}
+	return v.prefix + "." + name
+}
+
+func (v *Validator) Add(field string, message string) {
+	*v.errors = append(*v.errors, FieldError{Field: v.field(field), Message: message})
+	for counted := v; counted != nil; counted = counted.parent {
+		counted.count++
+	}
+}
+
+// Check adds message to field unless ok, and reports ok.
+func (v *Validator) Check(ok bool, field string, message string) bool {
+	if !ok {
+		v.Add(field, message)
+	}
+	return ok
+}
+
+// Valid reports whether none of v's fields, or those nested under it, failed.
+func (v *Validator) Valid() bool {
+	return v.count == 0
+}
+
+// Err is every error so far, including ones outside v.
+func (v *Validator) Err() error {
+	if len(*v.errors) == 0 {
+		return nil
+	}
+	return slices.Clone(*v.errors)
+}
+
+// Decode reads a JSON body into out, reporting a body that isn't JSON against "body".
+func (v *Validator) Decode(body string, out any) bool {
+	if err := json.Unmarshal([]byte(body), out); err != nil {
+		v.Add("body", "must be valid JSON")
+		return false
+	}
+	return true
+}
+
+type String struct {
+	v      *Validator
+	field  string
+	value  string
+	failed bool
+}
+
+func (v *Validator) String(field string, value string) *String {
+	return &String{v: v, field: field, value: value}
+}
+
+func (s *String) check(ok bool, message string) *String {
+	if !s.failed && !ok {
+		s.v.Add(s.field, message)
+		s.failed = true
+	}
+	return s
+}
+
+func (s *String) Required() *String {
+	return s.check(strings.TrimSpace(s.value) != "", "is required")
+}
+
+// MaxLength counts characters, not bytes.
+func (s *String) MaxLength(n int) *String {
+	return s.check(utf8.RuneCountInString(s.value) <= n, fmt.Sprintf("must be at most %d characters", n))
+}
+
+// Matches takes a description of the pattern for the message, since the expression
+// itself means little to a caller.
+func (s *String) Matches(pattern *regexp.Regexp, description string) *String {
+	return s.check(pattern.MatchString(s.value), "must be "+description)
+}
+
+func (s *String) OneOf(values ...string) *String {
+	return s.check(slices.Contains(values, s.value), "must be one of "+strings.Join(values, ", "))
+}
+
+// Equals is for values that have to match what's stored.
+func (s *String) Equals(value string) *String {
+	return s.check(s.value == value, fmt.Sprintf("must be %q", value))
+}
+
+func (s *String) Valid() bool {
+	return !s.failed
+}
+
+type Int struct {
+	v      *Validator
+	field  string
+	value  int64
+	failed bool
+}
+
+func (v *Validator) Int(field string, value int64) *Int {
+	return &Int{v: v, field: field, value: value}
+}
+
+// Number parses a path or query parameter.
+func (v *Validator) Number(field string, value string) *Int {
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	i := &Int{v: v, field: field, value: parsed}
+	if err != nil {
+		v.Add(field, "must be a whole number")
+		i.failed = true
+	}
+	return i
+}
+
+func (i *Int) check(ok bool, message string) *Int {
+	if !i.failed && !ok {
+		i.v.Add(i.field, message)
+		i.failed = true
+	}
+	return i
+}
+
+func (i *Int) Between(min int64, max int64) *Int {
+	return i.check(i.value >= min && i.value <= max, fmt.Sprintf("must be between %d and %d", min, max))
+}
+
+func (i *Int) Min(min int64) *Int {
+	return i.check(i.value >= min, fmt.Sprintf("must be at least %d", min))
+}
+
+func (i *Int) Equals(value int64) *Int {
+	return i.check(i.value == value, fmt.Sprintf("must be %d", value))
+}
+
+func (i *Int) Value() int64 {
+	return i.value
+}
+
+func (i *Int) Valid() bool {
+	return !i.failed
+}
+
+type Time struct {
+	v      *Validator
+	field  string
+	value  time.Time
+	failed bool
+}
+
+func (v *Validator) Time(field string, value time.Time) *Time {
+	return &Time{v: v, field: field, value: value}
+}
+
+func (t *Time) check(ok bool, message string) *Time {
+	if !t.failed && !ok {
+		t.v.Add(t.field, message)
+		t.failed = true
+	}
+	return t
+}
+
+func (t *Time) Required() *Time {
+	return t.check(!t.value.IsZero(), "is required")
+}
+
+func (t *Time) After(after time.Time, message string) *Time {
+	return t.check(t.value.After(after), message)
+}
+
+func (t *Time) Equals(value time.Time) *Time {
+	return t.check(t.value.Equal(value), "must be "+value.UTC().Format(time.RFC3339))
+}
+
+// Page reads the limit and cursor query parameters. Endpoints that predate pagination
+// only page when one of them is given, which paged reports.
+func (v *Validator) Page(params map[string]string) (limit int32, cursor string, paged bool) {
+	limitParam, hasLimit := params["limit"]
+	cursor, hasCursor := params["cursor"]
+
+	limit = 25
+	if hasLimit {
+		if parsed := v.Number("limit", limitParam).Between(1, 100); parsed.Valid() {
+			limit = int32(parsed.Value())
+		}
+	}
+
+	return limit, cursor, hasLimit || hasCursor
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestValidator(t *testing.T) {
+	v := New()
+	v.String("name", "").Required().MaxLength(3)
+	v.String("kind", "NFL").Required().OneOf("NFL", "CFB")
+	v.String("team", "Jets").OneOf("Chiefs", "Bills")
+
+	bid := v.At("bids[1]")
+	bid.Int("amount", 0).Between(1, 100)
+	bid.Number("week", "two").Min(1)
+	bid.Time("date", time.Time{}).Required().After(time.Now(), "has passed")
+	bid.String("div", "my league").Matches(regexp.MustCompile(`^\S+$`), "a single word")
+
+	want := Errors{
+		{Field: "name", Message: "is required"},
+		{Field: "team", Message: "must be one of Chiefs, Bills"},
+		{Field: "bids[1].amount", Message: "must be between 1 and 100"},
+		{Field: "bids[1].week", Message: "must be a whole number"},
+		{Field: "bids[1].date", Message: "is required"},
+		{Field: "bids[1].div", Message: "must be a single word"},
+	}
+
+	var got Errors
+	if !errors.As(v.Err(), &got) || len(got) != len(want) {
+		t.Fatalf("errors = %v", v.Err())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("error %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if v.Valid() || bid.Valid() || !v.At("bids[0]").Valid() {
+		t.Error("only the validators with failed fields should be invalid")
+	}
+
+	if New().Err() != nil {
+		t.Error("a validator without errors should be valid")
+	}
+}
+
+func TestPage(t *testing.T) {
+	v := New()
+	if limit, cursor, paged := v.Page(map[string]string{}); limit != 25 || cursor != "" || paged || !v.Valid() {
+		t.Errorf("Page() = %d %q %t %v", limit, cursor, paged, v.Err())
+	}
+
+	v = New()
+	if limit, cursor, paged := v.Page(map[string]string{"limit": "10", "cursor": "abc"}); limit != 10 || cursor != "abc" || !paged || !v.Valid() {
+		t.Errorf("Page() = %d %q %t %v", limit, cursor, paged, v.Err())
+	}
+
+	for _, limit := range []string{"0", "101", "ten"} {
+		v = New()
+		v.Page(map[string]string{"limit": limit})
+		if v.Valid() {
+			t.Errorf("limit %s should be rejected", limit)
+		}
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	v := New()
+	v.Int("amount", 500).Between(1, 100)
+
+	resp, _ := BadRequest(v.Err())
+	if resp.StatusCode != 400 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var body Response
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Message != "invalid request" || len(body.Errors) != 1 || body.Errors[0].Field != "amount" {
+		t.Errorf("body = %s", resp.Body)
+	}
+
+	resp, _ = BadRequest(errors.New("malformed cursor"))
+	json.Unmarshal([]byte(resp.Body), &body)
+	if resp.StatusCode != 400 || body.Message != "malformed cursor" || len(body.Errors) != 0 {
+		t.Errorf("body = %s", resp.Body)
+	}
+}